@@ -8,45 +8,168 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"io"
+	"log/slog"
+	"net"
 	"os"
+	"time"
 
 	"github.com/Lexer747/AcciPing/graph"
 	"github.com/Lexer747/AcciPing/graph/data"
 	"github.com/Lexer747/AcciPing/graph/terminal"
 	"github.com/Lexer747/AcciPing/ping"
+	"github.com/Lexer747/AcciPing/server"
 	"github.com/Lexer747/AcciPing/utils/errors"
 	"github.com/Lexer747/AcciPing/utils/siphon"
+	"github.com/Lexer747/AcciPing/utils/timeutils"
 )
 
 func main() {
-	p := ping.NewPing()
+	cfg, err := parseConfig(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+	if cfg.showVersion {
+		printVersion(os.Stdout)
+		return
+	}
+	if err = cfg.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+	if cfg.logFile != "" {
+		closeLog, err := startLogging(cfg.logFile, cfg.logPings)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(2)
+		}
+		defer closeLog()
+	} else {
+		// The default logger writes to stderr, which would be drawn over the graph.
+		slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	}
+	p, err := cfg.newPing()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+	listener, err := cfg.listen()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
 	ctx, cancelFunc := context.WithCancelCause(context.Background())
 	defer cancelFunc(nil)
 	term, err := terminal.NewTerminal()
 	if err != nil {
 		panic(err.Error())
 	}
+	if cfg.replayFile != "" {
+		g := newReplayGraph(ctx, term, cfg.replayFile, cfg.speed)
+		cfg.configure(g)
+		serve(ctx, listener, g)
+		runGraph(ctx, cancelFunc, g)
+		return
+	}
 	existingData, toUpdate := loadFile()
 
-	const pingsPerMinute = 60.0
 	const channelSize = 10
-	channel, err := p.CreateChannel(ctx, existingData.URL, pingsPerMinute, channelSize)
+	// The URL can be switched live with the 'u' key, and the rate with '[', ']' or a digit.
+	channel, switcher, err := p.CreateSwitchableChannel(ctx, existingData.URL, cfg.pingsPerMinute, channelSize)
 	if err != nil {
 		panic(err.Error())
 	}
+	if cfg.count > 0 {
+		channel = siphon.Limit(ctx, channel, cfg.count)
+	}
 	graphChannel, fileChannel := siphon.TeeBufferedChannel(ctx, channel, channelSize)
-	go writeToFile(ctx, fileChannel, toUpdate)
+	written := make(chan struct{})
+	urls := make(chan string)
+	go func() {
+		defer close(written)
+		writeToFile(ctx, fileChannel, urls, toUpdate, cfg.rotation)
+	}()
+	if cfg.captureDuration > 0 {
+		stop := time.AfterFunc(cfg.captureDuration, func() { cancelFunc(durationElapsed) })
+		defer stop.Stop()
+	}
+	if cfg.count > 0 {
+		go func() {
+			// Once the last ping is in the file the capture is complete.
+			<-written
+			cancelFunc(captureComplete)
+		}()
+	}
 
 	// The graph will take ownership of the data.
-	g, err := graph.NewGraphWithData(ctx, graphChannel, term, pingsPerMinute, existingData)
+	g, err := graph.NewGraphWithData(ctx, graphChannel, term, cfg.pingsPerMinute, existingData)
 	if err != nil {
 		panic(err.Error())
 	}
+	cfg.configure(g)
+	g.SetDetectStale(true)
+	g.SetSwitchURL(func(url string) error {
+		if err := switcher.SwitchURL(url); err != nil {
+			return err
+		}
+		// The file is labelled with the URL being pinged, like the graph's data.
+		select {
+		case urls <- url:
+		case <-written:
+		}
+		return nil
+	})
+	g.SetSwitchRate(switcher.SwitchRate)
+	serve(ctx, listener, g)
+	runGraph(ctx, cancelFunc, g)
+}
+
+// serve serves the capture of the graph over HTTP on the listener until the ctx is done, see the -serve flag.
+// Nothing is served without a listener.
+func serve(ctx context.Context, listener net.Listener, g *graph.Graph) {
+	if listener == nil {
+		return
+	}
+	go func() {
+		if err := server.Serve(ctx, listener, g); err != nil {
+			slog.Warn("stopped serving the capture", "err", err)
+		}
+	}()
+}
+
+// captureComplete stops the graph once the -n pings have been captured.
+var captureComplete = errors.New("captured every ping")
+
+// durationElapsed stops the graph once the capture has run for the -duration.
+var durationElapsed = errors.New("capture duration elapsed")
+
+// startLogging sets the default [slog] logger to write JSON to the file, at the debug level if every ping is
+// to be logged.
+func startLogging(logFile string, debug bool) (func(), error) {
+	f, err := os.OpenFile(logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o666)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't open log file %q", logFile)
+	}
+	level := slog.LevelInfo
+	if debug {
+		level = slog.LevelDebug
+	}
+	slog.SetDefault(slog.New(slog.NewJSONHandler(f, &slog.HandlerOptions{Level: level})))
+	return func() { _ = f.Close() }, nil
+}
+
+// expectedStop is true for the ways a capture ends which aren't an error.
+func expectedStop(err error) bool {
+	return errors.Is(err, terminal.UserCancelled) || errors.Is(err, captureComplete) || errors.Is(err, durationElapsed)
+}
+
+func runGraph(ctx context.Context, cancelFunc context.CancelCauseFunc, g *graph.Graph) {
 	// Very high FPS is good for responsiveness in the UI (since it's locked) and re-drawing on a re-size.
-	err = g.Run(ctx, cancelFunc, 60)
-	if err != nil && !errors.Is(err, terminal.UserCancelled) {
+	err := g.Run(ctx, cancelFunc, 60)
+	if err != nil && !expectedStop(err) {
 		panic(err.Error())
 	} else {
 		_ = g.Term.ClearScreen(true)
@@ -68,6 +191,7 @@ func loadFile() (*data.Data, *os.File) {
 		defer f.Close()
 		// First time, make a new file
 		existingData = data.NewData(demoURL)
+		existingData.Location = timeutils.LocalZoneName()
 		newFile, err := os.OpenFile(demoFilePath, os.O_CREATE|os.O_RDWR, 0o777)
 		if err != nil {
 			panic(err.Error())
@@ -98,27 +222,42 @@ func loadFile() (*data.Data, *os.File) {
 	return existingData, f
 }
 
-func writeToFile(ctx context.Context, input chan ping.PingResults, fileToUpdate *os.File) {
+// writeToFile adds every ping from the input to the file, and stores each URL from urls as the URL of the data.
+func writeToFile(ctx context.Context, input chan ping.PingResults, urls <-chan string, fileToUpdate *os.File, rotation rotation) {
 	defer fileToUpdate.Close()
 	ourData := &data.Data{}
+	var size int64
 	// Block: To scope this byte slice, we don't want to expose it to the running loop
 	{
 		// TODO provide an error channel and surface errors to the graph UI
 		file, _ := io.ReadAll(fileToUpdate)
 		_, _ = ourData.FromCompact(file)
+		size = int64(len(file))
+	}
+	save := func() {
+		// TODO provide an error channel and surface errors to the graph UI
+		_, _ = fileToUpdate.Seek(0, 0)
+		_ = ourData.AsCompact(fileToUpdate)
+		size, _ = fileToUpdate.Seek(0, io.SeekCurrent)
+		// A shorter URL makes the data shorter than what was written before.
+		_ = fileToUpdate.Truncate(size)
 	}
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case url := <-urls:
+			ourData.URL = url
+			save()
 		case p, ok := <-input:
 			if !ok {
 				return
 			}
+			if rotation.due(ourData, size, p.Data.Timestamp) {
+				ourData = rotation.tryRotate(fileToUpdate, ourData)
+			}
 			ourData.AddPoint(p)
-			// TODO provide an error channel and surface errors to the graph UI
-			_, _ = fileToUpdate.Seek(0, 0)
-			_ = ourData.AsCompact(fileToUpdate)
+			save()
 		}
 	}
 }
@@ -9,36 +9,145 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
+	"net"
 	"os"
+	"time"
 
 	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/utils/errors"
 )
 
-// Parses any `.ping` and prints them to stdout
+// Parses any `.ping` and prints them to stdout, or the -o file
 func main() {
 	printAll := false
+	asCSV := false
+	summaryCSV := false
+	from := ""
+	to := ""
+	ip := ""
+	outFile := ""
+	spans := false
+	spanGap := data.DefaultSpanGap
 	flag.BoolVar(&printAll, "a", false, "prints all raw values")
+	flag.BoolVar(&asCSV, "csv", false, "prints all raw values as CSV")
+	flag.BoolVar(&summaryCSV, "summary-csv", false, "prints one CSV row of statistics per file, for comparing many files")
+	flag.BoolVar(&spans, "spans", false, "prints each span of the file, the sessions separated by a gap of more than -span-gap")
+	flag.DurationVar(&spanGap, "span-gap", spanGap, "the time between two pings which splits them into separate spans, for -spans")
+	flag.StringVar(&from, "from", "", "only prints raw values at or after this RFC 3339 time, e.g. 2024-08-02T20:00:00Z")
+	flag.StringVar(&to, "to", "", "only prints raw values at or before this RFC 3339 time")
+	flag.StringVar(&ip, "ip", "", "only prints raw values for this IP address")
+	flag.StringVar(&outFile, "o", "", "writes to this file instead of stdout, the file is overwritten")
 	flag.Parse()
 	toPrint := flag.Args()
-	for _, file := range toPrint {
-		f, err := os.OpenFile(file, os.O_RDONLY, 0)
+	f, err := parseFilter(from, to, ip)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+	if summaryCSV && (printAll || asCSV || !f.Empty()) {
+		fmt.Fprintln(os.Stderr, "-summary-csv can't be combined with -a, -csv, -from, -to or -ip")
+		os.Exit(2)
+	}
+	if spans && (printAll || asCSV || summaryCSV || !f.Empty()) {
+		fmt.Fprintln(os.Stderr, "-spans can't be combined with -a, -csv, -summary-csv, -from, -to or -ip")
+		os.Exit(2)
+	}
+	if spanGap <= 0 {
+		fmt.Fprintf(os.Stderr, "-span-gap must be positive, got %s\n", spanGap)
+		os.Exit(2)
+	}
+	if !f.Empty() && !printAll && !asCSV {
+		fmt.Fprintln(os.Stderr, "-from, -to and -ip require -a or -csv")
+		os.Exit(2)
+	}
+	w := io.Writer(os.Stdout)
+	var out *os.File
+	if outFile != "" {
+		out, err = os.Create(outFile)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to open %q, %s\n", file, err.Error())
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(2)
+		}
+		w = out
+	}
+	err = write(w, toPrint, f, printAll, asCSV, summaryCSV, spans, spanGap)
+	if out != nil {
+		// The last of the output may only be written when the file is closed.
+		if closeErr := out.Close(); err == nil {
+			err = errors.Wrapf(closeErr, "couldn't write %q", outFile)
 		}
-		d, err := data.ReadData(f)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
+
+// write writes each file in the format picked, a file which can't be parsed is reported and skipped but
+// failing to write stops everything.
+func write(w io.Writer, files []string, f data.Filter, printAll, asCSV, summaryCSV, spans bool, spanGap time.Duration) error {
+	switch {
+	case summaryCSV:
+		if err := data.WriteCSVHeader(w, data.SummaryCSVHeader); err != nil {
+			return err
+		}
+	case asCSV:
+		if err := data.WriteCSVHeader(w, data.CSVHeader); err != nil {
+			return err
+		}
+	}
+	for _, file := range files {
+		d, err := readFile(file)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to parse %q, %s\n", file, err.Error())
+			continue
 		}
-		defer f.Close()
-		if printAll {
-			fmt.Fprintf(os.Stdout, "BEGIN %s: %s\n", d.URL, d.Header.String())
-			for i := range d.TotalCount {
-				p := d.GetFull(i)
-				fmt.Fprintf(os.Stdout, "%d: %s\n", i, p.String())
-			}
-			fmt.Fprintf(os.Stdout, "END %s: %s\n", d.URL, d.Header.String())
-		} else {
-			fmt.Fprintln(os.Stdout, d.String())
+		switch {
+		case summaryCSV:
+			err = data.WriteSummaryCSV(w, file, d)
+		case asCSV:
+			err = data.WriteCSV(w, file, d, f)
+		case spans:
+			err = data.WriteSpans(w, d, data.SpanConfig{Gap: spanGap})
+		case printAll:
+			err = data.WriteAll(w, d, f)
+		default:
+			err = data.WriteSummary(w, d)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "Failed to write %q", file)
+		}
+	}
+	return nil
+}
+
+func readFile(file string) (*data.Data, error) {
+	f, err := os.OpenFile(file, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return data.ReadData(f)
+}
+
+func parseFilter(from, to, ip string) (data.Filter, error) {
+	var f data.Filter
+	var err error
+	if from != "" {
+		if f.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return f, errors.Wrapf(err, "invalid -from %q", from)
+		}
+	}
+	if to != "" {
+		if f.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return f, errors.Wrapf(err, "invalid -to %q", to)
+		}
+	}
+	if ip != "" {
+		if f.IP = net.ParseIP(ip); f.IP == nil {
+			return f, errors.Errorf("invalid -ip %q", ip)
 		}
 	}
+	return f, nil
 }
@@ -0,0 +1,355 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Lexer747/AcciPing/graph"
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/graph/terminal"
+	"github.com/Lexer747/AcciPing/graph/terminal/typography"
+	"github.com/Lexer747/AcciPing/ping"
+	"github.com/Lexer747/AcciPing/utils/errors"
+)
+
+// Reads any `.pings` files and draws each of them as a graph in the terminal.
+func main() {
+	follow := false
+	pollInterval := time.Second
+	asciiOnly := false
+	timeFormat := ""
+	reverseX := false
+	maxXLabels := 0
+	relativeX := false
+	markOutage := false
+	markLatest := false
+	noMarkers := false
+	grid := false
+	trueColour := false
+	panel := false
+	gapStats := false
+	renderer := "default"
+	timeZone := ""
+	units := ""
+	precision := 0
+	annotationsFile := ""
+	warmup := 0
+	perSpan := false
+	compare := false
+	noClear := false
+	spanGap := data.DefaultSpanGap
+	flag.BoolVar(&follow, "follow", false,
+		"after drawing the file keep watching it, re-drawing the graph as another process writes new pings to it")
+	flag.DurationVar(&pollInterval, "poll", pollInterval, "how often to check a followed file for changes")
+	flag.BoolVar(&asciiOnly, "ascii", false, "draws the graph using only ASCII, for terminals or fonts without unicode glyphs")
+	flag.StringVar(&timeFormat, "time-format", "",
+		"the go time layout of the x-axis labels, e.g. \"03:04:05PM\" or \"02/01 15:04\" (default \"15:04:05.00\")")
+	flag.IntVar(&maxXLabels, "max-xlabels", 0,
+		"the most time labels drawn on the x-axis, spread evenly across it, 0 for as many as fit")
+	flag.BoolVar(&reverseX, "reverse-x", false, "draws the newest pings on the left of the graph instead of the right")
+	flag.BoolVar(&relativeX, "relative-x", false,
+		"labels the x-axis with the time elapsed since the first ping instead of the time of day")
+	flag.StringVar(&renderer, "render", renderer,
+		"how to draw the pings, one of: default|braille (braille is 2x4 dots per character, finer but needs a font with braille)")
+	flag.BoolVar(&trueColour, "truecolor", false,
+		"colours the lines between pings by latency, green to red, using 24-bit colour which not every terminal supports")
+	flag.BoolVar(&grid, "grid", false, "draws faint grid lines from the axis labels")
+	flag.BoolVar(&panel, "panel", false, "draws a panel of live numbers to the right of the graph, when the terminal is wide enough")
+	flag.BoolVar(&gapStats, "gaps", false, "adds the time between pings to the -panel, to show any jitter or gaps in the capture")
+	flag.BoolVar(&markLatest, "mark-latest", false, "highlights the most recent ping and draws a key of the markers")
+	flag.BoolVar(&noMarkers, "no-markers", false, "draws every ping the same, without marking the min, max or latest")
+	flag.BoolVar(&markOutage, "mark-outage", false, "underlines the longest streak of dropped packets")
+	flag.StringVar(&timeZone, "tz", "",
+		"the time zone to show timestamps in, e.g. \"Europe/London\" (default the zone the file was captured in, or local)")
+	flag.StringVar(&units, "units", "auto", "the units of the latency statistics, one of: ms|us|auto")
+	flag.IntVar(&precision, "precision", 0, "the significant figures of the latency statistics, 0 for full precision")
+	flag.IntVar(&warmup, "warmup", 0,
+		"excludes the first N good pings after each DNS resolution from the latency statistics, they're still drawn")
+	flag.StringVar(&annotationsFile, "annotations", "",
+		"a CSV file of \"<RFC 3339 timestamp>,<label>\" lines, each drawn as a labelled vertical line on the graph")
+	flag.BoolVar(&perSpan, "per-span", false,
+		"draws each span of the file, the sessions separated by a gap of more than -span-gap, as a frame of its own")
+	flag.DurationVar(&spanGap, "span-gap", spanGap, "the time between two pings which splits them into separate spans, for -per-span")
+	flag.BoolVar(&compare, "compare", false,
+		"draws exactly two files on the same axes, the second in magenta, for comparing two network paths")
+	flag.BoolVar(&noClear, "no-clear", false,
+		"prints each graph after the last instead of clearing the screen, so every graph is kept in the scrollback")
+	flag.Parse()
+	toDraw := flag.Args()
+	durationFormat, err := data.ParseDurationFormat(units, precision)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+	render, err := graph.ParseRenderer(renderer)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+	if maxXLabels < 0 {
+		fmt.Fprintf(os.Stderr, "-max-xlabels must not be negative, got %d\n", maxXLabels)
+		os.Exit(2)
+	}
+	if warmup < 0 {
+		fmt.Fprintf(os.Stderr, "-warmup must not be negative, got %d\n", warmup)
+		os.Exit(2)
+	}
+	var annotations []graph.Annotation
+	if annotationsFile != "" {
+		if annotations, err = graph.ReadAnnotationsFile(annotationsFile); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(2)
+		}
+	}
+	var override *time.Location
+	if timeZone != "" {
+		if override, err = time.LoadLocation(timeZone); err != nil {
+			fmt.Fprintf(os.Stderr, "Unknown -tz %q, %s\n", timeZone, err.Error())
+			os.Exit(2)
+		}
+	}
+	if follow && len(toDraw) != 1 {
+		fmt.Fprintln(os.Stderr, "-follow requires exactly one file")
+		os.Exit(2)
+	}
+	if follow && perSpan {
+		fmt.Fprintln(os.Stderr, "-follow can't be combined with -per-span")
+		os.Exit(2)
+	}
+	if compare && len(toDraw) != 2 {
+		fmt.Fprintln(os.Stderr, "-compare requires exactly two files")
+		os.Exit(2)
+	}
+	if compare && (follow || perSpan) {
+		fmt.Fprintln(os.Stderr, "-compare can't be combined with -follow or -per-span")
+		os.Exit(2)
+	}
+	if spanGap <= 0 {
+		fmt.Fprintf(os.Stderr, "-span-gap must be positive, got %s\n", spanGap)
+		os.Exit(2)
+	}
+
+	term, err := terminal.NewTerminal()
+	if err != nil {
+		panic(err.Error())
+	}
+	ctx, cancelFunc := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancelFunc()
+	configure := func(g *graph.Graph) {
+		g.SetASCII(asciiOnly)
+		g.SetTimeFormat(timeFormat)
+		g.SetReverseX(reverseX)
+		g.SetMaxXLabels(maxXLabels)
+		g.SetRelativeX(relativeX)
+		g.SetMarkLongestOutage(markOutage)
+		g.SetMarkLatest(markLatest)
+		g.SetShowMarkers(!noMarkers)
+		g.SetGrid(grid)
+		g.SetTrueColour(trueColour)
+		g.SetPanel(panel)
+		g.SetGapStats(gapStats)
+		g.SetRenderer(render)
+		g.SetDurationFormat(durationFormat)
+		g.SetWarmup(warmup)
+		g.SetAnnotations(annotations)
+		g.SetDetectStale(follow)
+		g.SetClearScreen(!noClear)
+	}
+	if compare {
+		if err = drawCompared(ctx, term, toDraw[0], toDraw[1], override, configure); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		fmt.Println()
+		return
+	}
+	for _, file := range toDraw {
+		d, err := readFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read %q, %s\n", file, err.Error())
+			continue
+		}
+		loc := displayLocation(d, override)
+		d.In(loc)
+		if perSpan {
+			if err = drawSpans(ctx, term, d, data.SpanConfig{Gap: spanGap}, asciiOnly, configure); err != nil {
+				panic(err.Error())
+			}
+			continue
+		}
+		g := newGraph(ctx, term, d)
+		configure(g)
+		if err = drawFrame(g); err != nil {
+			panic(err.Error())
+		}
+		if follow {
+			err = followFile(ctx, g, file, pollInterval, loc)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "\nStopped following %q, %s\n", file, err.Error())
+				os.Exit(1)
+			}
+		}
+		fmt.Println()
+	}
+}
+
+func readFile(file string) (*data.Data, error) {
+	f, err := os.OpenFile(file, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return data.ReadData(f)
+}
+
+// displayLocation is the override if there is one, otherwise the zone the data was captured in if known,
+// falling back to local time.
+func displayLocation(d *data.Data, override *time.Location) *time.Location {
+	if override != nil {
+		return override
+	}
+	if d.Location == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(d.Location)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unknown time zone %q in the file, using local time. %s\n", d.Location, err.Error())
+		return time.Local
+	}
+	return loc
+}
+
+func newGraph(ctx context.Context, term *terminal.Terminal, d *data.Data) *graph.Graph {
+	// We never receive pings live, so give the graph a closed channel. The graph takes ownership of the data.
+	pingChannel := make(chan ping.PingResults)
+	close(pingChannel)
+	g, err := graph.NewGraphWithData(ctx, pingChannel, term, 0, d)
+	if err != nil {
+		panic(err.Error())
+	}
+	return g
+}
+
+func drawFrame(g *graph.Graph) error {
+	frame, err := g.OneFrame()
+	if err != nil {
+		return err
+	}
+	return g.Term.Print(frame)
+}
+
+// drawCompared draws the first file with the second drawn over it on the same axes, see [graph.Graph.SetCompare].
+// Both are shown in the zone of the first.
+func drawCompared(
+	ctx context.Context,
+	term *terminal.Terminal,
+	file, otherFile string,
+	override *time.Location,
+	configure func(*graph.Graph),
+) error {
+	d, err := readFile(file)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %q", file)
+	}
+	other, err := readFile(otherFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %q", otherFile)
+	}
+	loc := displayLocation(d, override)
+	d.In(loc)
+	other.In(loc)
+	g := newGraph(ctx, term, d)
+	configure(g)
+	g.SetCompare(other, filepath.Base(file), filepath.Base(otherFile))
+	return drawFrame(g)
+}
+
+// drawSpans draws each span of the data (see [data.DetectSpans]) as a frame of its own, so that each has axes
+// ranged to fit only that span and arrows on the x-axis where the other spans are. A divider naming the span is
+// printed after each frame. Each frame is printed as a block after the last (see [graph.Graph.SetClearScreen])
+// so that every span stays on the screen.
+func drawSpans(
+	ctx context.Context,
+	term *terminal.Terminal,
+	d *data.Data,
+	cfg data.SpanConfig,
+	asciiOnly bool,
+	configure func(*graph.Graph),
+) error {
+	spans := data.DetectSpans(d, cfg)
+	for i, span := range spans {
+		g := newGraph(ctx, term, span.Data(d))
+		configure(g)
+		g.SetCaptureSpan(d.Header.TimeSpan)
+		g.SetClearScreen(false)
+		if err := drawFrame(g); err != nil {
+			return err
+		}
+		divider := spanDivider(i, len(spans), span, term.Size().Width)
+		if asciiOnly {
+			divider = typography.ASCII(divider)
+		}
+		if err := term.Print("\n" + divider + "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// spanDivider names the span across the width of the terminal, e.g. "── Span 1 of 3: ... ─────".
+func spanDivider(i, count int, span data.Span, width int) string {
+	name := fmt.Sprintf(" Span %d of %d: %s ", i+1, count, span.Header.TimeSpan.String())
+	return strings.Repeat(typography.Horizontal, 2) + name +
+		strings.Repeat(typography.Horizontal, max(width-2-utf8.RuneCountInString(name), 0))
+}
+
+// followFile polls the file for changes until the context is cancelled, adding any new points to the graph and
+// re-drawing it. The file is currently re-written in full by the writer so it is re-read in full too, only
+// the points which are new are given to the graph.
+func followFile(ctx context.Context, g *graph.Graph, file string, pollInterval time.Duration, loc *time.Location) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	var lastModified time.Time
+	var lastSize int64
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		if info.Size() != lastSize || !info.ModTime().Equal(lastModified) {
+			d, err := readFile(file)
+			if err != nil {
+				// Most likely we raced with the writer and read a partially written file, try again next poll.
+				continue
+			}
+			lastSize = info.Size()
+			lastModified = info.ModTime()
+			d.In(loc)
+			for i := g.Size(); i < d.TotalCount; i++ {
+				g.AddPoint(d.GetFull(i))
+			}
+		}
+		// Always re-draw, the terminal may have been re-sized. The graph only paints if something changed.
+		if err = drawFrame(g); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Lexer747/AcciPing/graph"
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/graph/terminal"
+	"github.com/Lexer747/AcciPing/graph/terminal/ansi"
+	"github.com/Lexer747/AcciPing/graph/terminal/th"
+	"github.com/Lexer747/AcciPing/ping"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrawSpans(t *testing.T) {
+	t.Parallel()
+	_, stdout, term, setTerm, err := th.NewTestTerminal()
+	require.NoError(t, err)
+	setTerm(terminal.Size{Height: 15, Width: 80})
+	d := data.NewData("www.example.com")
+	origin := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	// Two spans of three points, an hour apart.
+	for i, offset := range []time.Duration{0, time.Second, 2 * time.Second, time.Hour, time.Hour + time.Second, time.Hour + 2*time.Second} {
+		d.AddPoint(ping.PingResults{
+			Data: ping.PingDataPoint{Duration: time.Duration(i+1) * time.Millisecond, Timestamp: origin.Add(offset)},
+			IP:   net.IPv4allrouter,
+		})
+	}
+	require.NoError(t, drawSpans(context.Background(), term, d, data.SpanConfig{Gap: time.Minute}, false, func(*graph.Graph) {}))
+
+	buffer := make([]byte, 1<<20)
+	n, err := stdout.Read(buffer)
+	require.NoError(t, err)
+	drawn := string(buffer[:n])
+	require.NotContains(t, drawn, ansi.Clear, "a span mustn't clear the one before it")
+	require.NotContains(t, drawn, ansi.Home)
+	first, second := strings.Index(drawn, "Span 1 of 2"), strings.Index(drawn, "Span 2 of 2")
+	require.NotEqual(t, -1, first)
+	require.Greater(t, second, first)
+	require.Equal(t, 2, strings.Count(drawn, "www.example.com"), "both frames are drawn")
+}
+
+func TestSpanDivider(t *testing.T) {
+	t.Parallel()
+	d := data.NewData("www.example.com")
+	d.AddPoint(ping.PingResults{Data: ping.PingDataPoint{Duration: time.Millisecond, Timestamp: time.UnixMilli(0)}, IP: net.IPv4allrouter})
+	span := data.DetectSpans(d, data.SpanConfig{})[0]
+	divider := spanDivider(0, 3, span, 80)
+	require.True(t, strings.HasPrefix(divider, "── Span 1 of 3: "))
+	require.Equal(t, 80, utf8.RuneCountInString(divider))
+	require.Contains(t, spanDivider(2, 3, span, 10), "Span 3 of 3", "never truncated")
+}
@@ -0,0 +1,192 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/graph/terminal"
+	"github.com/Lexer747/AcciPing/graph/terminal/ansi"
+	"github.com/Lexer747/AcciPing/graph/terminal/typography"
+)
+
+// defaultWidth is used when stdout isn't a terminal, e.g. when piped to a file.
+const defaultWidth = 80
+
+// minBarWidth is the narrowest the bars are drawn, even if the terminal is narrower than the labels.
+const minBarWidth = 10
+
+// Reads any `.pings` files and prints the distribution of their latency as a horizontal bar chart, one bar per
+// range of latency, to characterise the link rather than how it changed over time.
+func main() {
+	buckets := 0
+	width := 0
+	asciiOnly := false
+	noColour := false
+	units := ""
+	precision := 0
+	flag.IntVar(&buckets, "buckets", 10, "the number of equal width ranges of latency to split the pings into")
+	flag.IntVar(&width, "width", 0, "the width of the chart in characters, 0 for the width of the terminal")
+	flag.BoolVar(&asciiOnly, "ascii", false, "draws the bars using only ASCII, for terminals or fonts without unicode glyphs")
+	flag.BoolVar(&noColour, "no-colour", false, "draws the bars without colour, the most common range is still marked with a *")
+	flag.StringVar(&units, "units", "auto", "the units of the latency ranges, one of: ms|us|auto")
+	flag.IntVar(&precision, "precision", 0, "the significant figures of the latency ranges, 0 for full precision")
+	flag.Parse()
+	if buckets < 1 {
+		fmt.Fprintf(os.Stderr, "-buckets must be positive, got %d\n", buckets)
+		os.Exit(2)
+	}
+	if width < 0 {
+		fmt.Fprintf(os.Stderr, "-width must not be negative, got %d\n", width)
+		os.Exit(2)
+	}
+	durationFormat, err := data.ParseDurationFormat(units, precision)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+	if width == 0 {
+		width = terminalWidth()
+	}
+	c := chart{
+		width:  width,
+		format: durationFormat,
+		ascii:  asciiOnly,
+		colour: !noColour,
+	}
+	for i, file := range flag.Args() {
+		d, err := readFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read %q, %s\n", file, err.Error())
+			continue
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		c.print(os.Stdout, file, d, buckets)
+	}
+}
+
+func readFile(file string) (*data.Data, error) {
+	f, err := os.OpenFile(file, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return data.ReadData(f)
+}
+
+func terminalWidth() int {
+	term, err := terminal.NewTerminal()
+	if err != nil {
+		return defaultWidth
+	}
+	return term.Size().Width
+}
+
+type chart struct {
+	width  int
+	format data.DurationFormat
+	ascii  bool
+	colour bool
+}
+
+// print writes a title then one line per bucket, e.g.
+//
+//	12.3ms - 15.6ms   420  42.00% │████████████████████
+//
+// the bars are scaled so that the most common range fills the width left after the labels.
+func (c chart) print(w io.Writer, file string, d *data.Data, buckets int) {
+	stats := d.Header.Stats
+	fmt.Fprintln(w, c.glyph(fmt.Sprintf("%s: %d good pings, %s %s, %s %s", file, stats.GoodCount,
+		typography.Mu, c.duration(time.Duration(stats.Mean)),
+		typography.Sigma, c.duration(time.Duration(stats.StandardDeviation)))))
+	histogram := d.Histogram(buckets)
+	if len(histogram) == 0 {
+		fmt.Fprintln(w, "  No good pings")
+		return
+	}
+	mode := data.Mode(histogram)
+	rows := make([][]string, len(histogram))
+	widths := make([]int, 4)
+	for i, b := range histogram {
+		rows[i] = []string{
+			c.duration(b.Min),
+			c.duration(b.Max),
+			strconv.FormatUint(b.Count, 10),
+			fmt.Sprintf("%.2f%%", float64(b.Count)/float64(stats.GoodCount)*100),
+		}
+		for j, column := range rows[i] {
+			widths[j] = max(widths[j], utf8.RuneCountInString(column))
+		}
+	}
+	labelWidth := widths[0] + len(" - ") + widths[1] + 2 + widths[2] + 2 + widths[3]
+	barWidth := max(c.width-labelWidth-len(" * ")-1, minBarWidth)
+	most := histogram[mode].Count
+	for i, b := range histogram {
+		marker := "   "
+		if i == mode {
+			marker = " * "
+		}
+		r := rows[i]
+		fmt.Fprintf(w, "%s - %s  %s  %s%s%s%s\n",
+			padLeft(r[0], widths[0]), padRight(r[1], widths[1]), padLeft(r[2], widths[2]), padLeft(r[3], widths[3]),
+			marker, c.glyph(typography.Vertical), c.bar(b.Count, most, barWidth, i == mode))
+	}
+}
+
+// duration is rounded to the microsecond first, the bucket edges are split evenly between the fastest and
+// slowest point so are rarely round numbers and anything finer isn't meaningful for a ping.
+func (c chart) duration(d time.Duration) string {
+	return c.format.Format(d.Round(time.Microsecond))
+}
+
+// padLeft and padRight count runes rather than bytes, unlike fmt's padding, as the units may be "µs".
+func padLeft(s string, width int) string {
+	return strings.Repeat(" ", max(width-utf8.RuneCountInString(s), 0)) + s
+}
+
+func padRight(s string, width int) string {
+	return s + strings.Repeat(" ", max(width-utf8.RuneCountInString(s), 0))
+}
+
+// bar is the count scaled to the width, any bucket with a point in it is drawn at least a sliver wide so that
+// it's distinct from an empty one.
+func (c chart) bar(count, most uint64, width int, isMode bool) string {
+	if count == 0 {
+		return ""
+	}
+	length := int(count * uint64(width) / most)
+	var bar string
+	if length == 0 {
+		bar = c.glyph(typography.LightBlock)
+	} else {
+		bar = c.glyph(strings.Repeat(typography.Block, length))
+	}
+	if !c.colour {
+		return bar
+	}
+	if isMode {
+		return ansi.Green(bar)
+	}
+	return ansi.Blue(bar)
+}
+
+func (c chart) glyph(s string) string {
+	if c.ascii {
+		return typography.ASCII(s)
+	}
+	return s
+}
@@ -0,0 +1,223 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/utils/errors"
+)
+
+// Walks all the `.pings` files passed (or found inside the directories passed) and prints a comparative table
+// of their statistics to stdout.
+func main() {
+	sortBy := ""
+	asCSV := false
+	drops := false
+	units := ""
+	precision := 0
+	flag.StringVar(&sortBy, "sort", "", "sorts the table by one of: mean|loss|count")
+	flag.BoolVar(&asCSV, "csv", false, "prints the table as CSV instead of aligned text")
+	flag.StringVar(&units, "units", "auto", "the units of the latency statistics, one of: ms|us|auto")
+	flag.IntVar(&precision, "precision", 0, "the significant figures of the latency statistics, 0 for full precision")
+	flag.BoolVar(&drops, "drops", false, "after the table prints how many streaks of each length of dropped packets each file has")
+	flag.Parse()
+	if drops && asCSV {
+		fmt.Fprintln(os.Stderr, "-drops can't be combined with -csv")
+		os.Exit(2)
+	}
+
+	sorter, err := getSorter(sortBy)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+	durationFormat, err := data.ParseDurationFormat(units, precision)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+	files := findFiles(flag.Args())
+	rows := make([]row, 0, len(files))
+	for _, file := range files {
+		r, err := readRow(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to summarise %q, %s\n", file, err.Error())
+			continue
+		}
+		rows = append(rows, r)
+	}
+	if sorter != nil {
+		slices.SortStableFunc(rows, sorter)
+	}
+	if asCSV {
+		err = printCSV(rows, durationFormat)
+	} else {
+		err = printTable(rows, durationFormat)
+	}
+	if err == nil && drops {
+		err = printDrops(rows)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
+
+type row struct {
+	file  string
+	data  *data.Data
+	p99   time.Duration
+	count uint64
+}
+
+func readRow(file string) (row, error) {
+	f, err := os.OpenFile(file, os.O_RDONLY, 0)
+	if err != nil {
+		return row{}, err
+	}
+	defer f.Close()
+	d, err := data.ReadData(f)
+	if err != nil {
+		return row{}, err
+	}
+	return row{
+		file:  file,
+		data:  d,
+		p99:   d.Percentile(99),
+		count: d.Header.Stats.GoodCount + d.Header.Stats.PacketsDropped,
+	}, nil
+}
+
+func (r row) columns(f data.DurationFormat) []string {
+	stats := r.data.Header.Stats
+	return []string{
+		r.file,
+		r.data.Header.TimeSpan.String(),
+		strconv.FormatUint(r.count, 10),
+		f.Format(time.Duration(stats.Mean)),
+		f.Format(time.Duration(stats.StandardDeviation)),
+		f.Format(r.p99),
+		fmt.Sprintf("%.2f%%", stats.PacketLoss()*100),
+	}
+}
+
+var headers = []string{"File", "TimeSpan", "Count", "Mean", "SD", "P99", "Loss"}
+
+func printTable(rows []row, f data.DurationFormat) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	for _, r := range rows {
+		fmt.Fprintln(w, strings.Join(r.columns(f), "\t"))
+	}
+	return w.Flush()
+}
+
+func printCSV(rows []row, f data.DurationFormat) error {
+	w := csv.NewWriter(os.Stdout)
+	if err := w.Write(headers); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		if err := w.Write(r.columns(f)); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// printDrops prints a histogram of the drop streak lengths of each file, e.g. five 2 packet drops and one 40
+// packet outage.
+func printDrops(rows []row) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, r := range rows {
+		fmt.Fprintf(w, "\n%s\n", r.file)
+		events := r.data.DropEvents()
+		if len(events) == 0 {
+			fmt.Fprintln(w, "  No dropped packets")
+			continue
+		}
+		counts := map[uint64]int{}
+		longest := events[0]
+		for _, event := range events {
+			counts[event.Length]++
+			if event.Length > longest.Length {
+				longest = event
+			}
+		}
+		lengths := make([]uint64, 0, len(counts))
+		for length := range counts {
+			lengths = append(lengths, length)
+		}
+		slices.Sort(lengths)
+		for _, length := range lengths {
+			fmt.Fprintf(w, "  %d\tx %d packets\n", counts[length], length)
+		}
+		fmt.Fprintf(w, "  Longest\t%d packets from %s to %s\n",
+			longest.Length, longest.Start.Format(time.DateTime), longest.End.Format(time.DateTime))
+	}
+	return w.Flush()
+}
+
+func getSorter(sortBy string) (func(a, b row) int, error) {
+	switch sortBy {
+	case "":
+		return nil, nil
+	case "mean":
+		return func(a, b row) int { return cmp.Compare(a.data.Header.Stats.Mean, b.data.Header.Stats.Mean) }, nil
+	case "loss":
+		return func(a, b row) int {
+			return cmp.Compare(a.data.Header.Stats.PacketLoss(), b.data.Header.Stats.PacketLoss())
+		}, nil
+	case "count":
+		return func(a, b row) int { return cmp.Compare(a.count, b.count) }, nil
+	default:
+		return nil, errors.Errorf("unknown -sort %q, expected one of: mean|loss|count", sortBy)
+	}
+}
+
+// findFiles expands any directories passed into all the `.pings` files they contain.
+func findFiles(paths []string) []string {
+	ret := []string{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open %q, %s\n", path, err.Error())
+			continue
+		}
+		if !info.IsDir() {
+			ret = append(ret, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && filepath.Ext(file) == ".pings" {
+				ret = append(ret, file)
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to walk %q, %s\n", path, err.Error())
+		}
+	}
+	return ret
+}
@@ -0,0 +1,59 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/Lexer747/AcciPing/ping"
+)
+
+// Pings every host passed once and prints a table of the results, sorted by latency with any failures last.
+func main() {
+	flag.Parse()
+	hosts := flag.Args()
+	if len(hosts) == 0 {
+		fmt.Fprintln(os.Stderr, "sweep requires at least one host to ping")
+		os.Exit(2)
+	}
+	ctx, cancelFunc := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancelFunc()
+
+	results := ping.OneShotMany(ctx, hosts)
+	sorted := make([]string, 0, len(results))
+	for host := range results {
+		sorted = append(sorted, host)
+	}
+	slices.SortFunc(sorted, func(a, b string) int {
+		left, right := results[a], results[b]
+		leftFailed := left.Err != nil || left.Dropped()
+		rightFailed := right.Err != nil || right.Dropped()
+		switch {
+		case leftFailed && rightFailed:
+			return cmp.Compare(a, b)
+		case leftFailed:
+			return 1
+		case rightFailed:
+			return -1
+		default:
+			return cmp.Compare(left.Duration, right.Duration)
+		}
+	})
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Host\tResult")
+	for _, host := range sorted {
+		fmt.Fprintf(w, "%s\t%s\n", host, results[host].String())
+	}
+	_ = w.Flush()
+}
@@ -13,10 +13,11 @@ import (
 
 	"github.com/Lexer747/AcciPing/graph/terminal"
 	"github.com/Lexer747/AcciPing/graph/terminal/ansi"
+	"github.com/Lexer747/AcciPing/gui"
 )
 
 // A small demo of the terminal API, this program will emit a terminal sized line every time it hears a key,
-// and exits on ctrl+c.
+// opens a text input on 'i', and exits on ctrl+c.
 func main() {
 	// First we need to check if we are running under a terminal
 	t, err := terminal.NewTerminal()
@@ -30,7 +31,7 @@ func main() {
 	writeLineListener := terminal.Listener{
 		Name: "blankLine",
 		Applicable: func(r rune) bool {
-			return r != 'l'
+			return r != 'l' && r != 'i'
 		},
 		Action: func(rune) error {
 			sizeDiv2 := (t.Size().Width / 2) - 7
@@ -54,9 +55,29 @@ func main() {
 			return t.ClearScreen(true)
 		},
 	}
+	// text input example, the box has to be run off the terminal's input go-routine so that it can hear the
+	// keys typed into it:
+	inputListener := terminal.Listener{
+		Name: "input",
+		Applicable: func(r rune) bool {
+			return r == 'i'
+		},
+		Action: func(rune) error {
+			go func() {
+				text, err := gui.InputBox{Prompt: "Type something, enter to finish or escape to cancel"}.Run(ctx, t)
+				_ = t.ClearScreen(true)
+				if err != nil {
+					_ = t.Print(ansi.Home + ansi.Red(err.Error()) + ansi.CursorPosition(2, 1))
+					return
+				}
+				_ = t.Print(ansi.Home + "You typed: " + ansi.Green(text) + ansi.CursorPosition(2, 1))
+			}()
+			return nil
+		},
+	}
 	// Actually start the terminal program.
 	// Note that the listeners are applied in order, so if more than one is applicable then the last entry will happen last
-	cleanup, err := t.StartRaw(ctx, cancelFunc, writeLineListener, clearScreenListener)
+	cleanup, err := t.StartRaw(ctx, cancelFunc, writeLineListener, clearScreenListener, inputListener)
 	defer cleanup()
 	if err != nil {
 		panic(err.Error())
@@ -64,7 +85,7 @@ func main() {
 	if err = t.ClearScreen(true); err != nil {
 		panic(err.Error())
 	}
-	t.Print("Press 'l' to clear the screen, any other char to print a line, ctrl-c to quit." + ansi.CursorPosition(2, 1))
+	t.Print("Press 'l' to clear the screen, 'i' to type some text, any other char to print a line, ctrl-c to quit." + ansi.CursorPosition(2, 1))
 	// Hold the main thread until the context is cancelled by the terminal
 	<-ctx.Done()
 }
@@ -0,0 +1,326 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package main
+
+import (
+	"flag"
+	"net"
+	"time"
+
+	"github.com/Lexer747/AcciPing/graph"
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/ping"
+	"github.com/Lexer747/AcciPing/server"
+	"github.com/Lexer747/AcciPing/utils/errors"
+)
+
+// config is every command line flag, see [parseConfig]. The flags are kept as they were given, the fields after
+// them are parsed from the flags by [config.validate].
+type config struct {
+	showVersion     bool
+	monotonic       bool
+	noSync          bool
+	dnsTrust        string
+	sourceAddress   string
+	iface           string
+	tos             int
+	forceGradients  bool
+	trueColour      bool
+	outOfOrder      bool
+	replayFile      string
+	asciiOnly       bool
+	reverseX        bool
+	maxXLabels      int
+	relativeX       bool
+	markOutage      bool
+	markLatest      bool
+	lossGauge       bool
+	noMarkers       bool
+	grid            bool
+	panel           bool
+	gapStats        bool
+	renderer        string
+	labels          string
+	yLabels         string
+	window          string
+	readoutZone     string
+	glyphs          string
+	glyphsFile      string
+	rotate          string
+	rotateName      string
+	units           string
+	precision       int
+	annotationsFile string
+	warmup          int
+	count           int
+	captureDuration time.Duration
+	rate            rateFlags
+	logFile         string
+	logPings        bool
+	adaptive        bool
+	adaptiveRate    ping.AdaptiveRate
+	replaySpeed     string
+	serveAddr       string
+	// set are the names of the flags given on the command line.
+	set map[string]bool
+
+	pingsPerMinute  float64
+	trust           ping.DNSCacheTrust
+	durationFormat  data.DurationFormat
+	render          graph.Renderer
+	labelLevel      graph.Labels
+	yLabelPlacement graph.YLabels
+	zone            graph.ReadoutZone
+	recentWindow    data.Window
+	pointGlyphs     graph.Glyphs
+	rotation        rotation
+	annotations     []graph.Annotation
+	speed           float64
+	// listenAddr is the address to -serve on, empty if the capture isn't served.
+	listenAddr string
+}
+
+// parseConfig defines every flag on the flag set and parses the arguments, use [config.validate] before using
+// the config.
+func parseConfig(fs *flag.FlagSet, args []string) (config, error) {
+	c := config{
+		dnsTrust:     "low",
+		renderer:     "default",
+		labels:       "full",
+		yLabels:      "even",
+		readoutZone:  "axis",
+		rotateName:   "dev.2006-01-02T150405.pings",
+		rate:         rateFlags{pingsPerMinute: 60},
+		adaptiveRate: ping.AdaptiveRate{PingsPerMinute: 600, Cooldown: 5},
+		replaySpeed:  "1x",
+	}
+	fs.BoolVar(&c.showVersion, "version", false, "prints the version of this build and exits, the same as the version subcommand")
+	fs.BoolVar(&c.monotonic, "monotonic", false,
+		"timestamps pings using the monotonic clock from the start of the capture, immune to wall clock adjustments")
+	fs.BoolVar(&c.noSync, "no-sync", false,
+		"disables synchronized output (DEC 2026) for terminals which echo the unknown escape sequence")
+	fs.StringVar(&c.dnsTrust, "dns-trust", c.dnsTrust,
+		"how many dropped packets an IP address from a DNS query may have before it's replaced, one of: low|nominal|high")
+	fs.StringVar(&c.sourceAddress, "source", "", "sends the pings from this local IPv4 address, e.g. 192.168.1.5")
+	fs.StringVar(&c.iface, "iface", "", "sends the pings out of this network interface, e.g. eth1")
+	fs.IntVar(&c.tos, "tos", 0,
+		"marks the pings with this type of service byte for QoS testing, e.g. 0xb8 for DSCP EF, may need elevated privileges")
+	fs.BoolVar(&c.forceGradients, "gradients", false,
+		"always draw the lines between points, even for sparse captures (toggle while running with 'g')")
+	fs.BoolVar(&c.trueColour, "truecolor", false,
+		"colours the lines between pings by latency, green to red, using 24-bit colour which not every terminal supports")
+	fs.BoolVar(&c.outOfOrder, "out-of-order", false,
+		"marks replies which arrive after their request timed out on the graph, they aren't counted as more packet loss")
+	fs.BoolVar(&c.asciiOnly, "ascii", false, "draws the graph using only ASCII, for terminals or fonts without unicode glyphs")
+	fs.IntVar(&c.maxXLabels, "max-xlabels", 0,
+		"the most time labels drawn on the x-axis, spread evenly across it, 0 for as many as fit")
+	fs.BoolVar(&c.reverseX, "reverse-x", false, "draws the newest pings on the left of the graph instead of the right")
+	fs.BoolVar(&c.relativeX, "relative-x", false,
+		"labels the x-axis with the time elapsed since the first ping instead of the time of day (toggle while running with 'r')")
+	fs.StringVar(&c.renderer, "render", c.renderer,
+		"how to draw the pings, one of: default|braille (braille is 2x4 dots per character, finer but needs a font with braille)")
+	fs.StringVar(&c.labels, "labels", c.labels,
+		"how much marks the min and max pings, one of: full|markers|none (cycle while running with 'l')")
+	fs.StringVar(&c.yLabels, "ylabels", c.yLabels,
+		"where the latency axis is labelled, one of: even|percentile (the p50, p90 and p99, each with a line across the graph)")
+	fs.StringVar(&c.window, "window", "",
+		"shows the mean of the most recent pings next to the lifetime mean in the title, either a number of pings or a duration e.g. 5m")
+	fs.StringVar(&c.glyphs, "glyphs", "",
+		"the characters the pings are drawn with as name=glyph pairs, e.g. point=●,latest=◉ (names: point|out-of-order|dropped|min|max|latest)")
+	fs.StringVar(&c.glyphsFile, "glyphs-file", "", "reads the glyphs from a file with a name=glyph pair on each line, -glyphs overrides it")
+	fs.StringVar(&c.readoutZone, "readout-zone", c.readoutZone,
+		"the time zone of the inspection cursor's readout, one of: axis|local|utc|file (cycle while running with 'z')")
+	fs.BoolVar(&c.grid, "grid", false, "draws faint grid lines from the axis labels (toggle while running with '#')")
+	fs.BoolVar(&c.panel, "panel", false,
+		"draws a panel of live numbers to the right of the graph, when the terminal is wide enough (toggle while running with 'p')")
+	fs.BoolVar(&c.gapStats, "gaps", false,
+		"adds the actual time between pings to the -panel next to the configured interval, to show any jitter or throttling")
+	fs.BoolVar(&c.markLatest, "mark-latest", false, "highlights the most recent ping and draws a key of the markers")
+	fs.BoolVar(&c.lossGauge, "loss-gauge", false, "draws the packet loss so far at the end of the time axis, coloured by how bad it is")
+	fs.BoolVar(&c.noMarkers, "no-markers", false, "draws every ping the same, without marking the min, max or latest (toggle while running with 'm')")
+	fs.BoolVar(&c.markOutage, "mark-outage", false,
+		"underlines the longest streak of dropped packets (toggle while running with 'o')")
+	fs.StringVar(&c.units, "units", "auto", "the units of the latency statistics, one of: ms|us|auto")
+	fs.IntVar(&c.precision, "precision", 0, "the significant figures of the latency statistics, 0 for full precision")
+	fs.IntVar(&c.count, "n", 0, "stops after this many pings (good or dropped) and prints the summary, 0 to run until ctrl-c")
+	fs.DurationVar(&c.captureDuration, "duration", 0,
+		"stops after this long (e.g. 1h) and prints the summary, combined with -n whichever is first, 0 to run until ctrl-c")
+	fs.StringVar(&c.logFile, "l", "", "appends structured (JSON) logs to this file, without it nothing is logged")
+	fs.BoolVar(&c.logPings, "log-pings", false, "logs every ping (latency, drop reason, ip, seq) at the debug level, requires -l")
+	fs.Float64Var(&c.rate.pingsPerMinute, "pings-per-minute", c.rate.pingsPerMinute, "how often to ping, 0 for as fast as possible")
+	fs.Float64Var(&c.rate.pingsPerSecond, "pings-per-second", 0, "how often to ping, instead of -pings-per-minute")
+	fs.DurationVar(&c.rate.interval, "interval", 0, "the time between pings e.g. 200ms, instead of -pings-per-minute")
+	fs.BoolVar(&c.adaptive, "adaptive", false,
+		"pings at -adaptive-rate while packets are dropped or slower than -adaptive-spike, then ramps back down to -pings-per-minute")
+	fs.Float64Var(&c.adaptiveRate.PingsPerMinute, "adaptive-rate", c.adaptiveRate.PingsPerMinute, "the pings per minute during an -adaptive event")
+	fs.DurationVar(&c.adaptiveRate.SpikeThreshold, "adaptive-spike", 0,
+		"the latency above which a ping starts an -adaptive event, 0 for only dropped packets")
+	fs.IntVar(&c.adaptiveRate.Cooldown, "adaptive-cooldown", c.adaptiveRate.Cooldown,
+		"how many healthy pings in a row end an -adaptive event")
+	fs.IntVar(&c.warmup, "warmup", 0,
+		"excludes the first N good pings after each DNS resolution from the latency statistics, they're still drawn")
+	fs.StringVar(&c.annotationsFile, "annotations", "",
+		"a CSV file of \"<RFC 3339 timestamp>,<label>\" lines, each drawn as a labelled vertical line on the graph")
+	fs.StringVar(&c.replayFile, "replay", "",
+		"instead of pinging, replays the points of a .pings file through the live graph, paced by their timestamps")
+	fs.StringVar(&c.serveAddr, "serve", "",
+		"serves the live capture over HTTP on this address, e.g. :8080, JSON stats at /stats and a WebSocket of each ping at /live. "+
+			"Only localhost unless a host is given")
+	fs.StringVar(&c.rotate, "rotate", "",
+		"moves the capture file aside and starts it afresh every day or once it reaches a size, either: daily or a size such as 100MB")
+	fs.StringVar(&c.rotateName, "rotate-name", c.rotateName,
+		"the name of a file moved aside by -rotate, as a Go time layout which is formatted with the time of its first ping")
+	fs.StringVar(&c.replaySpeed, "speed", c.replaySpeed, "how much faster than real time to -replay, e.g. 10x")
+	if err := fs.Parse(args); err != nil {
+		return config{}, err
+	}
+	c.showVersion = c.showVersion || fs.Arg(0) == "version"
+	c.set = setFlags(fs)
+	return c, nil
+}
+
+// validate checks every flag, parsing those which need it. The first invalid flag is the error.
+func (c *config) validate() error {
+	var err error
+	if c.pingsPerMinute, err = c.rate.pingsPerMinuteFrom(c.set); err != nil {
+		return err
+	}
+	if c.trust, err = ping.ParseDNSCacheTrust(c.dnsTrust); err != nil {
+		return err
+	}
+	if c.durationFormat, err = data.ParseDurationFormat(c.units, c.precision); err != nil {
+		return err
+	}
+	switch {
+	case c.count < 0:
+		return errors.Errorf("-n must not be negative, got %d", c.count)
+	case c.logPings && c.logFile == "":
+		return errors.New("-log-pings requires a log file, -l")
+	case c.captureDuration < 0:
+		return errors.Errorf("-duration must not be negative, got %s", c.captureDuration)
+	case c.maxXLabels < 0:
+		return errors.Errorf("-max-xlabels must not be negative, got %d", c.maxXLabels)
+	case c.warmup < 0:
+		return errors.Errorf("-warmup must not be negative, got %d", c.warmup)
+	case c.sourceAddress != "" && c.iface != "":
+		return errors.New("-source and -iface are mutually exclusive")
+	}
+	if c.render, err = graph.ParseRenderer(c.renderer); err != nil {
+		return err
+	}
+	if c.labelLevel, err = graph.ParseLabels(c.labels); err != nil {
+		return err
+	}
+	if c.yLabelPlacement, err = graph.ParseYLabels(c.yLabels); err != nil {
+		return err
+	}
+	if c.zone, err = graph.ParseReadoutZone(c.readoutZone); err != nil {
+		return err
+	}
+	if c.recentWindow, err = data.ParseWindow(c.window); err != nil {
+		return err
+	}
+	c.pointGlyphs = graph.DefaultGlyphs()
+	if c.glyphsFile != "" {
+		if c.pointGlyphs, err = graph.ReadGlyphsFile(c.glyphsFile); err != nil {
+			return err
+		}
+	}
+	if c.pointGlyphs, err = graph.ParseGlyphs(c.glyphs, c.pointGlyphs); err != nil {
+		return err
+	}
+	if c.rotation, err = parseRotation(c.rotate, c.rotateName); err != nil {
+		return err
+	}
+	if c.annotationsFile != "" {
+		if c.annotations, err = graph.ReadAnnotationsFile(c.annotationsFile); err != nil {
+			return err
+		}
+	}
+	if c.speed, err = parseSpeed(c.replaySpeed); err != nil {
+		return err
+	}
+	if c.adaptive {
+		if err = checkAdaptiveRate(c.adaptiveRate, c.pingsPerMinute); err != nil {
+			return err
+		}
+	}
+	if c.serveAddr != "" {
+		if c.listenAddr, err = server.ListenAddr(c.serveAddr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newPing is the pinger configured by the flags.
+func (c config) newPing() (*ping.Ping, error) {
+	p := ping.NewPingWithTrust(c.trust)
+	if c.monotonic {
+		p.UseMonotonicClock()
+	}
+	if c.outOfOrder {
+		p.RecordOutOfOrder()
+	}
+	if c.adaptive {
+		p.UseAdaptiveRate(c.adaptiveRate)
+	}
+	if err := p.UseTOS(c.tos); err != nil {
+		return nil, err
+	}
+	if c.sourceAddress != "" {
+		if err := p.UseSourceAddress(c.sourceAddress); err != nil {
+			return nil, err
+		}
+	}
+	if c.iface != "" {
+		if err := p.UseInterface(c.iface); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// listen is the listener to -serve the capture on, nil if it isn't served.
+func (c config) listen() (net.Listener, error) {
+	if c.listenAddr == "" {
+		return nil, nil //nolint:nilnil // Not serving isn't an error.
+	}
+	listener, err := net.Listen("tcp", c.listenAddr)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't -serve")
+	}
+	return listener, nil
+}
+
+// configure sets every option of the graph chosen by the flags.
+func (c config) configure(g *graph.Graph) {
+	g.SetSynchronizedOutput(!c.noSync)
+	g.SetForceGradients(c.forceGradients)
+	g.SetTrueColour(c.trueColour)
+	g.SetASCII(c.asciiOnly)
+	g.SetReverseX(c.reverseX)
+	g.SetMaxXLabels(c.maxXLabels)
+	g.SetRelativeX(c.relativeX)
+	g.SetMarkLongestOutage(c.markOutage)
+	g.SetMarkLatest(c.markLatest)
+	g.SetLossGauge(c.lossGauge)
+	g.SetShowMarkers(!c.noMarkers)
+	g.SetGrid(c.grid)
+	g.SetPanel(c.panel)
+	g.SetGapStats(c.gapStats)
+	g.SetRenderer(c.render)
+	g.SetLabels(c.labelLevel)
+	g.SetYLabels(c.yLabelPlacement)
+	g.SetWindow(c.recentWindow)
+	g.SetReadoutZone(c.zone)
+	g.SetGlyphs(c.pointGlyphs)
+	g.SetLogPings(c.logPings)
+	g.SetDurationFormat(c.durationFormat)
+	g.SetWarmup(c.warmup)
+	g.SetAnnotations(c.annotations)
+}
@@ -0,0 +1,62 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package main
+
+import (
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/Lexer747/AcciPing/graph"
+	"github.com/stretchr/testify/require"
+)
+
+func parseTestConfig(t *testing.T, args ...string) config {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	cfg, err := parseConfig(fs, args)
+	require.NoError(t, err)
+	return cfg
+}
+
+func TestConfig_defaults(t *testing.T) {
+	t.Parallel()
+	cfg := parseTestConfig(t)
+	require.NoError(t, cfg.validate())
+	require.InDelta(t, 60, cfg.pingsPerMinute, 0)
+	require.InDelta(t, 1, cfg.speed, 0)
+	require.Equal(t, graph.DefaultGlyphs(), cfg.pointGlyphs)
+	require.Empty(t, cfg.listenAddr)
+	listener, err := cfg.listen()
+	require.NoError(t, err)
+	require.Nil(t, listener, "not serving")
+	require.False(t, cfg.showVersion)
+
+	require.True(t, parseTestConfig(t, "version").showVersion)
+	require.True(t, parseTestConfig(t, "-version").showVersion)
+}
+
+func TestConfig_validate(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		args     []string
+		expected string
+	}{
+		{args: []string{"-n", "-1"}, expected: "-n must not be negative, got -1"},
+		{args: []string{"-log-pings"}, expected: "-log-pings requires a log file, -l"},
+		{args: []string{"-duration", "-1s"}, expected: "-duration must not be negative, got -1s"},
+		{args: []string{"-max-xlabels", "-1"}, expected: "-max-xlabels must not be negative, got -1"},
+		{args: []string{"-warmup", "-1"}, expected: "-warmup must not be negative, got -1"},
+		{args: []string{"-source", "192.168.1.5", "-iface", "eth1"}, expected: "-source and -iface are mutually exclusive"},
+		{args: []string{"-pings-per-minute", "1", "-interval", "1s"}, expected: "mutually exclusive"},
+		{args: []string{"-adaptive", "-pings-per-minute", "600"}, expected: "-adaptive-rate must be faster"},
+	} {
+		cfg := parseTestConfig(t, tc.args...)
+		require.ErrorContains(t, cfg.validate(), tc.expected, "%v", tc.args)
+	}
+}
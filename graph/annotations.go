@@ -0,0 +1,100 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package graph
+
+import (
+	"cmp"
+	"encoding/csv"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/graph/terminal"
+	"github.com/Lexer747/AcciPing/graph/terminal/ansi"
+	"github.com/Lexer747/AcciPing/graph/terminal/typography"
+	"github.com/Lexer747/AcciPing/utils/errors"
+)
+
+// Annotation marks a moment of interest on the graph (e.g. a deploy), drawn as a vertical line with the label
+// at the top.
+type Annotation struct {
+	Time  time.Time
+	Label string
+}
+
+// ReadAnnotations reads annotations from CSV where each record is an RFC 3339 timestamp followed by the label,
+// e.g. "2024-08-02T20:00:00Z,deploy v1.2". Lines beginning with '#' are ignored.
+func ReadAnnotations(r io.Reader) ([]Annotation, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't read annotations")
+	}
+	ret := make([]Annotation, 0, len(records))
+	for _, record := range records {
+		t, err := time.Parse(time.RFC3339, record[0])
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't read annotation %q", record[1])
+		}
+		ret = append(ret, Annotation{Time: t, Label: record[1]})
+	}
+	return ret, nil
+}
+
+// ReadAnnotationsFile is [ReadAnnotations] for the file with the given name.
+func ReadAnnotationsFile(name string) ([]Annotation, error) {
+	f, err := os.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't open annotations")
+	}
+	defer f.Close()
+	return ReadAnnotations(f)
+}
+
+// maxAnnotationLabel is the most of a label which is drawn, labels are also cut short so that they don't run
+// into the next annotation.
+const maxAnnotationLabel = 12
+
+// drawAnnotations draws a dashed vertical line for each annotation inside the span of the data, with the label
+// starting just after it along the top of the graph.
+func drawAnnotations(b *strings.Builder, annotations []Annotation, d *data.Data, s terminal.Size, labelSize int, reverse bool) {
+	type column struct {
+		x     int
+		label string
+	}
+	span := d.Header.TimeSpan
+	columns := make([]column, 0, len(annotations))
+	for _, a := range annotations {
+		if a.Time.Before(span.Begin) || a.Time.After(span.End) {
+			continue
+		}
+		columns = append(columns, column{x: getX(a.Time, d.Header, s, labelSize, reverse), label: a.Label})
+	}
+	slices.SortStableFunc(columns, func(a, b column) int { return cmp.Compare(a.x, b.x) })
+	line := strings.Repeat(
+		ansi.Gray(typography.DashedVertical)+ansi.CursorDown(1)+ansi.CursorBack(1),
+		max(s.Height-3, 0),
+	)
+	for i, c := range columns {
+		b.WriteString(ansi.CursorPosition(3, c.x) + line)
+		space := s.Width - c.x
+		if i+1 < len(columns) {
+			space = columns[i+1].x - c.x - 1
+		}
+		label := []rune(c.label)
+		label = label[:max(min(len(label), space, maxAnnotationLabel), 0)]
+		if len(label) > 0 {
+			b.WriteString(ansi.CursorPosition(2, c.x) + ansi.Blue(string(label)))
+		}
+	}
+}
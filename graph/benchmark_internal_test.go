@@ -0,0 +1,86 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package graph
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/graph/terminal"
+	"github.com/Lexer747/AcciPing/graph/terminal/th"
+	"github.com/Lexer747/AcciPing/ping"
+	"github.com/stretchr/testify/require"
+)
+
+var benchmarkSizes = []terminal.Size{
+	{Height: 25, Width: 80},
+	{Height: 50, Width: 200},
+	{Height: 100, Width: 400},
+}
+
+// BenchmarkComputeFrame measures drawing every point from scratch, as happens whenever the terminal is resized
+// or a new point arrives. Compare runs with benchstat, e.g.
+//
+//	go test ./graph -run '^$' -bench ComputeFrame -count 10 > old.txt
+func BenchmarkComputeFrame(b *testing.B) {
+	for _, points := range []int{1_000, 10_000, 100_000, 1_000_000} {
+		d := benchmarkData(points)
+		for _, size := range benchmarkSizes {
+			b.Run(fmt.Sprintf("points=%d/%dx%d", points, size.Width, size.Height), func(b *testing.B) {
+				g := benchmarkGraph(b, size, d)
+				b.ReportAllocs()
+				b.ResetTimer()
+				for range b.N {
+					g.dataMutex.Lock()
+					g.invalidateFrame()
+					g.dataMutex.Unlock()
+					if g.ComputeFrame() == "" {
+						b.Fatal("frame wasn't drawn")
+					}
+				}
+			})
+		}
+	}
+}
+
+// benchmarkData is a capture of [points] pings a second apart, from a fixed seed so that every run draws the
+// same frames. Every 50th packet is dropped so that the dropped packet indicators are also drawn.
+func benchmarkData(points int) *data.Data {
+	// Fixed seed, used in testing only, not sec sensitive
+	rng := rand.New(rand.NewPCG(5, 5)) //nolint:gosec
+	d := data.NewData("www.google.com")
+	ip := net.IPv4(8, 8, 8, 8)
+	for i := range points {
+		p := ping.PingDataPoint{
+			Duration:  time.Duration(rng.Float64() * float64(10*time.Millisecond)),
+			Timestamp: time.Time{}.Add(time.Duration(i) * time.Second),
+		}
+		if i%50 == 49 {
+			p = ping.PingDataPoint{DropReason: ping.TestDrop, Timestamp: p.Timestamp}
+		}
+		d.AddPoint(ping.PingResults{Data: p, IP: ip})
+	}
+	return d
+}
+
+func benchmarkGraph(b testing.TB, size terminal.Size, d *data.Data) *Graph {
+	b.Helper()
+	_, _, term, setTerm, err := th.NewTestTerminal()
+	require.NoError(b, err)
+	setTerm(size)
+	ctx, cancel := context.WithCancel(context.Background())
+	// cancel this, we don't want the graph collecting from the channel in the background
+	cancel()
+	g, err := NewGraphWithData(ctx, make(chan ping.PingResults), term, 0, d)
+	require.NoError(b, err)
+	return g
+}
@@ -0,0 +1,127 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package graph
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Lexer747/AcciPing/graph/terminal"
+	"github.com/Lexer747/AcciPing/graph/terminal/ansi"
+)
+
+// SetClearScreen controls whether [Graph.OneFrame] clears the screen and draws the graph from the top left, the
+// default, or returns the graph as a block of lines which is printed wherever the cursor is. Blocks printed one
+// after another scroll like any other output, so earlier graphs are kept in the scrollback. It must be set
+// before drawing.
+func (g *Graph) SetClearScreen(enabled bool) {
+	g.clearScreen = enabled
+}
+
+// blockCell is one cell of the frame, with the SGR parameters it's drawn with, "" for the default.
+type blockCell struct {
+	r      rune
+	colour string
+}
+
+// frameBlock plays the frame, which is drawn with absolute cursor positions, onto a grid of the size and returns
+// the grid as lines ending in a newline. Only the sequences the frame is drawn with are understood: moving the
+// cursor, clearing the screen and colours, anything else is dropped. Each line ends with its colour reset and
+// without trailing blank cells.
+func frameBlock(frame string, s terminal.Size) string {
+	grid := make([][]blockCell, s.Height)
+	blank := func() {
+		for row := range grid {
+			grid[row] = make([]blockCell, s.Width)
+			for col := range grid[row] {
+				grid[row][col] = blockCell{r: ' '}
+			}
+		}
+	}
+	blank()
+	runes := []rune(frame)
+	row, col, colour := 0, 0, ""
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '\033' {
+			if row >= 0 && row < s.Height && col >= 0 && col < s.Width {
+				grid[row][col] = blockCell{r: runes[i], colour: colour}
+			}
+			col++
+			continue
+		}
+		if i+1 >= len(runes) || runes[i+1] != '[' {
+			continue
+		}
+		// A CSI is terminated by a byte in the range 0x40 through 0x7E.
+		end := i + 2
+		for end < len(runes) && (runes[end] < 0x40 || runes[end] > 0x7e) {
+			end++
+		}
+		if end >= len(runes) {
+			break
+		}
+		params := string(runes[i+2 : end])
+		switch runes[end] {
+		case 'H':
+			r, c, _ := strings.Cut(params, ";")
+			row, col = blockParam(r)-1, blockParam(c)-1
+		case 'A':
+			row -= blockParam(params)
+		case 'B':
+			row += blockParam(params)
+		case 'C':
+			col += blockParam(params)
+		case 'D':
+			col -= blockParam(params)
+		case 'J':
+			if params == strconv.Itoa(int(ansi.CursorScreen)) {
+				blank()
+			}
+		case 'm':
+			colour = params
+			if params == "0" {
+				colour = ""
+			}
+		}
+		i = end
+	}
+
+	var b strings.Builder
+	for _, line := range grid {
+		last := len(line)
+		for last > 0 && line[last-1] == (blockCell{r: ' '}) {
+			last--
+		}
+		current := ""
+		for _, cell := range line[:last] {
+			if cell.colour != current {
+				if current != "" {
+					b.WriteString(ansi.R)
+				}
+				if cell.colour != "" {
+					b.WriteString(ansi.CSI + cell.colour + "m")
+				}
+				current = cell.colour
+			}
+			b.WriteRune(cell.r)
+		}
+		if current != "" {
+			b.WriteString(ansi.R)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// blockParam is a numeric CSI parameter, which defaults to 1 when omitted.
+func blockParam(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil || n == 0 {
+		return 1
+	}
+	return n
+}
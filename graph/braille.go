@@ -0,0 +1,151 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package graph
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/Lexer747/AcciPing/graph/terminal"
+	"github.com/Lexer747/AcciPing/graph/terminal/ansi"
+	"github.com/Lexer747/AcciPing/utils/errors"
+	"github.com/Lexer747/AcciPing/utils/numeric"
+)
+
+// Renderer is how the points of the graph are drawn, see [Graph.SetRenderer].
+type Renderer int
+
+const (
+	// DefaultRenderer draws each point as a single glyph, with gradients between them.
+	DefaultRenderer Renderer = iota
+	// BrailleRenderer plots each point as a dot of a braille character, each of which has 2 columns and 4 rows
+	// of dots. Consecutive points are joined by a line of dots, broken by dropped packets.
+	BrailleRenderer
+)
+
+// ParseRenderer parses the name of a [Renderer], one of "default" or "braille".
+func ParseRenderer(renderer string) (Renderer, error) {
+	switch renderer {
+	case "default":
+		return DefaultRenderer, nil
+	case "braille":
+		return BrailleRenderer, nil
+	default:
+		return DefaultRenderer, errors.Errorf("Unknown renderer %q, expected one of: default|braille", renderer)
+	}
+}
+
+func (opts drawOptions) braille() bool {
+	return opts.renderer == BrailleRenderer && !opts.ascii
+}
+
+const (
+	brailleWidth  = 2
+	brailleHeight = 4
+	brailleBlank  = 0x2800
+)
+
+// brailleDots are the bits of each dot in a braille character, indexed by [column][row].
+var brailleDots = [brailleWidth][brailleHeight]rune{
+	{0x01, 0x02, 0x04, 0x40},
+	{0x08, 0x10, 0x20, 0x80},
+}
+
+type brailleCell struct{ row, column int }
+
+// brailleCanvas accumulates the dots of every point before they are drawn, since each character may hold the
+// dots of many points.
+type brailleCanvas struct {
+	s         terminal.Size
+	labelSize int
+	cells     map[brailleCell]rune
+	// last is the dot of the previous point, the next point is joined to it. Nil after a dropped packet.
+	last *[2]int
+}
+
+func newBrailleCanvas(s terminal.Size, labelSize int) *brailleCanvas {
+	return &brailleCanvas{s: s, labelSize: labelSize, cells: map[brailleCell]rune{}}
+}
+
+// plot adds a point at the position (see [xPosition] and [yPosition]), joined to the previous point.
+func (c *brailleCanvas) plot(x, y float64) {
+	dot := [2]int{
+		min(max(int(x*brailleWidth), c.labelSize*brailleWidth), c.s.Width*brailleWidth-1),
+		// Like [getY] points outside the range are pinned to the edge of the graph.
+		min(max(int(y*brailleHeight), 2*brailleHeight), c.s.Height*brailleHeight-1),
+	}
+	if c.last == nil {
+		c.set(dot[0], dot[1])
+	} else {
+		c.line(*c.last, dot)
+	}
+	c.last = &dot
+}
+
+// lift stops the next point being joined to the previous one.
+func (c *brailleCanvas) lift() {
+	c.last = nil
+}
+
+func (c *brailleCanvas) set(x, y int) {
+	cell := brailleCell{row: y / brailleHeight, column: x / brailleWidth}
+	c.cells[cell] |= brailleDots[x%brailleWidth][y%brailleHeight]
+}
+
+// line sets every dot between from and to, using Bresenham's line algorithm.
+func (c *brailleCanvas) line(from, to [2]int) {
+	dx, dy := numeric.Abs(to[0]-from[0]), -numeric.Abs(to[1]-from[1])
+	sx, sy := 1, 1
+	if from[0] > to[0] {
+		sx = -1
+	}
+	if from[1] > to[1] {
+		sy = -1
+	}
+	err := dx + dy
+	x, y := from[0], from[1]
+	for {
+		c.set(x, y)
+		if x == to[0] && y == to[1] {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y += sy
+		}
+	}
+}
+
+// empty is true when there are no dots in the columns [from, to) of the row, like [drawWindow.empty].
+func (c *brailleCanvas) empty(row, from, to int) bool {
+	for column := from; column < to; column++ {
+		if _, ok := c.cells[brailleCell{row: row, column: column}]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+// draw writes every cell in order, so that the frame is the same each time it's drawn.
+func (c *brailleCanvas) draw(b *strings.Builder) {
+	cells := make([]brailleCell, 0, len(c.cells))
+	for cell := range c.cells {
+		cells = append(cells, cell)
+	}
+	slices.SortFunc(cells, func(a, b brailleCell) int {
+		return cmp.Or(cmp.Compare(a.row, b.row), cmp.Compare(a.column, b.column))
+	})
+	for _, cell := range cells {
+		b.WriteString(ansi.CursorPosition(cell.row, cell.column) + ansi.White(string(brailleBlank+c.cells[cell])))
+	}
+}
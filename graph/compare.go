@@ -0,0 +1,95 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package graph
+
+import (
+	"cmp"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/graph/terminal"
+	"github.com/Lexer747/AcciPing/graph/terminal/ansi"
+	"github.com/Lexer747/AcciPing/graph/terminal/typography"
+)
+
+// SetCompare draws another capture over the graph, for comparing two network paths. Both are drawn on the same
+// axes: the y-axis from the fastest to the slowest point of either and the x-axis across the time spanned by
+// both. The other capture's points and lines are drawn in magenta and a legend in the top right names each
+// capture with its mean. The stats in the title are of both captures together. The braille renderer can't
+// colour each dot so the default renderer is used while comparing. The graph doesn't take ownership of the
+// other data, it mustn't be changed while the graph draws it. Nil, the default, draws only the graph's data.
+func (g *Graph) SetCompare(other *data.Data, name, otherName string) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.compare = other
+	g.options.compareNames = [2]string{name, otherName}
+	g.invalidateFrame()
+}
+
+// comparing is true when there's another capture with any points to draw, see [Graph.SetCompare].
+func (opts drawOptions) comparing() bool {
+	return opts.compare != nil && opts.compare.TotalCount > 0
+}
+
+// sharedScale copies both data (not their points) with a header spanning both, so that when each is drawn they
+// share the same axes.
+func sharedScale(a, b *data.Data) (*data.Data, *data.Data) {
+	span := *a.Header.TimeSpan
+	if a.TotalCount == 0 {
+		span = *b.Header.TimeSpan
+	} else {
+		span.AddTimestamp(b.Header.TimeSpan.Begin)
+		span.AddTimestamp(b.Header.TimeSpan.End)
+	}
+	header := &data.Header{Stats: data.Merge(a.Header.Stats, b.Header.Stats), TimeSpan: &span}
+	scaledA, scaledB := *a, *b
+	scaledA.Header, scaledB.Header = header, header
+	return &scaledA, &scaledB
+}
+
+// compareColour is the colour of the other capture, see [Graph.SetCompare].
+var compareColour = ansi.Magenta
+
+// comparePalette is the palette for the other capture, the points are drawn in the [compareColour] rather than
+// white. Everything drawn along the top (internal errors and out of order replies) is the same for both.
+func (g Glyphs) comparePalette() palette {
+	ret := g.palette()
+	defaults := DefaultGlyphs()
+	point := cmp.Or(g.Point, defaults.Point)
+	ret.plain = compareColour(point)
+	ret.warmup = compareColour(point)
+	ret.drop = ansi.DarkMagenta(cmp.Or(g.Dropped, defaults.Dropped))
+	return ret
+}
+
+// compareLine is the colour of the lines between the other capture's points, see [drawGradients].
+func compareLine(_ time.Duration, s string) string {
+	return ansi.DarkMagenta(s)
+}
+
+// compareLegend names each capture in the colour it's drawn, right aligned along the top of the graph, e.g.
+// "× a.pings μ 8ms  × b.pings μ 12ms". The means are left out if they don't fit, then the legend.
+func compareLegend(s terminal.Size, labelSize int, d, other *data.Data, names [2]string, opts drawOptions) string {
+	point := cmp.Or(opts.glyphSet.Point, DefaultGlyphs().Point)
+	space := s.Width - labelSize - 2
+	mean := func(d *data.Data) string {
+		return " " + typography.Mu + " " + opts.durationFormat.Format(time.Duration(d.Header.Stats.Mean).Round(time.Microsecond))
+	}
+	for _, withMeans := range []bool{true, false} {
+		first, second := point+" "+names[0], point+" "+names[1]
+		if withMeans {
+			first += mean(d)
+			second += mean(other)
+		}
+		width := utf8.RuneCountInString(first) + 2 + utf8.RuneCountInString(second)
+		if width <= space {
+			return ansi.CursorPosition(2, s.Width-width) + ansi.White(first) + "  " + compareColour(second)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,112 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package graph
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/graph/terminal"
+	"github.com/Lexer747/AcciPing/graph/terminal/ansi"
+	"github.com/Lexer747/AcciPing/graph/terminal/typography"
+	"github.com/Lexer747/AcciPing/utils/numeric"
+)
+
+var cursorLine = ansi.Gray(typography.DottedVertical)
+
+// moveCursor moves the inspection cursor a column left or right, the first press shows it on the newest point.
+// Escape hides it. Should be called with the dataMutex held.
+func (g *Graph) moveCursor(r rune) {
+	first, last := g.plotColumns[0], g.plotColumns[1]
+	if last == 0 {
+		return // Nothing drawn yet
+	}
+	cursor := g.options.cursor
+	switch {
+	case r == escape:
+		cursor = 0
+	case cursor == 0 && g.options.reverseX:
+		cursor = first
+	case cursor == 0:
+		cursor = last
+	case r == terminal.KeyLeft:
+		cursor--
+	case r == terminal.KeyRight:
+		cursor++
+	}
+	if cursor != 0 {
+		cursor = min(max(cursor, first), last)
+	}
+	g.options.cursor = cursor
+	g.invalidateFrame()
+}
+
+const escape = '\x1b'
+
+// cursorColumn is the column the inspection cursor is drawn in, kept within the points.
+func (opts drawOptions) cursorColumn(s terminal.Size, labelSize int) int {
+	return min(max(opts.cursor, labelSize), s.Width-1)
+}
+
+// drawCursorLine draws the inspection cursor as a faint column, it's drawn behind the points.
+func drawCursorLine(b *strings.Builder, s terminal.Size, column int) {
+	for row := 2; row < s.Height; row++ {
+		b.WriteString(ansi.CursorPosition(row, column) + cursorLine)
+	}
+}
+
+// drawCursorReadout draws when the point nearest to the inspection cursor was sent, in the readout zone, and
+// its latency, or why it was dropped. The readout is drawn along the top of the graph on the side of the cursor
+// with more space.
+func drawCursorReadout(b *strings.Builder, d *data.Data, s terminal.Size, labelSize, column int, opts drawOptions) {
+	p := d.Get(nearestPoint(d, columnTime(column, d.Header, s, labelSize, opts.reverseX)))
+	format := opts.timeFormat
+	if format == "" {
+		format = defaultTimeFormat
+	}
+	value := p.Duration.String()
+	if !p.Good() {
+		value = p.DropReason.String()
+	}
+	readout := " " + opts.readoutZone.format(p.Timestamp, format, opts.fileZone) + " " + value + " "
+	start := column + 1
+	if column > (s.Width+labelSize)/2 {
+		start = column - utf8.RuneCountInString(readout)
+	}
+	b.WriteString(ansi.CursorPosition(2, max(start, labelSize+1)) + ansi.Cyan(readout))
+}
+
+// columnTime is the inverse of [getX], the time in the middle of the column.
+func columnTime(column int, info *data.Header, s terminal.Size, labelSize int, reverse bool) time.Time {
+	if info.TimeSpan.Duration == 0 {
+		return info.TimeSpan.End
+	}
+	newest, oldest := float64(s.Width-1), float64(labelSize)
+	if reverse {
+		newest, oldest = oldest, newest
+	}
+	age := numeric.NormalizeToRange(float64(column)+0.5, newest, oldest, 0, float64(info.TimeSpan.Duration))
+	return info.TimeSpan.End.Add(-time.Duration(age))
+}
+
+// nearestPoint is the index of the point with the timestamp closest to the time, the data must not be empty.
+func nearestPoint(d *data.Data, t time.Time) int64 {
+	i := int64(sort.Search(int(d.TotalCount), func(i int) bool { return !d.Get(int64(i)).Timestamp.Before(t) }))
+	switch {
+	case i == d.TotalCount:
+		return i - 1
+	case i == 0:
+		return 0
+	case t.Sub(d.Get(i-1).Timestamp) <= d.Get(i).Timestamp.Sub(t):
+		return i - 1
+	default:
+		return i
+	}
+}
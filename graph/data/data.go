@@ -11,14 +11,20 @@ import (
 	"math"
 	"net"
 	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Lexer747/AcciPing/ping"
+	"github.com/Lexer747/AcciPing/utils/check"
+	"github.com/Lexer747/AcciPing/utils/errors"
 	"github.com/Lexer747/AcciPing/utils/numeric"
 	"github.com/Lexer747/AcciPing/utils/sliceutils"
 )
 
+// Data is every point of a capture along with the statistics about them. Data isn't safe for concurrent use,
+// either guard it with a lock of your own or use a [SafeData].
 type Data struct {
 	URL         string
 	Header      *Header
@@ -26,7 +32,15 @@ type Data struct {
 	InsertOrder []DataIndexes
 	Blocks      []*Block
 	TotalCount  int64
-	Version     byte
+	// Version is the version of the compact format this data was read from, data is always written in the
+	// current version.
+	Version byte
+	// Location is the name of the time zone the data was captured in (e.g. "Europe/London"), empty if it's
+	// not known in which case timestamps are shown in the local time zone. See [Data.In].
+	Location string
+	// DuplicatesSkipped counts the points not added by [Data.AddPointDedup]. This isn't part of the compact
+	// format.
+	DuplicatesSkipped uint64
 }
 
 type DataIndexes struct {
@@ -46,7 +60,13 @@ func NewData(URL string) *Data {
 	return d
 }
 
+// AddPoint adds the point to the data. A good point with a negative latency can only come from something going
+// wrong on this machine (e.g. the clock jumping backwards), so it's added as a [ping.InternalError] rather than
+// being counted in the stats.
 func (d *Data) AddPoint(p ping.PingResults) {
+	if p.Data.Good() && p.Data.Duration < 0 {
+		p.Data.DropReason = ping.InternalError
+	}
 	blockIndex := d.Network.AddPoint(p.IP)
 	if blockIndex >= len(d.Blocks) {
 		d.addBlock()
@@ -61,6 +81,22 @@ func (d *Data) AddPoint(p ping.PingResults) {
 	})
 }
 
+// AddPointDedup is [Data.AddPoint] except that a point which is [ping.PingDataPoint.Equal] to the previous
+// point for the same IP is skipped and counted in [Data.DuplicatesSkipped], returning false. Useful when
+// combining captures which may overlap, it shouldn't be used while capturing since two adjacent pings can
+// legitimately be identical.
+func (d *Data) AddPointDedup(p ping.PingResults) bool {
+	if blockIndex, found := d.Network.blockIndex(p.IP); found {
+		raw := d.getBlock(blockIndex).Raw
+		if len(raw) > 0 && raw[len(raw)-1].Equal(p.Data) {
+			d.DuplicatesSkipped++
+			return false
+		}
+	}
+	d.AddPoint(p)
+	return true
+}
+
 func (d *Data) Get(index int64) ping.PingDataPoint {
 	this := d.InsertOrder[index]
 	return d.Blocks[this.BlockIndex].Raw[this.RawIndex]
@@ -75,6 +111,196 @@ func (d *Data) GetFull(index int64) ping.PingResults {
 		IP:   ip,
 	}
 }
+
+// Percentile returns the duration below which [percentile] percent of the good (not dropped) points fall,
+// using the nearest-rank method. Zero is returned if there are no good points. Panic's if the percentile isn't
+// in the range [0,100].
+func (d *Data) Percentile(percentile float64) time.Duration {
+	return d.Latencies().Percentile(percentile)
+}
+
+// Latencies are the latency of every good point, fastest first. Sorting them is the expensive part of
+// [Data.Percentile], so when more than one percentile is wanted sort them once and take each from here.
+type Latencies []time.Duration
+
+// Latencies sorts the latency of every good point, see [Latencies.Percentile].
+func (d *Data) Latencies() Latencies {
+	return d.sortedDurations()
+}
+
+// Percentile is the same as [Data.Percentile] for the data these latencies were sorted from.
+func (l Latencies) Percentile(percentile float64) time.Duration {
+	check.Checkf(percentile >= 0 && percentile <= 100, "Percentile %f out of range [0,100]", percentile)
+	if len(l) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil((percentile / 100) * float64(len(l))))
+	return l[max(rank-1, 0)]
+}
+
+// Range returns an iterator over the points with a timestamp in [from, to] inclusive, yielding the index of
+// each point (suitable for [Data.GetFull]) along with the point, in insert order. Points are inserted in
+// timestamp order so the bounds are binary searched rather than every point being scanned.
+//
+// Shaped as a range-over-func iterator, until the module requires go1.23 call it directly with the yield
+// function, iteration stops early when it returns false.
+func (d *Data) Range(from, to time.Time) func(yield func(int64, ping.PingDataPoint) bool) {
+	return func(yield func(int64, ping.PingDataPoint) bool) {
+		first := sort.Search(int(d.TotalCount), func(i int) bool { return !d.Get(int64(i)).Timestamp.Before(from) })
+		for i := int64(first); i < d.TotalCount; i++ {
+			p := d.Get(i)
+			if p.Timestamp.After(to) || !yield(i, p) {
+				return
+			}
+		}
+	}
+}
+
+// Warmup is the first few good packets after each DNS resolution, which are often anomalously slow, see
+// [Data.Warmup].
+type Warmup struct {
+	d *Data
+	n int
+	// points are the indexes (see [Data.Get]) of the warmup packets in insert order.
+	points []int64
+	// scanned is how many points have been looked at, the rest is where the scan got to.
+	scanned int64
+	good    int
+	block   int
+	last    time.Time
+}
+
+// Warmup finds the first n good packets after each DNS resolution. The data doesn't record when DNS was
+// queried, so a resolution is taken to be the start of each span (see [DetectSpans] with the default gap), a
+// good packet from a different IP than the last (only one IP is kept from each resolution) or a failure to
+// resolve or to listen which is retried. Dropped packets within them are not part of the warmup.
+func (d *Data) Warmup(n int) *Warmup {
+	w := &Warmup{d: d, n: n, block: -1}
+	w.Update()
+	return w
+}
+
+// Update finds the warmup packets among the points added since the warmup was found or last updated.
+func (w *Warmup) Update() {
+	for ; w.scanned < w.d.TotalCount; w.scanned++ {
+		i := w.scanned
+		p := w.d.Get(i)
+		if i == 0 || p.Timestamp.Sub(w.last) > DefaultSpanGap {
+			w.good = 0
+		}
+		w.last = p.Timestamp
+		switch {
+		case p.DropReason == ping.DNSFailure || p.DropReason == ping.ListenFailure:
+			w.good = 0
+			continue
+		case !p.Good():
+			continue
+		}
+		if block := w.d.InsertOrder[i].BlockIndex; block != w.block {
+			w.good = 0
+			w.block = block
+		}
+		if w.good < w.n {
+			w.good++
+			w.points = append(w.points, i)
+		}
+	}
+}
+
+// Contains is true when the point at the index is one of the warmup packets.
+func (w *Warmup) Contains(index int64) bool {
+	_, found := slices.BinarySearch(w.points, index)
+	return found
+}
+
+// Stats are the statistics of every point except the warmup packets.
+func (w *Warmup) Stats() *Stats {
+	ret := &Stats{}
+	w.AddStats(ret, 0)
+	return ret
+}
+
+// AddStats adds every point from the index onwards, except the warmup packets, to the stats. A point is only
+// ever part of the warmup if it was when it was added, so stats of the earlier points are kept up to date by
+// adding the points since, once the warmup has been updated with them (see [Warmup.Update]).
+func (w *Warmup) AddStats(stats *Stats, from int64) {
+	for i := from; i < w.d.TotalCount; i++ {
+		p := w.d.Get(i)
+		switch {
+		case p.InternalError():
+			stats.AddInternalError()
+		case p.OutOfOrder():
+			continue
+		case p.Dropped():
+			stats.AddDroppedPacket()
+		case !w.Contains(i):
+			stats.AddPoint(p.Duration)
+		}
+	}
+}
+
+// DropEvent is a streak of consecutive dropped packets.
+type DropEvent struct {
+	// Start and End are the timestamps of the first and last packets dropped.
+	Start, End time.Time
+	Length     uint64
+}
+
+// DropEvents finds every streak of consecutive dropped packets, in the order they happened. A good packet ends
+// a streak, internal errors and out of order replies neither end nor add to a streak. These are computed from the
+// points each time so aren't part of the compact format.
+func (d *Data) DropEvents() []DropEvent {
+	ret := []DropEvent{}
+	var current *DropEvent
+	for i := range d.TotalCount {
+		p := d.Get(i)
+		switch {
+		case p.InternalError(), p.OutOfOrder():
+			continue
+		case p.Dropped() && current == nil:
+			current = &DropEvent{Start: p.Timestamp, End: p.Timestamp, Length: 1}
+		case p.Dropped():
+			current.End = p.Timestamp
+			current.Length++
+		case current != nil:
+			ret = append(ret, *current)
+			current = nil
+		}
+	}
+	if current != nil {
+		ret = append(ret, *current)
+	}
+	return ret
+}
+
+// Runs are the longest streaks of consecutive good and dropped packets, see [Data.Runs].
+type Runs struct {
+	LongestGood, LongestDropped uint64
+}
+
+// Runs finds the longest streaks of consecutive good and dropped packets. Like [Data.DropEvents] internal errors
+// and out of order replies neither end nor add to a streak.
+func (d *Data) Runs() Runs {
+	var ret Runs
+	var good, dropped uint64
+	for i := range d.TotalCount {
+		p := d.Get(i)
+		switch {
+		case p.InternalError(), p.OutOfOrder():
+			continue
+		case p.Dropped():
+			good = 0
+			dropped++
+			ret.LongestDropped = max(ret.LongestDropped, dropped)
+		default:
+			dropped = 0
+			good++
+			ret.LongestGood = max(ret.LongestGood, good)
+		}
+	}
+	return ret
+}
+
 func (d *Data) End(index int64) bool {
 	return int(index) == len(d.InsertOrder)
 }
@@ -93,8 +319,24 @@ func (d *Data) getBlock(blockIndex int) *Block {
 	return d.Blocks[blockIndex]
 }
 
+// In changes the time zone of every timestamp, this doesn't change the instant of any timestamp only how they
+// are displayed.
+func (d *Data) In(loc *time.Location) {
+	d.Header.TimeSpan.in(loc)
+	for _, block := range d.Blocks {
+		block.Header.TimeSpan.in(loc)
+		for i := range block.Raw {
+			block.Raw[i].Timestamp = block.Raw[i].Timestamp.In(loc)
+		}
+	}
+}
+
 func (d *Data) String() string {
-	return fmt.Sprintf("%s: [%s] | %s", d.URL, d.Network.String(), d.Header.String())
+	ret := fmt.Sprintf("%s: [%s] | %s", d.URL, d.Network.String(), d.Header.String())
+	if d.DuplicatesSkipped > 0 {
+		ret += fmt.Sprintf(" | Duplicates Skipped %d", d.DuplicatesSkipped)
+	}
+	return ret
 }
 
 // TimeSpan is the time properties of a given thing
@@ -104,6 +346,11 @@ type TimeSpan struct {
 	Duration time.Duration
 }
 
+func (ts *TimeSpan) in(loc *time.Location) {
+	ts.Begin = ts.Begin.In(loc)
+	ts.End = ts.End.In(loc)
+}
+
 func (ts *TimeSpan) AddTimestamp(t time.Time) {
 	if ts.Begin.After(t) {
 		ts.Begin = t
@@ -121,12 +368,17 @@ type Header struct {
 }
 
 func (h *Header) AddPoint(p ping.PingDataPoint) {
-	if h.Stats.GoodCount == 0 {
+	if h.Stats.GoodCount == 0 && h.Stats.PacketsDropped == 0 && h.Stats.InternalErrors == 0 {
 		h.TimeSpan = &TimeSpan{Begin: p.Timestamp, End: p.Timestamp}
 	} else {
 		h.TimeSpan.AddTimestamp(p.Timestamp)
 	}
-	if p.Dropped() {
+	if p.InternalError() {
+		h.Stats.AddInternalError()
+	} else if p.OutOfOrder() {
+		// The probe the reply was for has already been counted as dropped.
+		return
+	} else if p.Dropped() {
 		h.Stats.AddDroppedPacket()
 	} else {
 		h.Stats.AddPoint(p.Duration)
@@ -148,10 +400,7 @@ type Network struct {
 // AddPoint will insert the IP into the network and return the block index for this IP, noting that it will
 // return an out of range index if this IP has not been seen before.
 func (n *Network) AddPoint(ip net.IP) int {
-	ip = ip.To16() // Ensure all saved IPs are in IPv6 format
-	if ip == nil {
-		ip = net.IPv6zero // DNS failure, etc
-	}
+	ip = normaliseIP(ip)
 	i, found := slices.BinarySearchFunc(n.IPs, ip, ipOrdering)
 	if found {
 		return n.BlockIndexes[i]
@@ -163,8 +412,25 @@ func (n *Network) AddPoint(ip net.IP) int {
 	return cur
 }
 
+// blockIndex is the block index for this IP, if it has been seen before.
+func (n *Network) blockIndex(ip net.IP) (int, bool) {
+	i, found := slices.BinarySearchFunc(n.IPs, normaliseIP(ip), ipOrdering)
+	if !found {
+		return 0, false
+	}
+	return n.BlockIndexes[i], true
+}
+
+func normaliseIP(ip net.IP) net.IP {
+	ip = ip.To16() // Ensure all saved IPs are in IPv6 format
+	if ip == nil {
+		ip = net.IPv6zero // DNS failure, etc
+	}
+	return ip
+}
+
 func (n *Network) String() string {
-	return sliceutils.Join(n.IPs, ",")
+	return strings.Join(sliceutils.Map(n.IPs, FormatIP), ",")
 }
 
 type Block struct {
@@ -186,7 +452,10 @@ type Stats struct {
 	Variance          float64
 	StandardDeviation float64
 	PacketsDropped    uint64
-	sumOfSquares      float64
+	// InternalErrors counts the points where this machine failed to ping, they are not counted as either good
+	// or dropped packets. This isn't part of the compact format, it's re-counted from the points when read.
+	InternalErrors uint64
+	sumOfSquares   float64
 }
 
 func (s Stats) PacketLoss() float64 {
@@ -197,6 +466,10 @@ func (s *Stats) AddDroppedPacket() {
 	s.PacketsDropped++
 }
 
+func (s *Stats) AddInternalError() {
+	s.InternalErrors++
+}
+
 // TODO float imprecision
 // TODO https://en.wikipedia.org/wiki/Kahan_summation_algorithm
 // Math proof for why this works:
@@ -235,9 +508,83 @@ func (s *Stats) AddPoints(values []time.Duration) {
 	}
 }
 
+// Merge combines the stats into new stats, as if every point had been added to one. See [MergeInto] to avoid
+// the allocation.
 func Merge(stats ...*Stats) *Stats {
-	// https://en.wikipedia.org/wiki/Algorithms_for_calculating_variance#Weighted_incremental_algorithm
-	panic("todo")
+	ret := &Stats{}
+	for _, s := range stats {
+		MergeInto(ret, ret, s)
+	}
+	return ret
+}
+
+// MergeInto combines a and b into dst, as if every point of both had been added to one. dst may be a or b, so
+// that a scratch Stats can be re-used (see [Stats.Reset]) instead of allocating.
+func MergeInto(dst, a, b *Stats) {
+	// https://en.wikipedia.org/wiki/Algorithms_for_calculating_variance#Parallel_algorithm
+	merged := Stats{
+		PacketsDropped: a.PacketsDropped + b.PacketsDropped,
+		InternalErrors: a.InternalErrors + b.InternalErrors,
+		GoodCount:      a.GoodCount + b.GoodCount,
+	}
+	switch {
+	case a.GoodCount == 0:
+		merged.Min, merged.Max, merged.Mean, merged.sumOfSquares = b.Min, b.Max, b.Mean, b.sumOfSquares
+	case b.GoodCount == 0:
+		merged.Min, merged.Max, merged.Mean, merged.sumOfSquares = a.Min, a.Max, a.Mean, a.sumOfSquares
+	default:
+		merged.Min = min(a.Min, b.Min)
+		merged.Max = max(a.Max, b.Max)
+		countA, countB, count := float64(a.GoodCount), float64(b.GoodCount), float64(merged.GoodCount)
+		delta := b.Mean - a.Mean
+		merged.Mean = a.Mean + delta*countB/count
+		merged.sumOfSquares = a.sumOfSquares + b.sumOfSquares + delta*delta*countA*countB/count
+	}
+	if merged.GoodCount >= 2 {
+		merged.Variance = merged.sumOfSquares / float64(merged.GoodCount-1)
+		merged.StandardDeviation = math.Sqrt(merged.Variance)
+	}
+	*dst = merged
+}
+
+// Reset clears the stats as if no points had been added, so they can be re-used.
+func (s *Stats) Reset() {
+	*s = Stats{}
+}
+
+// StatsSnapshot is a copy of the [Stats] at one moment, only the derived numbers and none of the state used to
+// add points. It's a value which can be handed out while the stats it came from carry on changing.
+type StatsSnapshot struct {
+	Min, Max          time.Duration
+	Mean              float64
+	Variance          float64
+	StandardDeviation float64
+	GoodCount         uint64
+	PacketsDropped    uint64
+	InternalErrors    uint64
+	// Count is every packet sent, good or dropped.
+	Count uint64
+	// PacketLoss is the fraction of packets dropped, zero when there are none.
+	PacketLoss float64
+}
+
+// Snapshot copies the stats as they are now, see [StatsSnapshot].
+func (s Stats) Snapshot() StatsSnapshot {
+	ret := StatsSnapshot{
+		Min:               s.Min,
+		Max:               s.Max,
+		Mean:              s.Mean,
+		Variance:          s.Variance,
+		StandardDeviation: s.StandardDeviation,
+		GoodCount:         s.GoodCount,
+		PacketsDropped:    s.PacketsDropped,
+		InternalErrors:    s.InternalErrors,
+		Count:             s.GoodCount + s.PacketsDropped,
+	}
+	if ret.Count > 0 {
+		ret.PacketLoss = s.PacketLoss()
+	}
+	return ret
 }
 
 func (ts TimeSpan) String() string {
@@ -261,40 +608,103 @@ func (ts TimeSpan) String() string {
 	return fmt.Sprintf("%s -> %s (%s)", ts.Begin.Format(firstFormat), ts.End.Format(format), ts.Duration.String())
 }
 
-func stringFloatTime(f float64) string {
-	d := time.Duration(f)
-	return d.String()
+// DurationFormat controls how the durations in the [Stats] strings are written. The zero value writes each
+// duration in full using the most suitable unit, see [time.Duration.String].
+type DurationFormat struct {
+	// Unit forces every duration to be written in this unit, either [time.Millisecond] or
+	// [time.Microsecond]. Zero picks the most suitable unit for each duration.
+	Unit time.Duration
+	// Precision rounds every duration to this many significant figures, zero doesn't round.
+	Precision int
+}
+
+// ParseDurationFormat parses the user facing units, one of "ms", "us" or "auto", and the precision.
+func ParseDurationFormat(units string, precision int) (DurationFormat, error) {
+	if precision < 0 {
+		return DurationFormat{}, errors.Errorf("Invalid precision %d, expected zero or more significant figures", precision)
+	}
+	f := DurationFormat{Precision: precision}
+	switch units {
+	case "ms":
+		f.Unit = time.Millisecond
+	case "us":
+		f.Unit = time.Microsecond
+	case "auto", "":
+	default:
+		return DurationFormat{}, errors.Errorf("Unknown units %q, expected one of: ms|us|auto", units)
+	}
+	return f, nil
+}
+
+func (f DurationFormat) Format(d time.Duration) string {
+	return f.formatFloat(float64(d))
+}
+
+func (f DurationFormat) formatFloat(d float64) string {
+	if f.Unit != 0 {
+		d /= float64(f.Unit)
+	}
+	if f.Precision > 0 {
+		d = numeric.RoundToNearestSigFig(d, f.Precision)
+	}
+	switch f.Unit {
+	case time.Millisecond:
+		return strconv.FormatFloat(d, 'f', -1, 64) + " ms"
+	case time.Microsecond:
+		return strconv.FormatFloat(d, 'f', -1, 64) + " \u00B5s"
+	default:
+		return time.Duration(d).String()
+	}
+}
+
+// withDefaultPrecision uses the precision if this format doesn't already round.
+func (f DurationFormat) withDefaultPrecision(precision int) DurationFormat {
+	if f.Precision == 0 {
+		f.Precision = precision
+	}
+	return f
 }
 
 func (s Stats) PickString(remainingSpace int) string {
+	return s.PickFormattedString(remainingSpace, DurationFormat{})
+}
+
+// PickFormattedString is [Stats.PickString] with the durations written using the format.
+func (s Stats) PickFormattedString(remainingSpace int, f DurationFormat) string {
 	// heuristic is good enough for now
 	switch {
 	case remainingSpace > 100:
-		return s.longString()
+		return s.longString(f)
 	case remainingSpace > 80 && s.PacketsDropped > 0:
-		return s.mediumString()
+		return s.mediumString(f)
 	case remainingSpace > 55 && s.PacketsDropped == 0:
-		return s.mediumString()
+		return s.mediumString(f)
 	case remainingSpace > 61 && s.PacketsDropped > 0:
-		return s.shortString()
+		return s.shortString(f)
 	case remainingSpace > 45 && s.PacketsDropped == 0:
-		return s.shortString()
+		return s.shortString(f)
 	case remainingSpace > 10:
-		return s.superShortString()
+		return s.superShortString(f)
 	default:
 		return ""
 	}
 }
 
+// WindowString compares the mean of the stats of a [Window] with the lifetime mean, e.g.
+// "now: μ 12.5ms / lifetime: μ 10.1ms".
+func WindowString(recent, lifetime *Stats, f DurationFormat) string {
+	f = f.withDefaultPrecision(4)
+	return fmt.Sprintf("now: \u03BC %s / lifetime: \u03BC %s", f.formatFloat(recent.Mean), f.formatFloat(lifetime.Mean))
+}
+
 func (s Stats) String() string {
-	return s.mediumString()
+	return s.mediumString(DurationFormat{})
 }
 
-func (s Stats) superShortString() string {
+func (s Stats) superShortString(f DurationFormat) string {
 	var b strings.Builder
-	fmt.Fprintf(&b, "\u03BC %s | \u03C3 %s",
-		stringFloatTime(numeric.RoundToNearestSigFig(s.Mean, 4)),
-		stringFloatTime(numeric.RoundToNearestSigFig(s.StandardDeviation, 4)))
+	f = f.withDefaultPrecision(4)
+	fmt.Fprintf(&b, "\u03BC %s | \u03C3 %s", f.formatFloat(s.Mean), f.formatFloat(s.StandardDeviation))
 	if s.PacketsDropped > 0 {
 		fmt.Fprintf(&b, " | %.1f%%", numeric.RoundToNearestSigFig(s.PacketLoss(), 4)*100)
 	}
@@ -302,10 +712,9 @@ func (s Stats) superShortString() string {
 	return b.String()
 }
 
-func (s Stats) shortString() string {
+func (s Stats) shortString(f DurationFormat) string {
 	var b strings.Builder
-	fmt.Fprintf(&b, "\u03BC %s | \u03C3 %s",
-		stringFloatTime(s.Mean), stringFloatTime(s.StandardDeviation))
+	fmt.Fprintf(&b, "\u03BC %s | \u03C3 %s", f.formatFloat(s.Mean), f.formatFloat(s.StandardDeviation))
 	if s.PacketsDropped > 0 {
 		fmt.Fprintf(&b, " | Loss %.1f%%", numeric.RoundToNearestSigFig(s.PacketLoss(), 4)*100)
 	}
@@ -313,24 +722,37 @@ func (s Stats) shortString() string {
 	return b.String()
 }
 
-func (s Stats) mediumString() string {
+func (s Stats) mediumString(f DurationFormat) string {
 	var b strings.Builder
-	fmt.Fprintf(&b, "Average \u03BC %s | SD \u03C3 %s",
-		stringFloatTime(s.Mean), stringFloatTime(s.StandardDeviation))
+	fmt.Fprintf(&b, "Average \u03BC %s | SD \u03C3 %s", f.formatFloat(s.Mean), f.formatFloat(s.StandardDeviation))
 	if s.PacketsDropped > 0 {
 		fmt.Fprintf(&b, " | PacketLoss %.1f%%", numeric.RoundToNearestSigFig(s.PacketLoss(), 4)*100)
 	}
 	fmt.Fprintf(&b, " | Packet Count %d", s.PacketsDropped+s.GoodCount)
+	if s.InternalErrors > 0 {
+		fmt.Fprintf(&b, " | Errors %d", s.InternalErrors)
+	}
 	return b.String()
 }
 
-func (s Stats) longString() string {
+func (s Stats) longString(f DurationFormat) string {
 	var b strings.Builder
-	fmt.Fprintf(&b, "Average \u03BC %s | SD \u03C3 %s",
-		stringFloatTime(s.Mean), stringFloatTime(s.StandardDeviation))
+	fmt.Fprintf(&b, "Average \u03BC %s | SD \u03C3 %s", f.formatFloat(s.Mean), f.formatFloat(s.StandardDeviation))
 	fmt.Fprintf(&b, " | PacketLoss %.1f%% | Dropped %d", numeric.RoundToNearestSigFig(s.PacketLoss(), 4)*100, s.PacketsDropped)
 	fmt.Fprintf(&b, " | Good Packets %d | Packet Count %d", s.GoodCount, s.PacketsDropped+s.GoodCount)
+	if s.InternalErrors > 0 {
+		fmt.Fprintf(&b, " | Internal Errors %d", s.InternalErrors)
+	}
 	return b.String()
 }
 
-const currentDataVersion = 1
+// currentDataVersion history:
+//   - 1: the initial format
+//   - 2: adds [Data.Location] to the end of the data
+//   - 3: adds the [ping.PingDataPoint.TTL] of every point, block by block, to the end of the data
+const currentDataVersion = 3
+
+// CurrentVersion is the version of the compact format this build writes, and the newest it can read.
+func CurrentVersion() byte {
+	return currentDataVersion
+}
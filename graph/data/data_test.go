@@ -436,3 +436,386 @@ func TestDataOrdering(t *testing.T) {
 		})
 	}
 }
+
+func TestPercentile(t *testing.T) {
+	t.Parallel()
+	graphData := data.NewData("www.google.com")
+	for i := range 100 {
+		graphData.AddPoint(ping.PingResults{
+			Data: ping.PingDataPoint{Duration: time.Duration(100-i) * time.Millisecond, Timestamp: origin.Add(time.Duration(i) * time.Minute)},
+			IP:   net.IPv4allrouter,
+		})
+	}
+	graphData.AddPoint(ping.PingResults{
+		Data: ping.PingDataPoint{DropReason: ping.TestDrop, Timestamp: origin.Add(101 * time.Minute)},
+		IP:   net.IPv4allrouter,
+	})
+	assert.Equal(t, 1*time.Millisecond, graphData.Percentile(0))
+	assert.Equal(t, 50*time.Millisecond, graphData.Percentile(50))
+	assert.Equal(t, 99*time.Millisecond, graphData.Percentile(99))
+	assert.Equal(t, 100*time.Millisecond, graphData.Percentile(100))
+	assert.Equal(t, time.Duration(0), data.NewData("").Percentile(99))
+
+	latencies := graphData.Latencies()
+	assert.Len(t, latencies, 100)
+	for _, p := range []float64{0, 50, 90, 99, 100} {
+		assert.Equal(t, graphData.Percentile(p), latencies.Percentile(p))
+	}
+}
+
+func TestDurationFormat(t *testing.T) {
+	t.Parallel()
+	d := 8_052_048 * time.Nanosecond
+	type Case struct {
+		Units     string
+		Precision int
+		Expected  string
+	}
+	cases := []Case{
+		{Units: "auto", Precision: 0, Expected: "8.052048ms"},
+		{Units: "auto", Precision: 3, Expected: "8.05ms"},
+		{Units: "ms", Precision: 3, Expected: "8.05 ms"},
+		{Units: "ms", Precision: 0, Expected: "8.052048 ms"},
+		{Units: "us", Precision: 2, Expected: "8100 µs"},
+	}
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s-%d", c.Units, c.Precision), func(t *testing.T) {
+			t.Parallel()
+			f, err := data.ParseDurationFormat(c.Units, c.Precision)
+			require.NoError(t, err)
+			assert.Equal(t, c.Expected, f.Format(d))
+		})
+	}
+	_, err := data.ParseDurationFormat("s", 0)
+	require.Error(t, err)
+	_, err = data.ParseDurationFormat("ms", -1)
+	require.Error(t, err)
+}
+
+func TestDropEvents(t *testing.T) {
+	t.Parallel()
+	graphData := data.NewData("www.google.com")
+	reasons := []ping.Dropped{
+		ping.TestDrop, ping.NotDropped, ping.Timeout, ping.Timeout, ping.InternalError, ping.Timeout,
+		ping.NotDropped, ping.NotDropped, ping.DNSFailure,
+	}
+	for i, reason := range reasons {
+		graphData.AddPoint(ping.PingResults{
+			Data: ping.PingDataPoint{Duration: time.Millisecond, Timestamp: origin.Add(time.Duration(i) * time.Minute), DropReason: reason},
+			IP:   net.IPv4allrouter,
+		})
+	}
+	assert.Equal(t, []data.DropEvent{
+		{Start: origin, End: origin, Length: 1},
+		{Start: origin.Add(2 * time.Minute), End: origin.Add(5 * time.Minute), Length: 3},
+		{Start: origin.Add(8 * time.Minute), End: origin.Add(8 * time.Minute), Length: 1},
+	}, graphData.DropEvents())
+	assert.Empty(t, data.NewData("").DropEvents())
+}
+
+func TestRuns(t *testing.T) {
+	t.Parallel()
+	graphData := data.NewData("www.google.com")
+	reasons := []ping.Dropped{
+		ping.NotDropped, ping.Timeout, ping.Timeout, ping.InternalError, ping.Timeout, ping.NotDropped,
+		ping.NotDropped, ping.InternalError, ping.NotDropped, ping.DNSFailure,
+	}
+	for i, reason := range reasons {
+		graphData.AddPoint(ping.PingResults{
+			Data: ping.PingDataPoint{Duration: time.Millisecond, Timestamp: origin.Add(time.Duration(i) * time.Minute), DropReason: reason},
+			IP:   net.IPv4allrouter,
+		})
+	}
+	assert.Equal(t, data.Runs{LongestGood: 3, LongestDropped: 3}, graphData.Runs())
+	assert.Equal(t, data.Runs{}, data.NewData("").Runs())
+}
+
+func TestRange(t *testing.T) {
+	t.Parallel()
+	graphData := data.NewData("www.google.com")
+	for i := range 10 {
+		graphData.AddPoint(ping.PingResults{
+			Data: ping.PingDataPoint{Duration: time.Duration(i) * time.Millisecond, Timestamp: origin.Add(time.Duration(i) * time.Minute)},
+			IP:   net.IPv4allrouter,
+		})
+	}
+	collect := func(from, to time.Time, limit int) []int64 {
+		ret := []int64{}
+		graphData.Range(from, to)(func(i int64, p ping.PingDataPoint) bool {
+			assert.Equal(t, graphData.Get(i), p)
+			ret = append(ret, i)
+			return len(ret) < limit
+		})
+		return ret
+	}
+	assert.Equal(t, []int64{2, 3, 4, 5}, collect(origin.Add(2*time.Minute), origin.Add(5*time.Minute), 10))
+	assert.Equal(t, []int64{3, 4, 5}, collect(origin.Add(150*time.Second), origin.Add(330*time.Second), 10))
+	assert.Equal(t, []int64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, collect(origin.Add(-time.Hour), origin.Add(time.Hour), 10))
+	assert.Equal(t, []int64{0, 1}, collect(origin, origin.Add(time.Hour), 2))
+	assert.Empty(t, collect(origin.Add(time.Hour), origin.Add(2*time.Hour), 10))
+	assert.Empty(t, collect(origin.Add(5*time.Minute), origin.Add(2*time.Minute), 10))
+	assert.Equal(t, net.IPv4allrouter, graphData.GetFull(collect(origin.Add(9*time.Minute), origin.Add(time.Hour), 10)[0]).IP)
+}
+
+func TestWarmup(t *testing.T) {
+	t.Parallel()
+	graphData := data.NewData("www.google.com")
+	add := func(i int, ip net.IP, duration time.Duration, reason ping.Dropped) {
+		graphData.AddPoint(ping.PingResults{
+			Data: ping.PingDataPoint{Duration: duration, Timestamp: origin.Add(time.Duration(i) * time.Minute), DropReason: reason},
+			IP:   ip,
+		})
+	}
+	add(0, net.IPv4allrouter, 90*time.Millisecond, ping.NotDropped)
+	add(1, net.IPv4allrouter, 0, ping.Timeout)
+	add(2, net.IPv4allrouter, 50*time.Millisecond, ping.NotDropped)
+	add(3, net.IPv4allrouter, 10*time.Millisecond, ping.NotDropped)
+	add(4, net.IPv4bcast, 80*time.Millisecond, ping.NotDropped)
+	add(5, net.IPv4bcast, 20*time.Millisecond, ping.NotDropped)
+
+	w := graphData.Warmup(2)
+	var warmup []int64
+	for i := range graphData.TotalCount {
+		if w.Contains(i) {
+			warmup = append(warmup, i)
+		}
+	}
+	assert.Equal(t, []int64{0, 2, 4, 5}, warmup)
+	stats := w.Stats()
+	assert.Equal(t, uint64(1), stats.GoodCount)
+	assert.Equal(t, uint64(1), stats.PacketsDropped)
+	assert.Equal(t, 10*time.Millisecond, stats.Min)
+	assert.Equal(t, 10*time.Millisecond, stats.Max)
+
+	assert.Equal(t, *graphData.Header.Stats, *graphData.Warmup(0).Stats())
+}
+
+func TestWarmup_spans(t *testing.T) {
+	t.Parallel()
+	graphData := data.NewData("www.google.com")
+	add := func(at time.Duration, ip net.IP, reason ping.Dropped) {
+		graphData.AddPoint(ping.PingResults{
+			Data: ping.PingDataPoint{Duration: 10 * time.Millisecond, Timestamp: origin.Add(at), DropReason: reason},
+			IP:   ip,
+		})
+	}
+	// Two sessions on the same IP, each resolved DNS afresh.
+	add(0, net.IPv4allrouter, ping.NotDropped)
+	add(time.Second, net.IPv4allrouter, ping.NotDropped)
+	add(2*time.Second, net.IPv4allrouter, ping.NotDropped)
+	add(time.Hour, net.IPv4allrouter, ping.NotDropped)
+	add(time.Hour+time.Second, net.IPv4allrouter, ping.NotDropped)
+	// A failed resolution then the same IP again.
+	add(time.Hour+2*time.Second, nil, ping.DNSFailure)
+	add(time.Hour+3*time.Second, net.IPv4allrouter, ping.NotDropped)
+	add(time.Hour+4*time.Second, net.IPv4allrouter, ping.NotDropped)
+	// IPs which change back and forth each start again.
+	add(time.Hour+5*time.Second, net.IPv4bcast, ping.NotDropped)
+	add(time.Hour+6*time.Second, net.IPv4allrouter, ping.NotDropped)
+	add(time.Hour+7*time.Second, net.IPv4allrouter, ping.NotDropped)
+
+	w := graphData.Warmup(1)
+	var warmup []int64
+	for i := range graphData.TotalCount {
+		if w.Contains(i) {
+			warmup = append(warmup, i)
+		}
+	}
+	assert.Equal(t, []int64{0, 3, 6, 8, 9}, warmup)
+	assert.Equal(t, uint64(5), w.Stats().GoodCount)
+}
+
+func TestWarmup_update(t *testing.T) {
+	t.Parallel()
+	graphData := data.NewData("www.google.com")
+	w := graphData.Warmup(1)
+	for i := range 4 {
+		graphData.AddPoint(ping.PingResults{
+			Data: ping.PingDataPoint{Duration: time.Millisecond, Timestamp: origin.Add(time.Duration(i) * time.Hour)},
+			IP:   net.IPv4allrouter,
+		})
+		w.Update()
+		require.Equal(t, graphData.Warmup(1), w, "after point %d", i)
+	}
+	assert.True(t, w.Contains(3), "every point starts a span")
+}
+
+func TestWarmup_addStats(t *testing.T) {
+	t.Parallel()
+	graphData := data.NewData("www.google.com")
+	kept := &data.Stats{}
+	for i, ip := range []net.IP{net.IPv4allrouter, net.IPv4allrouter, net.IPv4bcast, net.IPv4allrouter, net.IPv4bcast, net.IPv4bcast} {
+		p := ping.PingDataPoint{Duration: time.Duration(i+1) * time.Millisecond, Timestamp: origin.Add(time.Duration(i) * time.Minute)}
+		if i == 3 {
+			p = ping.PingDataPoint{DropReason: ping.Timeout, Timestamp: p.Timestamp}
+		}
+		from := graphData.TotalCount
+		graphData.AddPoint(ping.PingResults{Data: p, IP: ip})
+		graphData.Warmup(1).AddStats(kept, from)
+		require.Equal(t, *graphData.Warmup(1).Stats(), *kept, "after point %d", i)
+	}
+	assert.Equal(t, uint64(3), kept.GoodCount)
+}
+
+func TestAddPointDedup(t *testing.T) {
+	t.Parallel()
+	graphData := data.NewData("www.google.com")
+	point := func(i int, ip net.IP) ping.PingResults {
+		return ping.PingResults{
+			Data: ping.PingDataPoint{Duration: time.Millisecond, Timestamp: origin.Add(time.Duration(i) * time.Minute)},
+			IP:   ip,
+		}
+	}
+	assert.True(t, graphData.AddPointDedup(point(0, net.IPv4allrouter)))
+	assert.False(t, graphData.AddPointDedup(point(0, net.IPv4allrouter)))
+	assert.True(t, graphData.AddPointDedup(point(0, net.IPv4bcast)), "a different IP isn't a duplicate")
+	assert.False(t, graphData.AddPointDedup(point(0, net.IPv4allrouter)), "the previous point for this IP")
+	assert.True(t, graphData.AddPointDedup(point(1, net.IPv4allrouter)))
+	assert.True(t, graphData.AddPointDedup(point(0, net.IPv4allrouter)), "only the previous point is checked")
+	assert.Equal(t, int64(4), graphData.TotalCount)
+	assert.Equal(t, uint64(2), graphData.DuplicatesSkipped)
+	assert.Contains(t, graphData.String(), "Duplicates Skipped 2")
+
+	graphData.AddPoint(point(0, net.IPv4allrouter))
+	assert.Equal(t, int64(5), graphData.TotalCount, "AddPoint never skips")
+}
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+	values := []time.Duration{5, 6, 5, 30, 7, 12, 9, 8}
+	for _, split := range []int{0, 1, 4, 8} {
+		expected := &data.Stats{}
+		expected.AddPoints(values)
+		expected.AddDroppedPacket()
+		a, b := &data.Stats{}, &data.Stats{}
+		a.AddPoints(values[:split])
+		b.AddPoints(values[split:])
+		b.AddDroppedPacket()
+
+		merged := data.Merge(a, b)
+		assertStatsEqual(t, *expected, *merged, 8, "split at %d", split)
+		assert.Equal(t, expected.Min, merged.Min, "split at %d", split)
+		assert.Equal(t, expected.Max, merged.Max, "split at %d", split)
+		assert.Equal(t, uint64(1), merged.PacketsDropped, "split at %d", split)
+
+		// Re-using one of the inputs as the destination.
+		data.MergeInto(a, a, b)
+		assertStatsEqual(t, *expected, *a, 8, "split at %d", split)
+	}
+	scratch := data.Merge()
+	assert.Equal(t, data.Stats{}, *scratch)
+	scratch.AddPoint(time.Second)
+	scratch.Reset()
+	assert.Equal(t, data.Stats{}, *scratch)
+}
+
+func BenchmarkMerge(b *testing.B) {
+	x, y := &data.Stats{}, &data.Stats{}
+	x.AddPoints([]time.Duration{5, 6, 5, 30})
+	y.AddPoints([]time.Duration{7, 12, 9, 8})
+	b.Run("Merge", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			_ = data.Merge(x, y)
+		}
+	})
+	b.Run("MergeInto", func(b *testing.B) {
+		b.ReportAllocs()
+		scratch := &data.Stats{}
+		for range b.N {
+			scratch.Reset()
+			data.MergeInto(scratch, x, y)
+		}
+	})
+}
+
+func TestFormatIP(t *testing.T) {
+	t.Parallel()
+	d := data.NewData("www.example.com")
+	d.AddPoint(ping.PingResults{Data: ping.PingDataPoint{Duration: time.Millisecond, Timestamp: origin}, IP: net.IPv4(192, 168, 0, 1)})
+	d.AddPoint(ping.PingResults{
+		Data: ping.PingDataPoint{Duration: time.Millisecond, Timestamp: origin.Add(time.Second)},
+		IP:   net.ParseIP("2001:db8::1"),
+	})
+	d.AddPoint(ping.PingResults{Data: ping.PingDataPoint{Timestamp: origin.Add(2 * time.Second), DropReason: ping.Timeout}})
+
+	// Read back after a round trip through the compact format, which stores every IP as 16 bytes.
+	var b strings.Builder
+	require.NoError(t, d.AsCompact(&b))
+	read, err := data.ReadData(strings.NewReader(b.String()))
+	require.NoError(t, err)
+	for _, d := range []*data.Data{d, read} {
+		v4 := d.GetFull(0).IP
+		require.Len(t, v4, net.IPv6len, "stored IPv4-mapped")
+		require.Equal(t, "192.168.0.1", data.FormatIP(v4))
+		require.Equal(t, "2001:db8::1", data.FormatIP(d.GetFull(1).IP))
+		require.Equal(t, "::", data.FormatIP(d.GetFull(2).IP), "no IP at all")
+		require.Equal(t, "::,192.168.0.1,2001:db8::1", d.Network.String())
+	}
+	require.Equal(t, "192.168.0.1", data.FormatIP(net.IPv4(192, 168, 0, 1).To4()))
+}
+
+func TestAddPoint_negativeLatency(t *testing.T) {
+	t.Parallel()
+	d := data.NewData("www.google.com")
+	for i, duration := range []time.Duration{10 * time.Millisecond, -5 * time.Millisecond, 20 * time.Millisecond, 0} {
+		d.AddPoint(ping.PingResults{
+			Data: ping.PingDataPoint{Duration: duration, Timestamp: origin.Add(time.Duration(i) * time.Second)},
+			IP:   net.IPv4allrouter,
+		})
+	}
+	stats := d.Header.Stats
+	assert.InDelta(t, float64(10*time.Millisecond), stats.Mean, 1, "the negative latency is excluded, zero isn't")
+	assert.Equal(t, time.Duration(0), stats.Min)
+	assert.Equal(t, uint64(3), stats.GoodCount)
+	assert.Equal(t, uint64(1), stats.InternalErrors)
+	assert.Equal(t, uint64(0), stats.PacketsDropped, "not counted as packet loss")
+	negative := d.Get(1)
+	assert.True(t, negative.InternalError())
+	assert.Equal(t, -5*time.Millisecond, negative.Duration, "the raw value is kept")
+}
+
+func TestAddPoint_outOfOrder(t *testing.T) {
+	t.Parallel()
+	d := data.NewData("www.google.com")
+	for i, reason := range []ping.Dropped{ping.NotDropped, ping.Timeout, ping.OutOfOrder, ping.NotDropped} {
+		d.AddPoint(ping.PingResults{
+			Data: ping.PingDataPoint{Duration: time.Millisecond, Timestamp: origin.Add(time.Duration(i) * time.Second), DropReason: reason},
+			IP:   net.IPv4allrouter,
+		})
+	}
+	stats := d.Header.Stats
+	assert.Equal(t, uint64(2), stats.GoodCount)
+	assert.Equal(t, uint64(1), stats.PacketsDropped, "the late reply's probe is only counted once")
+	assert.InDelta(t, 1.0/3, stats.PacketLoss(), 1e-9)
+	assert.Equal(t, origin.Add(3*time.Second), d.Header.TimeSpan.End)
+	assert.Equal(t, []data.DropEvent{{Start: origin.Add(time.Second), End: origin.Add(time.Second), Length: 1}}, d.DropEvents())
+	assert.Equal(t, data.Runs{LongestGood: 1, LongestDropped: 1}, d.Runs())
+}
+
+func TestStatsSnapshot(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, data.StatsSnapshot{}, (&data.Stats{}).Snapshot(), "no packets isn't NaN packet loss")
+
+	stats := &data.Stats{}
+	stats.AddPoints([]time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond})
+	stats.AddDroppedPacket()
+	stats.AddInternalError()
+	snapshot := stats.Snapshot()
+	assert.Equal(t, data.StatsSnapshot{
+		Min:               10 * time.Millisecond,
+		Max:               30 * time.Millisecond,
+		Mean:              stats.Mean,
+		Variance:          stats.Variance,
+		StandardDeviation: stats.StandardDeviation,
+		GoodCount:         3,
+		PacketsDropped:    1,
+		InternalErrors:    1,
+		Count:             4,
+		PacketLoss:        0.25,
+	}, snapshot)
+
+	stats.AddPoint(time.Second)
+	assert.Equal(t, uint64(3), snapshot.GoodCount, "unchanged by later points")
+	assert.Equal(t, 30*time.Millisecond, snapshot.Max)
+}
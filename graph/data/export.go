@@ -0,0 +1,163 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package data
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/Lexer747/AcciPing/ping"
+)
+
+// Filter selects which points are written, the zero value selects every point.
+type Filter struct {
+	// From and To are the inclusive bounds of the timestamps selected, either can be zero to leave that end
+	// open.
+	From, To time.Time
+	// IP when set selects only the points for that IP address.
+	IP net.IP
+}
+
+// Empty is true when the filter selects every point.
+func (f Filter) Empty() bool {
+	return f.From.IsZero() && f.To.IsZero() && f.IP == nil
+}
+
+// Each calls do for every point which matches the filter, in insert order.
+func (d *Data) Each(f Filter, do func(int64, ping.PingResults)) {
+	to := f.To
+	if to.IsZero() {
+		to = d.Header.TimeSpan.End
+	}
+	d.Range(f.From, to)(func(i int64, _ ping.PingDataPoint) bool {
+		p := d.GetFull(i)
+		if f.IP == nil || f.IP.Equal(p.IP) {
+			do(i, p)
+		}
+		return true
+	})
+}
+
+// WriteSummary writes the one line description of the data, the URL, IPs, time span and stats.
+func WriteSummary(w io.Writer, d *Data) error {
+	_, err := fmt.Fprintln(w, d.String())
+	return err
+}
+
+// WriteSpans writes the summary of the data and then a line for each span of it (see [DetectSpans]), the span's
+// time span, stats and the gap to the next span.
+func WriteSpans(w io.Writer, d *Data, cfg SpanConfig) error {
+	if _, err := fmt.Fprintf(w, "%s: %s\n", d.URL, d.Header.String()); err != nil {
+		return err
+	}
+	for i, span := range DetectSpans(d, cfg) {
+		next := ""
+		if span.Gap > 0 {
+			next = " | Gap " + span.Gap.String()
+		}
+		if _, err := fmt.Fprintf(w, "%d: %s%s\n", i, span.Header.String(), next); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteAll writes every point which matches the filter on its own line, between a line for the start and end
+// of the data.
+func WriteAll(w io.Writer, d *Data, f Filter) error {
+	if _, err := fmt.Fprintf(w, "BEGIN %s: %s\n", d.URL, d.Header.String()); err != nil {
+		return err
+	}
+	var err error
+	d.Each(f, func(i int64, p ping.PingResults) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, "%d: %s\n", i, p.String())
+		}
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "END %s: %s\n", d.URL, d.Header.String())
+	return err
+}
+
+// CSVHeader is the first row of the CSV written by [WriteCSV].
+var CSVHeader = []string{"file", "index", "timestamp", "ip", "duration_ns", "dropped"}
+
+// WriteCSV writes a CSV row for every point which matches the filter, the file is the first column so that the
+// points of many files can be written to the same table after a single [CSVHeader].
+func WriteCSV(w io.Writer, file string, d *Data, f Filter) error {
+	c := csv.NewWriter(w)
+	d.Each(f, func(i int64, p ping.PingResults) {
+		_ = c.Write([]string{
+			file,
+			strconv.FormatInt(i, 10),
+			p.Data.Timestamp.Format(time.RFC3339Nano),
+			FormatIP(p.IP),
+			strconv.FormatInt(p.Data.Duration.Nanoseconds(), 10),
+			dropReason(p.Data),
+		})
+	})
+	c.Flush()
+	return c.Error()
+}
+
+// SummaryCSVHeader is the first row of the CSV written by [WriteSummaryCSV].
+var SummaryCSVHeader = []string{
+	"file", "url", "start", "end", "duration_ns", "count", "mean_ns", "sd_ns", "p99_ns", "loss_percent",
+	"longest_good_streak", "longest_drop_streak",
+}
+
+// WriteSummaryCSV writes a single CSV row of the statistics of the whole data, for comparing many files in
+// the same table after a single [SummaryCSVHeader]. Only the percentile and the streaks need to look at the
+// points, everything else is in the header.
+func WriteSummaryCSV(w io.Writer, file string, d *Data) error {
+	stats := d.Header.Stats
+	span := d.Header.TimeSpan
+	count := stats.GoodCount + stats.PacketsDropped
+	loss := 0.0
+	if count > 0 {
+		loss = stats.PacketLoss() * 100
+	}
+	runs := d.Runs()
+	c := csv.NewWriter(w)
+	_ = c.Write([]string{
+		file,
+		d.URL,
+		span.Begin.Format(time.RFC3339Nano),
+		span.End.Format(time.RFC3339Nano),
+		strconv.FormatInt(span.End.Sub(span.Begin).Nanoseconds(), 10),
+		strconv.FormatUint(count, 10),
+		strconv.FormatInt(int64(stats.Mean), 10),
+		strconv.FormatInt(int64(stats.StandardDeviation), 10),
+		strconv.FormatInt(d.Percentile(99).Nanoseconds(), 10),
+		strconv.FormatFloat(loss, 'f', 2, 64),
+		strconv.FormatUint(runs.LongestGood, 10),
+		strconv.FormatUint(runs.LongestDropped, 10),
+	})
+	c.Flush()
+	return c.Error()
+}
+
+// WriteCSVHeader writes the header row to the CSV, either [CSVHeader] or [SummaryCSVHeader].
+func WriteCSVHeader(w io.Writer, header []string) error {
+	c := csv.NewWriter(w)
+	_ = c.Write(header)
+	c.Flush()
+	return c.Error()
+}
+
+func dropReason(p ping.PingDataPoint) string {
+	if p.DropReason == ping.NotDropped {
+		return ""
+	}
+	return p.DropReason.String()
+}
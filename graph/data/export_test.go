@@ -0,0 +1,104 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package data_test
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/ping"
+	"github.com/stretchr/testify/require"
+)
+
+func exportData() *data.Data {
+	d := data.NewData("www.google.com")
+	points := []ping.PingResults{
+		{Data: ping.PingDataPoint{Duration: 5 * time.Millisecond, Timestamp: origin}, IP: net.IPv4(1, 1, 1, 1)},
+		{Data: ping.PingDataPoint{Duration: 7 * time.Millisecond, Timestamp: origin.Add(time.Second)}, IP: net.IPv4(1, 1, 1, 1)},
+		{Data: ping.PingDataPoint{Timestamp: origin.Add(2 * time.Second), DropReason: ping.Timeout}, IP: net.IPv4(1, 1, 1, 1)},
+		{Data: ping.PingDataPoint{Duration: 6 * time.Millisecond, Timestamp: origin.Add(3 * time.Second)}, IP: net.IPv4(8, 8, 8, 8)},
+	}
+	for _, p := range points {
+		d.AddPoint(p)
+	}
+	return d
+}
+
+func TestWriteCSV(t *testing.T) {
+	t.Parallel()
+	var b strings.Builder
+	require.NoError(t, data.WriteCSVHeader(&b, data.CSVHeader))
+	require.NoError(t, data.WriteCSV(&b, "a.pings", exportData(), data.Filter{}))
+	require.Equal(t, `file,index,timestamp,ip,duration_ns,dropped
+a.pings,0,2000-01-01T00:00:00Z,1.1.1.1,5000000,
+a.pings,1,2000-01-01T00:00:01Z,1.1.1.1,7000000,
+a.pings,2,2000-01-01T00:00:02Z,1.1.1.1,0,Timeout
+a.pings,3,2000-01-01T00:00:03Z,8.8.8.8,6000000,
+`, b.String())
+}
+
+func TestWriteCSV_filter(t *testing.T) {
+	t.Parallel()
+	var b strings.Builder
+	filter := data.Filter{From: origin.Add(time.Second), IP: net.IPv4(1, 1, 1, 1)}
+	require.NoError(t, data.WriteCSV(&b, "a.pings", exportData(), filter))
+	require.Equal(t, `a.pings,1,2000-01-01T00:00:01Z,1.1.1.1,7000000,
+a.pings,2,2000-01-01T00:00:02Z,1.1.1.1,0,Timeout
+`, b.String())
+	b.Reset()
+	require.NoError(t, data.WriteCSV(&b, "a.pings", exportData(), data.Filter{To: origin}))
+	require.Equal(t, "a.pings,0,2000-01-01T00:00:00Z,1.1.1.1,5000000,\n", b.String())
+}
+
+func TestWriteSummaryCSV(t *testing.T) {
+	t.Parallel()
+	var b strings.Builder
+	require.NoError(t, data.WriteCSVHeader(&b, data.SummaryCSVHeader))
+	require.NoError(t, data.WriteSummaryCSV(&b, "a.pings", exportData()))
+	require.Equal(t, `file,url,start,end,duration_ns,count,mean_ns,sd_ns,p99_ns,loss_percent,longest_good_streak,longest_drop_streak
+a.pings,www.google.com,2000-01-01T00:00:00Z,2000-01-01T00:00:03Z,3000000000,4,6000000,1000000,7000000,25.00,2,1
+`, b.String())
+
+	b.Reset()
+	require.NoError(t, data.WriteSummaryCSV(&b, "empty.pings", data.NewData("www.example.com")))
+	require.Contains(t, b.String(), ",0,0,0,0,0,0.00,0,0\n", "no packets isn't any packet loss")
+}
+
+func TestWriteSpans(t *testing.T) {
+	t.Parallel()
+	var b strings.Builder
+	d := exportData()
+	require.NoError(t, data.WriteSpans(&b, d, data.SpanConfig{Gap: 1500 * time.Millisecond}))
+	spans := data.DetectSpans(d, data.SpanConfig{Gap: 1500 * time.Millisecond})
+	require.Len(t, spans, 1)
+	require.Equal(t, "www.google.com: "+d.Header.String()+"\n0: "+spans[0].Header.String()+"\n", b.String())
+
+	b.Reset()
+	d.AddPoint(ping.PingResults{Data: ping.PingDataPoint{Duration: 5 * time.Millisecond, Timestamp: origin.Add(time.Minute)}})
+	require.NoError(t, data.WriteSpans(&b, d, data.SpanConfig{Gap: 1500 * time.Millisecond}))
+	lines := strings.Split(b.String(), "\n")
+	require.Len(t, lines, 4)
+	require.True(t, strings.HasSuffix(lines[1], " | Packet Count 4 | Gap 57s"), lines[1])
+	require.True(t, strings.HasPrefix(lines[2], "1: "), lines[2])
+	require.True(t, strings.HasSuffix(lines[2], " | Packet Count 1"), lines[2])
+}
+
+func TestWriteAll(t *testing.T) {
+	t.Parallel()
+	var b strings.Builder
+	d := exportData()
+	require.NoError(t, data.WriteAll(&b, d, data.Filter{IP: net.IPv4(8, 8, 8, 8)}))
+	header := "www.google.com: " + d.Header.String()
+	require.Equal(t, "BEGIN "+header+"\n3: 8.8.8.8 | 00:00:03 | 6ms\nEND "+header+"\n", b.String())
+
+	b.Reset()
+	require.NoError(t, data.WriteSummary(&b, d))
+	require.Equal(t, d.String()+"\n", b.String())
+}
@@ -0,0 +1,38 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package data
+
+import "time"
+
+// GapStats are the stats of the time between consecutive points, in the order they were added, i.e. the actual
+// interval the pings were sent at. Compared to the configured interval this shows any jitter or throttling in
+// when the pings are sent. Gaps which split the data into separate spans (see [DetectSpans]) are where the
+// capture was stopped, not how it was scheduled, so they're left out. So are late replies, which have the same
+// timestamp as the probe they answer, and internal errors, which weren't sent. The GoodCount of the stats is the
+// number of gaps, zero if there are fewer than two points in any span. Like [DetectSpans] these are computed from
+// the points each time.
+func (d *Data) GapStats(cfg SpanConfig) *Stats {
+	spanGap := cfg.Gap
+	if spanGap == 0 {
+		spanGap = DefaultSpanGap
+	}
+	ret := &Stats{}
+	var last *time.Time
+	for i := range d.TotalCount {
+		p := d.Get(i)
+		if p.OutOfOrder() || p.InternalError() {
+			continue
+		}
+		if last != nil {
+			if gap := p.Timestamp.Sub(*last); gap <= spanGap {
+				ret.AddPoint(gap)
+			}
+		}
+		last = &p.Timestamp
+	}
+	return ret
+}
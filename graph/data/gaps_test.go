@@ -0,0 +1,54 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package data_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/ping"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGapStats(t *testing.T) {
+	t.Parallel()
+	graphData := data.NewData("www.google.com")
+	assert.Equal(t, uint64(0), graphData.GapStats(data.SpanConfig{}).GoodCount, "no points")
+	offsets := []time.Duration{0, time.Second, 3 * time.Second, 4 * time.Second, time.Hour, time.Hour + 2*time.Second}
+	for i, offset := range offsets {
+		p := ping.PingDataPoint{Duration: time.Millisecond, Timestamp: origin.Add(offset)}
+		if i == 2 {
+			// Dropped packets were still sent on schedule
+			p = ping.PingDataPoint{DropReason: ping.Timeout, Timestamp: origin.Add(offset)}
+		}
+		graphData.AddPoint(ping.PingResults{Data: p, IP: net.IPv4allrouter})
+	}
+	gaps := graphData.GapStats(data.SpanConfig{})
+	require.Equal(t, uint64(4), gaps.GoodCount, "the gap between the spans is left out")
+	assert.Equal(t, time.Second, gaps.Min)
+	assert.Equal(t, 2*time.Second, gaps.Max)
+	assert.InDelta(t, float64(1500*time.Millisecond), gaps.Mean, 1)
+	assert.InDelta(t, float64(577350269), gaps.StandardDeviation, 1)
+
+	all := graphData.GapStats(data.SpanConfig{Gap: 2 * time.Hour})
+	assert.Equal(t, uint64(5), all.GoodCount)
+	assert.Equal(t, time.Hour-4*time.Second, all.Max)
+
+	// A late reply has the timestamp of its probe, and an internal error was never sent.
+	graphData.AddPoint(ping.PingResults{
+		Data: ping.PingDataPoint{DropReason: ping.OutOfOrder, Timestamp: origin.Add(time.Hour + 2*time.Second)},
+		IP:   net.IPv4allrouter,
+	})
+	graphData.AddPoint(ping.PingResults{
+		Data: ping.PingDataPoint{DropReason: ping.InternalError, Timestamp: origin.Add(time.Hour + 2*time.Second)},
+		IP:   net.IPv4allrouter,
+	})
+	assert.Equal(t, gaps, graphData.GapStats(data.SpanConfig{}), "neither is another gap")
+}
@@ -0,0 +1,74 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package data
+
+import (
+	"slices"
+	"sort"
+	"time"
+)
+
+// Bucket is a range of latency and the number of good points in it, see [Data.Histogram].
+type Bucket struct {
+	// Min is the start of the range, inclusive.
+	Min time.Duration
+	// Max is the end of the range, exclusive except for the last bucket which includes the slowest point.
+	Max   time.Duration
+	Count uint64
+}
+
+// Histogram splits the latency of the good points, from the fastest to the slowest, into the number of buckets
+// of equal width and counts the points in each. When every good point has the same latency there's nothing to
+// split and a single bucket is returned. Nil if there are no good points or no buckets.
+func (d *Data) Histogram(buckets int) []Bucket {
+	durations := d.sortedDurations()
+	if len(durations) == 0 || buckets < 1 {
+		return nil
+	}
+	lo, hi := durations[0], durations[len(durations)-1]
+	if lo == hi {
+		return []Bucket{{Min: lo, Max: hi, Count: uint64(len(durations))}}
+	}
+	ret := make([]Bucket, buckets)
+	start := 0
+	for i := range ret {
+		ret[i].Min = lo + time.Duration(int64(hi-lo)*int64(i)/int64(buckets))
+		ret[i].Max = lo + time.Duration(int64(hi-lo)*int64(i+1)/int64(buckets))
+		end := len(durations)
+		if i < buckets-1 {
+			end = start + sort.Search(len(durations)-start, func(j int) bool { return durations[start+j] >= ret[i].Max })
+		}
+		ret[i].Count = uint64(end - start)
+		start = end
+	}
+	return ret
+}
+
+// Mode is the index of the bucket with the most points, the first of them if more than one has the most. -1 if
+// there are no buckets.
+func Mode(buckets []Bucket) int {
+	mode := -1
+	for i, b := range buckets {
+		if mode == -1 || b.Count > buckets[mode].Count {
+			mode = i
+		}
+	}
+	return mode
+}
+
+// sortedDurations is the latency of every good point, fastest first.
+func (d *Data) sortedDurations() []time.Duration {
+	durations := make([]time.Duration, 0, d.Header.Stats.GoodCount)
+	for i := range d.TotalCount {
+		p := d.Get(i)
+		if p.Good() {
+			durations = append(durations, p.Duration)
+		}
+	}
+	slices.Sort(durations)
+	return durations
+}
@@ -0,0 +1,56 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package data_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/ping"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogram(t *testing.T) {
+	t.Parallel()
+	graphData := data.NewData("www.google.com")
+	// Two routes, one much slower than the other.
+	for i, ms := range []int{51, 10, 12, 50, -1, 11, 12} {
+		p := ping.PingDataPoint{Duration: time.Duration(ms) * time.Millisecond, Timestamp: origin.Add(time.Duration(i) * time.Second)}
+		if ms < 0 {
+			p = ping.PingDataPoint{DropReason: ping.TestDrop, Timestamp: p.Timestamp}
+		}
+		graphData.AddPoint(ping.PingResults{Data: p})
+	}
+	buckets := graphData.Histogram(4)
+	require.Equal(t, []data.Bucket{
+		{Min: 10 * time.Millisecond, Max: 20250 * time.Microsecond, Count: 4},
+		{Min: 20250 * time.Microsecond, Max: 30500 * time.Microsecond, Count: 0},
+		{Min: 30500 * time.Microsecond, Max: 40750 * time.Microsecond, Count: 0},
+		{Min: 40750 * time.Microsecond, Max: 51 * time.Millisecond, Count: 2},
+	}, buckets)
+	assert.Equal(t, buckets, graphData.Histogram(4), "deterministic")
+	assert.Equal(t, 0, data.Mode(buckets))
+
+	buckets = graphData.Histogram(41)
+	require.Len(t, buckets, 41)
+	assert.Equal(t, uint64(1), buckets[0].Count, "10ms")
+	assert.Equal(t, uint64(2), buckets[2].Count, "both 12ms")
+	assert.Equal(t, uint64(2), buckets[40].Count, "the last bucket includes the slowest")
+	assert.Equal(t, 2, data.Mode(buckets), "the first of the most")
+
+	assert.Nil(t, graphData.Histogram(0))
+	assert.Nil(t, data.NewData("").Histogram(4))
+	assert.Equal(t, -1, data.Mode(nil))
+
+	flat := data.NewData("www.google.com")
+	for i := range 3 {
+		flat.AddPoint(ping.PingResults{Data: ping.PingDataPoint{Duration: time.Millisecond, Timestamp: origin.Add(time.Duration(i) * time.Second)}})
+	}
+	assert.Equal(t, []data.Bucket{{Min: time.Millisecond, Max: time.Millisecond, Count: 3}}, flat.Histogram(4))
+}
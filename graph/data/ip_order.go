@@ -21,3 +21,12 @@ func ipOrdering(a, b net.IP) int {
 	}
 	return 0
 }
+
+// FormatIP is how an IP is displayed. Every IP is stored in its 16 byte form (see [Network.AddPoint]), so an
+// IPv4 address is recognised by its IPv4-mapped prefix and printed as a dotted quad, never as "::ffff:a.b.c.d".
+func FormatIP(ip net.IP) string {
+	if v4 := ip.To4(); v4 != nil {
+		return v4.String()
+	}
+	return ip.String()
+}
@@ -0,0 +1,59 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package data
+
+import (
+	"io"
+	"sync"
+
+	"github.com/Lexer747/AcciPing/ping"
+)
+
+// SafeData guards a [Data] with a lock so that it can be used from multiple goroutines, e.g. one adding points
+// from a channel while another draws or writes them.
+type SafeData struct {
+	mu sync.Mutex
+	d  *Data
+}
+
+// NewSafeData takes ownership of the data, after which it must only be accessed through the SafeData.
+func NewSafeData(d *Data) *SafeData {
+	return &SafeData{d: d}
+}
+
+func (s *SafeData) AddPoint(p ping.PingResults) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.d.AddPoint(p)
+}
+
+// AddPointDedup see [Data.AddPointDedup].
+func (s *SafeData) AddPointDedup(p ping.PingResults) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.AddPointDedup(p)
+}
+
+func (s *SafeData) AsCompact(w io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.AsCompact(w)
+}
+
+func (s *SafeData) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.String()
+}
+
+// Read calls f with the lock held, anything from the data which is used after f returns (e.g. a [Block] or
+// the [Header]) must be copied.
+func (s *SafeData) Read(f func(d *Data)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(s.d)
+}
@@ -0,0 +1,54 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package data_test
+
+import (
+	"bytes"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/ping"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSafeData is most useful when run with -race.
+func TestSafeData(t *testing.T) {
+	t.Parallel()
+	safe := data.NewSafeData(data.NewData("www.google.com"))
+	const writers = 4
+	const points = 100
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range points {
+				safe.AddPoint(ping.PingResults{
+					Data: ping.PingDataPoint{Duration: time.Millisecond, Timestamp: origin.Add(time.Duration(w*points+i) * time.Second)},
+					IP:   net.IPv4allrouter,
+				})
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range points {
+			var b bytes.Buffer
+			require.NoError(t, safe.AsCompact(&b))
+			_ = safe.String()
+		}
+	}()
+	wg.Wait()
+	safe.Read(func(d *data.Data) {
+		require.Equal(t, int64(writers*points), d.TotalCount)
+		require.Equal(t, uint64(writers*points), d.Header.Stats.GoodCount)
+	})
+}
@@ -29,6 +29,9 @@ const (
 	_ Identifier = 0xff
 )
 
+// ReadData reads a `.pings` file written by [Data.AsCompact]. Every version of this compact format (see
+// [currentDataVersion]) can be read, anything else is an error. There is no earlier on-disk format to migrate
+// from, the compact Data has been the only format `.pings` files have been written in.
 func ReadData(r io.Reader) (*Data, error) {
 	toReadFrom, err := io.ReadAll(r)
 	if err != nil {
@@ -72,7 +75,7 @@ func (d *Data) AsCompact(w io.Writer) error {
 func (d *Data) write(ret []byte) int {
 	networkHeader, networkData := d.Network.twoPhaseWrite()
 	i := writeByte(ret, DataID)
-	i += writeByte(ret[i:], d.Version)
+	i += writeByte(ret[i:], byte(currentDataVersion))
 	i += writeLen(ret[i:], d.InsertOrder)
 	i += writeInt64(ret[i:], d.TotalCount)
 	i += networkHeader(ret[i:])
@@ -96,9 +99,16 @@ func (d *Data) write(ret []byte) int {
 		i += blockData(ret[i:])
 	}
 	i += writeString(ret[i:], d.URL)
+	i += writeStringLen(ret[i:], d.Location)
+	i += writeString(ret[i:], d.Location)
+	for _, block := range d.Blocks {
+		i += block.writeTTLs(ret[i:])
+	}
 	return i
 }
 
+// FromCompact reads data written by [Data.AsCompact] in any version up to the current version. Input which
+// isn't a complete compact Data (e.g. a truncated or corrupt file) returns an error rather than panicking.
 func (d *Data) FromCompact(input []byte) (int, error) {
 	if d.Network == nil {
 		d.Network = &Network{}
@@ -108,12 +118,22 @@ func (d *Data) FromCompact(input []byte) (int, error) {
 	}
 	i, err := readID(input, DataID)
 	if err != nil {
+		return i, errors.Wrap(err, "while reading compact Data, this isn't a .pings file")
+	}
+	if err = need(input[i:], 1+2*int64Len); err != nil {
 		return i, errors.Wrap(err, "while reading compact Data")
 	}
 	i += readByte(input[i:], &d.Version)
+	if d.Version == 0 || d.Version > currentDataVersion {
+		return i, errors.Errorf("unknown data version %d, this build supports up to %d", d.Version, currentDataVersion)
+	}
 	insertOrderLen := 0
 	i += readLen(input[i:], &insertOrderLen)
 	i += readInt64(input[i:], &d.TotalCount)
+	if d.TotalCount != int64(insertOrderLen) {
+		return i, errors.Errorf("while reading compact Data, %d points were counted but %d were inserted",
+			d.TotalCount, insertOrderLen)
+	}
 	networkHeaderReader, networkDataReader := d.Network.twoPhaseRead()
 	var IPsLen, blockIndexesLen int
 	n, err := networkHeaderReader(input[i:], &IPsLen, &blockIndexesLen)
@@ -121,23 +141,31 @@ func (d *Data) FromCompact(input []byte) (int, error) {
 		return i, errors.Wrap(err, "while reading compact Data")
 	}
 	i += n
+	if err = need(input[i:], 2*int64Len); err != nil {
+		return i, errors.Wrap(err, "while reading compact Data")
+	}
 	i += readInt(input[i:], &n) // drop block header len, we know it's fixed until new versions are introduced
 	blockLen := 0
 	i += readLen(input[i:], &blockLen)
+	if err = needEach(input[i:], blockLen, blockHeaderLen()); err != nil {
+		return i, errors.Wrap(err, "while reading compact Data's blocks")
+	}
 	d.Blocks = make([]*Block, blockLen)
-	blockSizes := make([]*int, blockLen)
+	blockSizes := make([]int, blockLen)
 	blockReads := make([]BlockRead, blockLen)
 	for index := range blockLen {
 		d.Blocks[index] = &Block{}
-		blockSizes[index] = new(int)
 		header, data := d.Blocks[index].twoPhaseRead()
-		n, err := header(input[i:], blockSizes[index])
+		n, err := header(input[i:], &blockSizes[index])
 		if err != nil {
 			return i, errors.Wrap(err, "while reading compact Data")
 		}
 		i += n
 		blockReads[index] = data
 	}
+	if err = need(input[i:], int64Len); err != nil {
+		return i, errors.Wrap(err, "while reading compact Data's URL")
+	}
 	URLLen := 0
 	i += readLen(input[i:], &URLLen)
 	n, err = d.Header.FromCompact(input[i:])
@@ -147,6 +175,9 @@ func (d *Data) FromCompact(input []byte) (int, error) {
 	i += n
 
 	// Phase 2 read the variable sized data
+	if err = needEach(input[i:], insertOrderLen, dataIndexesLen); err != nil {
+		return i, errors.Wrap(err, "while reading compact Data's insert order")
+	}
 	d.InsertOrder = make([]DataIndexes, insertOrderLen)
 	for index := range d.InsertOrder {
 		insert := &d.InsertOrder[index]
@@ -154,16 +185,71 @@ func (d *Data) FromCompact(input []byte) (int, error) {
 		if err != nil {
 			return i, errors.Wrap(err, "while reading compact Data")
 		}
+		if insert.BlockIndex < 0 || insert.BlockIndex >= blockLen ||
+			insert.RawIndex < 0 || insert.RawIndex >= blockSizes[insert.BlockIndex] {
+			return i, errors.Errorf("while reading compact Data, point %d is at %+v which isn't in any block", index, *insert)
+		}
 		i += n
 	}
-	i += networkDataReader(input[i:], IPsLen, blockIndexesLen)
+	n, err = networkDataReader(input[i:], IPsLen, blockIndexesLen)
+	if err != nil {
+		return i, errors.Wrap(err, "while reading compact Data")
+	}
+	i += n
+	for _, index := range d.Network.BlockIndexes {
+		if index < 0 || index >= blockLen {
+			return i, errors.Errorf("while reading compact Data, an IP is in block %d but there are %d blocks", index, blockLen)
+		}
+	}
 	for index, blockData := range blockReads {
-		i += blockData(input[i:], *blockSizes[index])
+		n, err := blockData(input[i:], blockSizes[index])
+		if err != nil {
+			return i, errors.Wrap(err, "while reading compact Data")
+		}
+		i += n
+	}
+	if err = need(input[i:], URLLen); err != nil {
+		return i, errors.Wrap(err, "while reading compact Data's URL")
 	}
 	i += readString(input[i:], &d.URL, URLLen)
+	if d.Version >= 2 {
+		if err = need(input[i:], int64Len); err != nil {
+			return i, errors.Wrap(err, "while reading compact Data's location")
+		}
+		locationLen := 0
+		i += readLen(input[i:], &locationLen)
+		if err = need(input[i:], locationLen); err != nil {
+			return i, errors.Wrap(err, "while reading compact Data's location")
+		}
+		i += readString(input[i:], &d.Location, locationLen)
+	}
+	if d.Version >= 3 {
+		for _, block := range d.Blocks {
+			n, err := block.readTTLs(input[i:])
+			if err != nil {
+				return i, errors.Wrap(err, "while reading compact Data")
+			}
+			i += n
+		}
+	}
+	d.countInternalErrors()
 	return i, nil
 }
 
+// countInternalErrors re-counts the [Stats.InternalErrors] from the points, since they aren't stored.
+func (d *Data) countInternalErrors() {
+	d.Header.Stats.InternalErrors = 0
+	for _, block := range d.Blocks {
+		block.Header.Stats.InternalErrors = 0
+		for _, p := range block.Raw {
+			if p.InternalError() {
+				block.Header.Stats.AddInternalError()
+				d.Header.Stats.AddInternalError()
+			}
+		}
+	}
+}
+
 func (d *Data) byteLen() int {
 	return idLen + // Identifier
 		1 + // Version
@@ -173,7 +259,17 @@ func (d *Data) byteLen() int {
 		intLen + // block
 		sliceLenCompact(d.Blocks) +
 		sliceLenFixed(d.InsertOrder, dataIndexesLen) +
-		stringLen(d.URL)
+		stringLen(d.URL) +
+		stringLen(d.Location) +
+		d.ttlsLen()
+}
+
+func (d *Data) ttlsLen() int {
+	l := 0
+	for _, block := range d.Blocks {
+		l += len(block.Raw) * ttlLen
+	}
+	return l
 }
 
 func (b *Block) AsCompact(w io.Writer) error {
@@ -190,7 +286,8 @@ func (b *Block) FromCompact(input []byte) (int, error) {
 	if err != nil {
 		return i, err
 	}
-	return data(input[i:], rawLen), nil
+	n, err := data(input[i:], rawLen)
+	return i + n, err
 }
 
 func (b *Block) write(ret []byte) int {
@@ -215,7 +312,7 @@ func (b *Block) twoPhaseWrite() (PhasedWrite, PhasedWrite) {
 		}
 }
 
-type BlockRead = func(input []byte, rawLen int) int
+type BlockRead = func(input []byte, rawLen int) (int, error)
 
 func (b *Block) twoPhaseRead() (
 	func(input []byte, rawLen *int) (int, error),
@@ -229,6 +326,9 @@ func (b *Block) twoPhaseRead() (
 			if err != nil {
 				return i, errors.Wrap(err, "while reading compact Block")
 			}
+			if err = need(input[i:], int64Len); err != nil {
+				return i, errors.Wrap(err, "while reading compact Block")
+			}
 			i += readLen(input[i:], blockLen)
 			n, err := b.Header.FromCompact(input[i:])
 			if err != nil {
@@ -236,16 +336,44 @@ func (b *Block) twoPhaseRead() (
 			}
 			return i + n, err
 		},
-		func(input []byte, rawLen int) int {
+		func(input []byte, rawLen int) (int, error) {
+			if err := needEach(input, rawLen, pingDataPointLen); err != nil {
+				return 0, errors.Wrap(err, "while reading compact Block's points")
+			}
 			b.Raw = make([]ping.PingDataPoint, rawLen)
 			i := 0
 			for rawIndex := range b.Raw {
-				i += readPingDataPoint(input[i:], &b.Raw[rawIndex])
+				n, err := readPingDataPoint(input[i:], &b.Raw[rawIndex])
+				if err != nil {
+					return i, errors.Wrap(err, "while reading compact Block's points")
+				}
+				i += n
 			}
-			return i
+			return i, nil
 		}
 }
 
+// writeTTLs writes the [ping.PingDataPoint.TTL] of every point in the block, these are written separately to
+// the points since they were added in version 3.
+func (b *Block) writeTTLs(ret []byte) int {
+	i := 0
+	for _, raw := range b.Raw {
+		i += writeByte(ret[i:], raw.TTL)
+	}
+	return i
+}
+
+func (b *Block) readTTLs(input []byte) (int, error) {
+	if err := needEach(input, len(b.Raw), ttlLen); err != nil {
+		return 0, errors.Wrap(err, "while reading compact Block's TTLs")
+	}
+	i := 0
+	for rawIndex := range b.Raw {
+		i += readByte(input[i:], &b.Raw[rawIndex].TTL)
+	}
+	return i, nil
+}
+
 func (b *Block) byteLen() int {
 	return idLen + headerLen + sliceLenFixed(b.Raw, pingDataPointLen)
 }
@@ -332,18 +460,27 @@ func (n *Network) twoPhaseWrite() (PhasedWrite, PhasedWrite) {
 
 func (n *Network) twoPhaseRead() (
 	func(input []byte, IPsLen, blockIndexesLen *int) (int, error),
-	func(input []byte, IPsLen, blockIndexesLen int) int) {
+	func(input []byte, IPsLen, blockIndexesLen int) (int, error)) {
 	return func(input []byte, IPsLen, blockIndexesLen *int) (int, error) {
 			i, err := readID(input, NetworkID)
 			if err != nil {
 				return i, errors.Wrap(err, "while reading compact Network")
 			}
+			if err = need(input[i:], 3*int64Len); err != nil {
+				return i, errors.Wrap(err, "while reading compact Network")
+			}
 			i += readInt(input[i:], &n.curBlockIndex)
 			i += readLen(input[i:], IPsLen)
 			i += readLen(input[i:], blockIndexesLen)
 			return i, nil
 		},
-		func(input []byte, IPsLen, blockIndexesLen int) int {
+		func(input []byte, IPsLen, blockIndexesLen int) (int, error) {
+			if err := needEach(input, IPsLen, netIPLen); err != nil {
+				return 0, errors.Wrap(err, "while reading compact Network's IPs")
+			}
+			if err := needEach(input[IPsLen*netIPLen:], blockIndexesLen, intLen); err != nil {
+				return 0, errors.Wrap(err, "while reading compact Network's blocks")
+			}
 			n.IPs = make([]net.IP, IPsLen)
 			n.BlockIndexes = make([]int, blockIndexesLen)
 			i := 0
@@ -354,7 +491,7 @@ func (n *Network) twoPhaseRead() (
 			for blockIndex := range n.BlockIndexes {
 				i += readInt(input[i:], &n.BlockIndexes[blockIndex])
 			}
-			return i
+			return i, nil
 		}
 }
 
@@ -370,7 +507,8 @@ func (n *Network) FromCompact(input []byte) (int, error) {
 	if err != nil {
 		return i, err
 	}
-	return data(input[i:], IPsLen, BlockIndexesLen), nil
+	n2, err := data(input[i:], IPsLen, BlockIndexesLen)
+	return i + n2, err
 }
 
 func (s *Stats) AsCompact(w io.Writer) error {
@@ -398,6 +536,9 @@ func (s *Stats) FromCompact(input []byte) (int, error) {
 	if err != nil {
 		return i, errors.Wrap(err, "while reading compact Stats")
 	}
+	if err = need(input[i:], statsLen-idLen); err != nil {
+		return i, errors.Wrap(err, "while reading compact Stats")
+	}
 	i += readDuration(input[i:], &s.Min)
 	i += readDuration(input[i:], &s.Max)
 	i += readFloat64(input[i:], &s.Mean)
@@ -433,6 +574,9 @@ func (ts *TimeSpan) FromCompact(input []byte) (int, error) {
 	if err != nil {
 		return i, errors.Wrap(err, "while reading compact TimeSpan")
 	}
+	if err = need(input[i:], timeSpanLen-idLen); err != nil {
+		return i, errors.Wrap(err, "while reading compact TimeSpan")
+	}
 	i += readTime(input[i:], &ts.Begin)
 	i += readTime(input[i:], &ts.End)
 	i += readDuration(input[i:], &ts.Duration)
@@ -451,6 +595,9 @@ func (di *DataIndexes) AsCompact(w io.Writer) error {
 }
 
 func (di *DataIndexes) FromCompact(input []byte) (int, error) {
+	if err := need(input, dataIndexesLen); err != nil {
+		return 0, errors.Wrap(err, "while reading compact DataIndexes")
+	}
 	i := readInt(input, &di.BlockIndex)
 	i += readInt(input[i:], &di.RawIndex)
 	return i, nil
@@ -476,6 +623,7 @@ const (
 	timeDurationLen = int64Len
 	idLen           = 1
 	netIPLen        = 16
+	ttlLen          = 1
 
 	timeSpanLen      = idLen + 2*timeLen + timeDurationLen
 	statsLen         = idLen + 2*timeDurationLen + 4*float64Len + 2*uint64Len
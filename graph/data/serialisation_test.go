@@ -8,6 +8,8 @@ package data_test
 
 import (
 	"bytes"
+	"encoding/binary"
+	"fmt"
 	"net"
 	"os"
 	"testing"
@@ -132,6 +134,60 @@ func TestCompactData(t *testing.T) {
 	testCompacter(t, testData, &data.Data{})
 }
 
+func TestCompactDataWithInternalErrors(t *testing.T) {
+	t.Parallel()
+	testData := data.NewData("www.google.com")
+	testData.AddPoint(ping.PingResults{
+		Data: ping.PingDataPoint{Duration: 1, Timestamp: time.UnixMilli(1000)},
+		IP:   net.IPv4bcast,
+	})
+	testData.AddPoint(ping.PingResults{
+		Data: ping.PingDataPoint{DropReason: ping.InternalError, Timestamp: time.UnixMilli(2000)},
+		IP:   net.IPv4bcast,
+	})
+	require.Equal(t, uint64(1), testData.Header.Stats.InternalErrors)
+	require.Equal(t, uint64(0), testData.Header.Stats.PacketsDropped)
+	testCompacter(t, testData, &data.Data{})
+}
+
+func TestCompactDataWithLocation(t *testing.T) {
+	t.Parallel()
+	testData := data.NewData("www.google.com")
+	testData.Location = "Europe/London"
+	testData.AddPoint(ping.PingResults{
+		Data: ping.PingDataPoint{Duration: 1, Timestamp: time.UnixMilli(1000)},
+		IP:   net.IPv4bcast,
+	})
+	testCompacter(t, testData, &data.Data{})
+}
+
+func TestCompactDataWithTTL(t *testing.T) {
+	t.Parallel()
+	testData := data.NewData("www.google.com")
+	for i, ttl := range []uint8{0, 57, 57, 118} {
+		testData.AddPoint(ping.PingResults{
+			Data: ping.PingDataPoint{Duration: 1, Timestamp: time.UnixMilli(int64(1000 * (i + 1))), TTL: ttl},
+			IP:   net.IPv4bcast,
+		})
+	}
+	testData.AddPoint(ping.PingResults{
+		Data: ping.PingDataPoint{Duration: 1, Timestamp: time.UnixMilli(5000), TTL: 60},
+		IP:   net.IPv4allrouter,
+	})
+	testCompacter(t, testData, &data.Data{})
+}
+
+func TestReadVersion1(t *testing.T) {
+	t.Parallel()
+	f, err := os.OpenFile("testdata/small-2-02-08-2024.pings", os.O_RDONLY, 0)
+	require.NoError(t, err)
+	defer f.Close()
+	d, err := data.ReadData(f)
+	require.NoError(t, err)
+	require.Equal(t, byte(1), d.Version)
+	require.Equal(t, "", d.Location, "version 1 has no location, local time is used")
+}
+
 func TestCompactLargeData(t *testing.T) {
 	t.Parallel()
 	testData := data.NewData("www.google.com")
@@ -188,3 +244,107 @@ func TestFiles(t *testing.T) {
 		}.Run,
 	)
 }
+
+func TestReadCorruptData(t *testing.T) {
+	t.Parallel()
+	testData := data.NewData("www.google.com")
+	testData.AddPoint(ping.PingResults{
+		Data: ping.PingDataPoint{Duration: 1, Timestamp: time.UnixMilli(1000)},
+		IP:   net.IPv4bcast,
+	})
+	var b bytes.Buffer
+	require.NoError(t, testData.AsCompact(&b))
+	raw := b.Bytes()
+
+	// Every early return for input which stops short.
+	for length := range len(raw) {
+		_, err := data.ReadData(bytes.NewReader(raw[:length]))
+		require.Errorf(t, err, "truncated to %d bytes", length)
+	}
+
+	// Where things are in the compact form of the one point data, see [data.Data.AsCompact].
+	const (
+		insertOrderLen  = 2
+		totalCount      = 10
+		IPsLen          = 27
+		blocksLen       = 51
+		firstBlockLen   = 60
+		insertOrder     = 258
+		blockIndexes    = 290
+		intLen          = 8
+		huge            = 1 << 62
+		ttls            = 1
+		endOfDropReason = intLen + len("www.google.com") + ttls + 1
+	)
+	setInt := func(at int, value uint64) func([]byte) {
+		return func(b []byte) { binary.LittleEndian.PutUint64(b[at:], value) }
+	}
+	for _, tc := range []struct {
+		name     string
+		corrupt  func([]byte)
+		expected string
+	}{
+		{name: "not data", corrupt: func(b []byte) { b[0] = byte(data.BlockID) }, expected: "this isn't a .pings file"},
+		{name: "future version", corrupt: func(b []byte) { b[1] = 0xff }, expected: "unknown data version 255, this build supports up to 3"},
+		{name: "count mismatch", corrupt: setInt(totalCount, 2), expected: "2 points were counted but 1 were inserted"},
+		{name: "huge insert order", corrupt: func(b []byte) {
+			setInt(insertOrderLen, huge)(b)
+			setInt(totalCount, huge)(b)
+		}, expected: "insert order"},
+		{name: "huge IPs", corrupt: setInt(IPsLen, huge), expected: "Network's IPs"},
+		{name: "huge blocks", corrupt: setInt(blocksLen, huge), expected: "Data's blocks"},
+		{name: "huge block", corrupt: setInt(firstBlockLen, huge), expected: "Block's points"},
+		{name: "missing block", corrupt: setInt(insertOrder, 1), expected: "point 0 is at {BlockIndex:1 RawIndex:0}"},
+		{name: "missing point", corrupt: setInt(insertOrder+intLen, 1), expected: "point 0 is at {BlockIndex:0 RawIndex:1}"},
+		{name: "IP without a block", corrupt: setInt(blockIndexes, 3), expected: "an IP is in block 3 but there are 1 blocks"},
+		{name: "drop reason", corrupt: func(b []byte) { b[len(b)-endOfDropReason] = 0xab }, expected: "unknown drop reason 171"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			corrupt := bytes.Clone(raw)
+			tc.corrupt(corrupt)
+			_, err := data.ReadData(bytes.NewReader(corrupt))
+			require.ErrorContains(t, err, tc.expected)
+		})
+	}
+}
+
+// BenchmarkCompact measures a round-trip of a capture through the compact format, as happens when a capture is
+// saved and later re-opened.
+func BenchmarkCompact(b *testing.B) {
+	for _, points := range []int{1_000, 10_000, 100_000, 1_000_000} {
+		d := data.NewData("www.google.com")
+		for i := range points {
+			d.AddPoint(ping.PingResults{
+				Data: ping.PingDataPoint{
+					Duration:  time.Duration(i%1000) * time.Microsecond,
+					Timestamp: time.Time{}.Add(time.Duration(i) * time.Second),
+				},
+				IP: net.IPv4(8, 8, 8, 8),
+			})
+		}
+		var buf bytes.Buffer
+		require.NoError(b, d.AsCompact(&buf))
+		encoded := buf.Bytes()
+		b.Run(fmt.Sprintf("AsCompact/points=%d", points), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(encoded)))
+			var w bytes.Buffer
+			for range b.N {
+				w.Reset()
+				if err := d.AsCompact(&w); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("FromCompact/points=%d", points), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(encoded)))
+			for range b.N {
+				if _, err := (&data.Data{}).FromCompact(encoded); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
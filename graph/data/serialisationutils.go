@@ -16,6 +16,25 @@ import (
 	"github.com/Lexer747/AcciPing/utils/errors"
 )
 
+// need is an error when there are fewer than n bytes of input left to read, either the input is truncated or n was
+// read from a corrupt length.
+func need(input []byte, n int) error {
+	if n < 0 || len(input) < n {
+		return errors.Errorf("need %d bytes but only %d are left, the data is truncated or corrupt", n, len(input))
+	}
+	return nil
+}
+
+// needEach is [need] for count items of itemLen bytes each, checked without multiplying so that a corrupt count
+// can't overflow.
+func needEach(input []byte, count, itemLen int) error {
+	if count < 0 || count > len(input)/itemLen {
+		return errors.Errorf("need %d items of %d bytes but only %d bytes are left, the data is truncated or corrupt",
+			count, itemLen, len(input))
+	}
+	return nil
+}
+
 // sliceLenCompact works out the dynamic size for all items in a slice.s
 func sliceLenCompact[S ~[]T, T Compact](slice S) int {
 	i := int64Len // 1 int64 to encode the length
@@ -42,11 +61,17 @@ func writePingDataPoint(b []byte, p ping.PingDataPoint) int {
 	return i
 }
 
-func readPingDataPoint(b []byte, p *ping.PingDataPoint) int {
+func readPingDataPoint(b []byte, p *ping.PingDataPoint) (int, error) {
+	if err := need(b, pingDataPointLen); err != nil {
+		return 0, err
+	}
 	i := readDuration(b, &p.Duration)
 	i += readTime(b[i:], &p.Timestamp)
 	i += readByte(b[i:], &p.DropReason)
-	return i
+	if p.DropReason > ping.InternalError && p.DropReason != ping.TestDrop {
+		return i, errors.Errorf("unknown drop reason %d", p.DropReason)
+	}
+	return i, nil
 }
 
 func writeTime(b []byte, t time.Time) int {
@@ -83,6 +108,9 @@ func readIP(b []byte, ip net.IP) int {
 }
 
 func readID(b []byte, id Identifier) (int, error) {
+	if err := need(b, idLen); err != nil {
+		return 0, err
+	}
 	if id != Identifier(b[0]) {
 		return 0, errors.Errorf("Unexpected id %d != %d", b[0], id)
 	}
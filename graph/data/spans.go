@@ -0,0 +1,80 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package data
+
+import (
+	"time"
+
+	"github.com/Lexer747/AcciPing/ping"
+)
+
+// SpanConfig is how [DetectSpans] splits the data.
+type SpanConfig struct {
+	// Gap is the time between two consecutive points which splits them into separate spans, zero for
+	// [DefaultSpanGap].
+	Gap time.Duration
+}
+
+// DefaultSpanGap is the gap which splits spans when the [SpanConfig] doesn't pick one.
+const DefaultSpanGap = time.Minute
+
+// Span is a stretch of the data without a gap in it, a file which was captured over many sessions has a span
+// for each session. See [DetectSpans].
+type Span struct {
+	// First and Last are the indexes (see [Data.Get]) of the first and last points in the span.
+	First, Last int64
+	Header      Header
+	// Gap is the time from the last point of this span to the first point of the next, zero for the last span.
+	Gap time.Duration
+}
+
+// Count is the number of points in the span, good, dropped or internal errors.
+func (s Span) Count() int64 {
+	return s.Last - s.First + 1
+}
+
+// Data copies the points of the span out of the data it was detected in into data of its own, with the same URL
+// and location, so that it can be drawn or written on its own.
+func (s Span) Data(d *Data) *Data {
+	ret := NewData(d.URL)
+	ret.Location = d.Location
+	d.Range(s.Header.TimeSpan.Begin, s.Header.TimeSpan.End)(func(i int64, _ ping.PingDataPoint) bool {
+		if i > s.Last {
+			return false
+		}
+		if i >= s.First {
+			ret.AddPoint(d.GetFull(i))
+		}
+		return true
+	})
+	return ret
+}
+
+// DetectSpans splits the points, in the order they were added, wherever consecutive points are more than the
+// gap apart. Like [Data.DropEvents] these are computed from the points each time, the data isn't changed.
+func DetectSpans(d *Data, cfg SpanConfig) []Span {
+	gap := cfg.Gap
+	if gap == 0 {
+		gap = DefaultSpanGap
+	}
+	ret := []Span{}
+	var last time.Time
+	for i := range d.TotalCount {
+		p := d.Get(i)
+		if i == 0 || p.Timestamp.Sub(last) > gap {
+			if len(ret) > 0 {
+				ret[len(ret)-1].Gap = p.Timestamp.Sub(last)
+			}
+			ret = append(ret, Span{First: i, Header: Header{Stats: &Stats{}, TimeSpan: &TimeSpan{}}})
+		}
+		current := &ret[len(ret)-1]
+		current.Header.AddPoint(p)
+		current.Last = i
+		last = p.Timestamp
+	}
+	return ret
+}
@@ -0,0 +1,58 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package data_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/ping"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectSpans(t *testing.T) {
+	t.Parallel()
+	graphData := data.NewData("www.google.com")
+	offsets := []time.Duration{0, time.Second, 2 * time.Second, time.Hour, time.Hour + time.Second, 3 * time.Hour}
+	for i, offset := range offsets {
+		graphData.AddPoint(ping.PingResults{
+			Data: ping.PingDataPoint{Duration: time.Duration(i+1) * time.Millisecond, Timestamp: origin.Add(offset)},
+			IP:   net.IPv4allrouter,
+		})
+	}
+	spans := data.DetectSpans(graphData, data.SpanConfig{})
+	require.Len(t, spans, 3)
+	assert.Equal(t, int64(0), spans[0].First)
+	assert.Equal(t, int64(2), spans[0].Last)
+	assert.Equal(t, int64(3), spans[0].Count())
+	assert.Equal(t, time.Hour-2*time.Second, spans[0].Gap)
+	assert.Equal(t, origin, spans[0].Header.TimeSpan.Begin)
+	assert.Equal(t, 2*time.Second, spans[0].Header.TimeSpan.Duration)
+	assert.InDelta(t, float64(2*time.Millisecond), spans[0].Header.Stats.Mean, 1)
+	assert.Equal(t, int64(3), spans[1].First)
+	assert.Equal(t, int64(2), spans[1].Count())
+	assert.Equal(t, 2*time.Hour-time.Second, spans[1].Gap)
+	assert.Equal(t, int64(5), spans[2].First)
+	assert.Equal(t, int64(1), spans[2].Count())
+	assert.Equal(t, time.Duration(0), spans[2].Gap, "last span")
+
+	require.Len(t, data.DetectSpans(graphData, data.SpanConfig{Gap: 2 * time.Hour}), 1, "no gap is big enough")
+	require.Len(t, data.DetectSpans(graphData, data.SpanConfig{Gap: 500 * time.Millisecond}), 6)
+	assert.Empty(t, data.DetectSpans(data.NewData(""), data.SpanConfig{}))
+
+	graphData.Location = "Europe/London"
+	second := spans[1].Data(graphData)
+	require.Equal(t, int64(2), second.TotalCount)
+	assert.Equal(t, graphData.URL, second.URL)
+	assert.Equal(t, graphData.Location, second.Location)
+	assert.Equal(t, graphData.GetFull(3), second.GetFull(0))
+	assert.Equal(t, graphData.GetFull(4), second.GetFull(1))
+	assert.Equal(t, spans[1].Header.Stats.Mean, second.Header.Stats.Mean)
+}
@@ -0,0 +1,71 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package data
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Lexer747/AcciPing/utils/errors"
+)
+
+// Window is the most recent stretch of the data, either a number of points or a duration. The zero value is
+// no window, see [WindowStats].
+type Window struct {
+	// Points is the number of most recent points in the window, zero for no limit.
+	Points int
+	// Duration is how far back from the most recent point the window reaches, zero for no limit.
+	Duration time.Duration
+}
+
+// ParseWindow parses either a number of points (e.g. "100") or a duration (e.g. "5m"), the empty string is no
+// window.
+func ParseWindow(window string) (Window, error) {
+	if window == "" {
+		return Window{}, nil
+	}
+	if points, err := strconv.Atoi(window); err == nil {
+		if points <= 0 {
+			return Window{}, errors.Errorf("Invalid window %q, expected a positive number of points", window)
+		}
+		return Window{Points: points}, nil
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		return Window{}, errors.Errorf("Invalid window %q, expected a number of points or a duration such as 5m", window)
+	}
+	return Window{Duration: d}, nil
+}
+
+// Enabled is false for the zero window.
+func (w Window) Enabled() bool {
+	return w.Points > 0 || w.Duration > 0
+}
+
+// WindowStats are the stats of the points in the window, alongside the lifetime stats in the [Header]. Stats
+// can't have a point taken away, so rather than keeping a running copy these are computed from the most recent
+// points each time; the data keeps every point already so this only costs the size of the window. Like
+// [DetectSpans] the data isn't changed.
+func WindowStats(d *Data, w Window) *Stats {
+	ret := &Stats{}
+	if d.TotalCount == 0 {
+		return ret
+	}
+	latest := d.Get(d.TotalCount - 1).Timestamp
+	h := Header{Stats: ret, TimeSpan: &TimeSpan{}}
+	for i := d.TotalCount - 1; i >= 0; i-- {
+		if w.Points > 0 && d.TotalCount-i > int64(w.Points) {
+			break
+		}
+		p := d.Get(i)
+		if w.Duration > 0 && latest.Sub(p.Timestamp) > w.Duration {
+			break
+		}
+		h.AddPoint(p)
+	}
+	return ret
+}
@@ -0,0 +1,58 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package data_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/ping"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindowStats(t *testing.T) {
+	t.Parallel()
+	graphData := data.NewData("www.google.com")
+	for i := range 10 {
+		p := ping.PingDataPoint{Duration: time.Duration(i+1) * time.Millisecond, Timestamp: origin.Add(time.Duration(i) * time.Second)}
+		if i == 8 {
+			p = ping.PingDataPoint{DropReason: ping.TestDrop, Timestamp: p.Timestamp}
+		}
+		graphData.AddPoint(ping.PingResults{Data: p, IP: net.IPv4allrouter})
+	}
+	recent := data.WindowStats(graphData, data.Window{Points: 3})
+	assert.Equal(t, uint64(2), recent.GoodCount)
+	assert.Equal(t, uint64(1), recent.PacketsDropped)
+	assert.InDelta(t, float64(9*time.Millisecond), recent.Mean, 1)
+	recent = data.WindowStats(graphData, data.Window{Duration: 2 * time.Second})
+	assert.Equal(t, uint64(3), recent.GoodCount+recent.PacketsDropped, "the window includes its start")
+	recent = data.WindowStats(graphData, data.Window{Points: 100, Duration: time.Second})
+	assert.Equal(t, uint64(2), recent.GoodCount+recent.PacketsDropped, "the smaller limit wins")
+	assert.Equal(t, graphData.Header.Stats.GoodCount, data.WindowStats(graphData, data.Window{Points: 100}).GoodCount)
+	assert.Equal(t, uint64(0), data.WindowStats(data.NewData(""), data.Window{Points: 3}).GoodCount)
+}
+
+func TestParseWindow(t *testing.T) {
+	t.Parallel()
+	for input, expected := range map[string]data.Window{
+		"":    {},
+		"100": {Points: 100},
+		"5m":  {Duration: 5 * time.Minute},
+		"90s": {Duration: 90 * time.Second},
+	} {
+		w, err := data.ParseWindow(input)
+		require.NoError(t, err, "%q", input)
+		require.Equal(t, expected, w, "%q", input)
+	}
+	for _, input := range []string{"0", "-5", "-1m", "soon"} {
+		_, err := data.ParseWindow(input)
+		require.Error(t, err, "%q", input)
+	}
+}
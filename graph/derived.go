@@ -0,0 +1,74 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package graph
+
+import (
+	"github.com/Lexer747/AcciPing/graph/data"
+)
+
+// derived is what's computed from every point of the data for a frame, kept between frames so that it's only
+// computed again once more points have arrived. Guarded by the dataMutex.
+type derived struct {
+	// of is the data this was computed from, and count how many points it had at the time.
+	of    *data.Data
+	count int64
+
+	latencies data.Latencies
+	gaps      *data.Stats
+
+	// warmup and compareWarmup are added to as points arrive rather than emptied, see [warmupStats.get].
+	warmup, compareWarmup warmupStats
+}
+
+// update empties the cache when it's for older or different data.
+func (c *derived) update(d *data.Data) {
+	if c.of == d && c.count == d.TotalCount {
+		return
+	}
+	*c = derived{of: d, count: d.TotalCount, warmup: c.warmup, compareWarmup: c.compareWarmup}
+}
+
+// sortedLatencies are [data.Data.Latencies], sorted once for each new point rather than once for each use.
+func (c *derived) sortedLatencies(d *data.Data) data.Latencies {
+	c.update(d)
+	if c.latencies == nil {
+		c.latencies = d.Latencies()
+	}
+	return c.latencies
+}
+
+// gapStats are [data.Data.GapStats] with the default span gap, computed once for each new point.
+func (c *derived) gapStats(d *data.Data) *data.Stats {
+	c.update(d)
+	if c.gaps == nil {
+		c.gaps = d.GapStats(data.SpanConfig{})
+	}
+	return c.gaps
+}
+
+// warmupStats are the stats of the data without its warmup packets, see [data.Warmup.Stats].
+type warmupStats struct {
+	of     *data.Data
+	n      int
+	count  int64
+	warmup *data.Warmup
+	stats  data.Stats
+}
+
+// get is the stats of the data without the first n good packets after each DNS resolution. Only the points added
+// since the last call are scanned, unless the data or n have changed.
+func (w *warmupStats) get(d *data.Data, n int) data.Stats {
+	if w.of != d || w.n != n {
+		*w = warmupStats{of: d, n: n, warmup: d.Warmup(n)}
+	}
+	if w.count < d.TotalCount {
+		w.warmup.Update()
+		w.warmup.AddStats(&w.stats, w.count)
+		w.count = d.TotalCount
+	}
+	return w.stats
+}
@@ -9,8 +9,12 @@ package graph
 import (
 	"fmt"
 	"math"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/Lexer747/AcciPing/graph/data"
 	"github.com/Lexer747/AcciPing/graph/terminal"
@@ -32,38 +36,213 @@ func getTimeBetweenFrames(fps int, pingsPerMinute float64) time.Duration {
 // TODO compute the frame into an existing buffer instead of a string API
 func (g *Graph) computeFrame(timeBetweenFrames time.Duration, drawSpinner bool) string {
 	s := g.Term.Size() // This is race-y so ensure a consistent size for rendering
+	if tooSmallToDraw(s) {
+		if g.lastFrame.tooSmall && g.lastFrame.Match(s) {
+			return "" // Already told the user
+		}
+		// Forget the last frame so that once the terminal is big enough again we re-draw from scratch.
+		g.lastFrame = frame{
+			yAxis:        yAxis{size: s.Height},
+			xAxis:        xAxis{size: s.Width},
+			spinnerIndex: g.lastFrame.spinnerIndex,
+			tooSmall:     true,
+		}
+		return drawTooSmall(s)
+	}
 	g.dataMutex.Lock()
 	count := g.data.TotalCount
-	if count == 0 {
-		g.dataMutex.Unlock()
-		return "" // no data yet
-	}
+	opts := g.options
 	spinnerValue := ""
 	if drawSpinner {
 		g.lastFrame.spinnerIndex++
-		spinnerValue = spinner(s, g.lastFrame.spinnerIndex, timeBetweenFrames)
+		spinnerValue = opts.glyphs(spinner(s, g.lastFrame.spinnerIndex, timeBetweenFrames))
+	}
+	if count == 0 {
+		defer g.dataMutex.Unlock()
+		return g.waitingFrame(s, spinnerValue)
+	}
+	status := spinnerValue
+	if opts.detectStale {
+		status += opts.glyphs(g.staleNotice(s))
 	}
 	if count == g.lastFrame.PacketCount && g.lastFrame.Match(s) {
 		g.dataMutex.Unlock() // fast path the frame didn't change
-		return spinnerValue
+		if status == g.lastFrame.spinner {
+			return "" // Nothing changed at all, the spinner only moves every few frames
+		}
+		g.lastFrame.spinner = status
+		return status
 	}
 
-	x := computeXAxis(s.Width, g.data.Header.TimeSpan)
-	y := computeYAxis(s, g.data.Header.Stats, g.url)
-	innerFrame := computeInnerFrame(s, g.data, y)
+	x, y, innerFrame := g.computeFrameParts(s, opts)
 	// Everything we need is now cached we can unlock a bit early while we tidy up for the next frame
 	g.dataMutex.Unlock()
-	finished := paint(s, x.axis, y.axis, innerFrame, spinnerValue)
+	finished := paint(s, x.axis, y.axis, innerFrame, status)
 	g.lastFrame = frame{
 		PacketCount:  count,
 		yAxis:        y,
 		xAxis:        x,
 		insideFrame:  innerFrame,
 		spinnerIndex: g.lastFrame.spinnerIndex,
+		spinner:      status,
 	}
 	return finished
 }
 
+const (
+	// staleIntervals is how many pings can be missing before the capture is considered stale.
+	staleIntervals = 5
+	// minStaleAge stops captures which ping as fast as possible from being flagged by any small hiccup.
+	minStaleAge = 5 * time.Second
+)
+
+// staleNotice is drawn along the top of the graph, left of the spinner, when the newest point is older than a
+// few pings, otherwise it's empty. The expected time between pings comes from the pings per minute or if
+// that's unknown the average over the data. Should be called with the dataMutex held.
+func (g *Graph) staleNotice(s terminal.Size) string {
+	if !g.arrived {
+		// The newest point was read with the data, on startup it's as old as the file.
+		return ""
+	}
+	interval := ping.PingsPerMinuteToDuration(g.pingsPerMinute)
+	if interval == 0 && g.data.TotalCount > 1 {
+		interval = g.data.Header.TimeSpan.Duration / time.Duration(g.data.TotalCount-1)
+	}
+	age := g.now().Sub(g.data.Header.TimeSpan.End)
+	if age < max(staleIntervals*interval, minStaleAge) {
+		return ""
+	}
+	notice := " stale - no data for " + age.Truncate(time.Second).String() + " "
+	column := s.Width - 4 - len(notice)
+	if column < 1 {
+		return ""
+	}
+	return ansi.CursorPosition(1, column) + ansi.Yellow(notice)
+}
+
+// glyphs applies the ASCII fallback to the drawn string if enabled.
+func (opts drawOptions) glyphs(drawn string) string {
+	if opts.ascii {
+		return typography.ASCII(drawn)
+	}
+	return drawn
+}
+
+// computeFrameParts computes the axes and everything inside them for the size, should be called with the
+// dataMutex held and at least one point of data.
+func (g *Graph) computeFrameParts(s terminal.Size, opts drawOptions) (xAxis, yAxis, string) {
+	d := g.data
+	if opts.warmup > 0 {
+		d = withoutWarmup(d, g.derived.warmup.get(d, opts.warmup))
+	}
+	var unscaled [2]*data.Data
+	if opts.comparing() {
+		compare := opts.compare
+		if opts.warmup > 0 {
+			compare = withoutWarmup(compare, g.derived.compareWarmup.get(compare, opts.warmup))
+		}
+		// Kept for the legend, which has the mean of each.
+		unscaled = [2]*data.Data{d, compare}
+		// opts is a copy, the other data is swapped for its copy on the shared scale.
+		d, opts.compare = sharedScale(d, compare)
+		opts.renderer = DefaultRenderer
+	}
+	graphSize := opts.graphSize(s)
+	var x xAxis
+	if opts.lossGauge && graphSize.Width >= minLossGaugeWidth {
+		// The gauge is drawn over the end of the x-axis, the labels stay where they'd be without it.
+		x = computeXAxis(graphSize.Width, lossGaugeWidth, d.Header.TimeSpan, opts)
+		x.axis += lossGauge(d.Header.Stats)
+	} else {
+		x = computeXAxis(graphSize.Width, 0, d.Header.TimeSpan, opts)
+	}
+	var percentiles []percentileLine
+	if opts.yLabels == PercentileYLabels {
+		percentiles = percentileLines(d, g.derived.sortedLatencies(g.data), graphSize)
+	}
+	var recent *data.Stats
+	if opts.window.Enabled() {
+		recent = data.WindowStats(d, opts.window)
+	}
+	y := computeYAxis(graphSize, d.Header.Stats, recent, g.title(), opts.durationFormat, percentiles)
+	g.plotColumns = [2]int{y.labelSize, graphSize.Width - 1}
+	innerFrame := computeInnerFrame(graphSize, d, x, y, opts, &g.window)
+	if opts.comparing() {
+		innerFrame += compareLegend(graphSize, y.labelSize, unscaled[0], unscaled[1], opts.compareNames, opts)
+	}
+	if opts.showPanel(s) {
+		lines := g.readout(d, opts)
+		var b strings.Builder
+		drawPanel(&b, placePanel(frameLayout(s), s, len(lines)), lines)
+		innerFrame += b.String()
+		// The frame is still for the whole terminal, see [frame.Match].
+		x.size = s.Width
+	}
+	innerFrame = opts.glyphs(innerFrame)
+	x.axis = opts.glyphs(x.axis)
+	y.axis = opts.glyphs(y.axis)
+	return x, y, innerFrame
+}
+
+const (
+	minDrawableHeight = 5
+	minDrawableWidth  = 20
+)
+
+// tooSmallToDraw is true when the terminal doesn't have enough space to draw the axes and a meaningful amount
+// of data.
+func tooSmallToDraw(s terminal.Size) bool {
+	return s.Height < minDrawableHeight || s.Width < minDrawableWidth
+}
+
+// drawTooSmall is drawn instead of the graph when [tooSmallToDraw], it says how big the terminal needs to be in
+// as much detail as fits.
+func drawTooSmall(s terminal.Size) string {
+	need := fmt.Sprintf("\u2265%dx%d", minDrawableWidth, minDrawableHeight)
+	msg := need
+	for _, candidate := range []string{"Terminal too small (need " + need + ")", "Too small, need " + need, "Need " + need} {
+		if utf8.RuneCountInString(candidate) <= s.Width {
+			msg = candidate
+			break
+		}
+	}
+	return ansi.Clear + drawCentred(s, msg, ansi.Yellow)
+}
+
+// waitingFrame is drawn until the first point arrives, it should be called with the dataMutex held. Like any
+// other frame once drawn only changes to the spinner are re-drawn.
+func (g *Graph) waitingFrame(s terminal.Size, spinnerValue string) string {
+	if g.lastFrame.waiting && g.lastFrame.Match(s) {
+		if spinnerValue == g.lastFrame.spinner {
+			return ""
+		}
+		g.lastFrame.spinner = spinnerValue
+		return spinnerValue
+	}
+	g.lastFrame = frame{
+		yAxis:        yAxis{size: s.Height},
+		xAxis:        xAxis{size: s.Width},
+		spinnerIndex: g.lastFrame.spinnerIndex,
+		spinner:      spinnerValue,
+		waiting:      true,
+	}
+	return drawWaiting(s, g.url) + spinnerValue
+}
+
+func drawWaiting(s terminal.Size, url string) string {
+	return ansi.Clear + drawCentred(s, "Waiting for first reply to "+url+"...", ansi.Cyan)
+}
+
+// drawCentred draws the message in the middle of the terminal, cut short if the terminal isn't wide enough.
+func drawCentred(s terminal.Size, msg string, colour func(string) string) string {
+	if runes := []rune(msg); len(runes) > s.Width {
+		msg = string(runes[:max(s.Width, 0)])
+	}
+	row := max(s.Height/2, 1)
+	column := max((s.Width-utf8.RuneCountInString(msg))/2, 0) + 1
+	return ansi.CursorPosition(row, column) + colour(msg)
+}
+
 var spinnerArray = [...]string{
 	typography.UpperLeftQuadrantCircularArc,
 	typography.UpperRightQuadrantCircularArc,
@@ -82,31 +261,80 @@ func spinner(s terminal.Size, i int, timeBetweenFrames time.Duration) string {
 	return ansi.CursorPosition(1, s.Width-3) + ansi.Cyan(spinnerArray[a%len(spinnerArray)])
 }
 
-func translate(s terminal.Size, p ping.PingDataPoint, info *data.Header, labelSize int) (y, x int) {
-	x = getX(p.Timestamp, info, s, labelSize)
+func translate(s terminal.Size, p ping.PingDataPoint, info *data.Header, labelSize int, reverse bool) (y, x int) {
+	x = getX(p.Timestamp, info, s, labelSize, reverse)
 	y = getY(p.Duration, info, s)
 	return
 }
 
 func getY(dur time.Duration, info *data.Header, s terminal.Size) int {
-	return int(numeric.NormalizeToRange(
+	// Points which aren't part of the stats (see [withoutWarmup]) can be outside the range, these are pinned to
+	// the edge of the graph.
+	return min(max(int(yPosition(dur, info, s)), 2), s.Height-1)
+}
+
+// yPosition is [getY] before it's truncated to a row, the fraction is how far down the row the duration is.
+func yPosition(dur time.Duration, info *data.Header, s terminal.Size) float64 {
+	if info.Stats.Min == info.Stats.Max {
+		// Every point has the same latency (or there are no good points), there's no range to normalise
+		// against so draw everything along the centre of the graph.
+		return float64(flatRow(s))
+	}
+	return numeric.NormalizeToRange(
 		float64(dur),
 		float64(info.Stats.Min),
 		float64(info.Stats.Max),
 		float64(s.Height-1),
 		2,
-	))
+	)
+}
+
+// flatRow is the row every point is drawn on when they all have the same latency, see [isFlat].
+func flatRow(s terminal.Size) int {
+	return (s.Height + 1) / 2
+}
+
+// isFlat is true when there are good points and they all have the same latency, so there's no range on the
+// y-axis.
+func isFlat(stats *data.Stats) bool {
+	return stats.GoodCount > 0 && stats.Min == stats.Max
+}
+
+// withoutWarmup is a shallow copy of the data with the statistics which exclude the warmup packets (see
+// [warmupStats]), so that they don't skew the title or y-axis. The points are shared, it must not be added to.
+func withoutWarmup(d *data.Data, stats data.Stats) *data.Data {
+	header := *d.Header
+	header.Stats = &stats
+	ret := *d
+	ret.Header = &header
+	return &ret
 }
 
-func getX(t time.Time, info *data.Header, s terminal.Size, labelSize int) int {
+// getX maps the time to a column, the newest time is on the right unless reversed in which case the newest is
+// on the left.
+func getX(t time.Time, info *data.Header, s terminal.Size, labelSize int, reverse bool) int {
+	return int(xPosition(t, info, s, labelSize, reverse))
+}
+
+// xPosition is [getX] before it's truncated to a column, the fraction is how far across the column the time
+// is.
+func xPosition(t time.Time, info *data.Header, s terminal.Size, labelSize int, reverse bool) float64 {
+	if info.TimeSpan.Duration == 0 {
+		// Every point has the same timestamp, draw them in the centre of the graph.
+		return float64((s.Width - 1 + labelSize) / 2)
+	}
+	newest, oldest := float64(s.Width-1), float64(labelSize)
+	if reverse {
+		newest, oldest = oldest, newest
+	}
 	timestamp := info.TimeSpan.End.Sub(t)
-	return int(numeric.NormalizeToRange(
+	return numeric.NormalizeToRange(
 		float64(timestamp),
 		0,
 		float64(info.TimeSpan.Duration),
-		float64(s.Width-1),
-		float64(labelSize),
-	))
+		newest,
+		oldest,
+	)
 }
 
 type gradientState struct {
@@ -130,35 +358,188 @@ func (g gradientState) draw() bool {
 	return g.lastGoodIndex != -1
 }
 
-var plain = ansi.White(typography.Multiply)
-var drop = ansi.Red(typography.Block)
 var dropFiller = ansi.Red(typography.LightBlock)
+var dnsDropFiller = ansi.Blue(typography.LightBlock)
 
-func computeInnerFrame(s terminal.Size, d *data.Data, yAxis yAxis) string {
+// computeInnerFrame draws everything inside the axes, the window is re-used between frames (see [drawWindow]).
+func computeInnerFrame(s terminal.Size, d *data.Data, xAxis xAxis, yAxis yAxis, opts drawOptions, window *drawWindow) string {
 	centreY := s.Height / 2
 	centreX := s.Width / 2
+	palette := opts.glyphSet.palette()
 	if d.TotalCount <= 1 {
-		return ansi.CursorPosition(centreY, centreX) + plain + " " + d.Blocks[0].Raw[0].Duration.String()
+		return ansi.CursorPosition(centreY, centreX) + palette.plain + " " + d.Blocks[0].Raw[0].Duration.String()
 	}
-	ret := ""
-	droppedBar, droppedFiller := makeDroppedPacketIndicators(d, s)
+	// The whole frame is composited into one buffer, avoiding the quadratic cost of concatenating every point
+	// onto a string and so that the frame can be written to the terminal in one go.
+	var b strings.Builder
 
 	// Now iterate over all the individual data points and add them to the graph
 
-	if shouldGradient(s, d, yAxis.labelSize) {
-		ret += drawGradients(d, s, yAxis)
+	if opts.grid {
+		drawGrid(&b, s, xAxis, yAxis)
+	}
+	drawPercentileLines(&b, s, yAxis)
+	braille := opts.braille()
+	if !braille && (opts.forceGradients || shouldGradient(s, d, yAxis.labelSize)) {
+		line := grayLine
+		if opts.trueColour {
+			line = newHeatScale(d.Header.Stats).colour
+		}
+		if opts.comparing() {
+			drawGradients(&b, opts.compare, s, yAxis, opts.reverseX, compareLine)
+		}
+		drawGradients(&b, d, s, yAxis, opts.reverseX, line)
+	}
+	if len(opts.annotations) > 0 {
+		drawAnnotations(&b, opts.annotations, d, s, yAxis.labelSize, opts.reverseX)
 	}
+	cursor := opts.cursorColumn(s, yAxis.labelSize)
+	if opts.cursor != 0 {
+		drawCursorLine(&b, s, cursor)
+	}
+
+	var warmup *data.Warmup
+	if opts.warmup > 0 {
+		warmup = d.Warmup(opts.warmup)
+	}
+	var canvas *brailleCanvas
+	if braille {
+		canvas = newBrailleCanvas(s, yAxis.labelSize)
+	}
+	window.reset(s)
+	namePercentileLines(window, yAxis, opts.durationFormat)
+	placer := pointPlacer{
+		d:         d,
+		s:         s,
+		labelSize: yAxis.labelSize,
+		opts:      opts,
+		warmup:    warmup,
+		canvas:    canvas,
+		centreX:   centreX,
+		palette:   palette,
+		// Every point would be both the min and the max, instead of labelling each of them the latency is noted
+		// once.
+		flat: isFlat(d.Header.Stats),
+	}
+	if opts.comparing() {
+		// Drawn first so that the graph's own points are on top where they overlap.
+		compared := placer
+		compared.d = opts.compare
+		compared.palette = opts.glyphSet.comparePalette()
+		compared.warmup = nil
+		if opts.warmup > 0 {
+			compared.warmup = opts.compare.Warmup(opts.warmup)
+		}
+		compared.placeAll(window, braille)
+	}
+	placer.placeAll(window, braille)
+	placeTTLLane(window, canvas, d, s, yAxis.labelSize, opts.reverseX)
+	window.draw(&b)
+	if braille {
+		canvas.draw(&b)
+	}
+	if placer.flat {
+		drawFlatNote(&b, d.Header.Stats, s, yAxis.labelSize)
+	}
+	if hasDNSFailure(d) && keyRowEmpty(window, canvas, s, yAxis.labelSize+1, dropKeyWidth) {
+		drawDropKey(&b, s, yAxis.labelSize, palette)
+	}
+	if opts.markLongestOutage {
+		drawLongestOutage(&b, d, s, yAxis.labelSize, opts.reverseX)
+	}
+	if opts.markLatest && !opts.hideMarkers {
+		drawLatest(&b, d, s, yAxis.labelSize, opts.reverseX, palette)
+		if keyColumn := s.Width - markerKeyWidth; keyColumn > yAxis.labelSize && keyRowEmpty(window, canvas, s, keyColumn, markerKeyWidth) {
+			b.WriteString(ansi.CursorPosition(s.Height-1, keyColumn) + palette.markerKey)
+		}
+	}
+	if opts.cursor != 0 {
+		drawCursorReadout(&b, d, s, yAxis.labelSize, cursor, opts)
+	}
+
+	return b.String()
+}
+
+// drawFlatNote explains the single row of points when every ping has the same latency, it's drawn just below
+// the row and cut short if the graph isn't wide enough.
+func drawFlatNote(b *strings.Builder, stats *data.Stats, s terminal.Size, labelSize int) {
+	note := "every ping took " + stats.Min.String()
+	space := s.Width - labelSize - 1
+	if space <= 0 {
+		return
+	}
+	if len(note) > space {
+		note = note[:space]
+	}
+	// Below the points unless that's the x-axis, then above.
+	row := flatRow(s) + 1
+	if row >= s.Height {
+		row = flatRow(s) - 1
+	}
+	b.WriteString(ansi.CursorPosition(row, labelSize+1) + ansi.Gray(note))
+}
+
+// pointPlacer places each point of the data into a [drawWindow].
+type pointPlacer struct {
+	d         *data.Data
+	s         terminal.Size
+	labelSize int
+	opts      drawOptions
+	warmup    *data.Warmup
+	// canvas is only set for the braille renderer, which is never placed in parallel.
+	canvas  *brailleCanvas
+	centreX int
+	palette palette
+	flat    bool
+}
 
+// place draws the points [from, to) into the window.
+func (pp pointPlacer) place(window *drawWindow, from, to int64) {
+	d, s, opts := pp.d, pp.s, pp.opts
 	lastWasDropped := false
 	lastDroppedTerminalX := -1
-	for i := range d.TotalCount {
+	if from > 0 {
+		// Carry on from the previous point, so that the gap is filled between consecutive dropped packets either
+		// side of from.
+		if previous := d.Get(from - 1); isDroppedColumn(previous) {
+			lastWasDropped = true
+			lastDroppedTerminalX = getX(previous.Timestamp, d.Header, s, pp.labelSize, opts.reverseX)
+		}
+	}
+	for i := from; i < to; i++ {
 		p := d.Get(i)
-		x := getX(p.Timestamp, d.Header, s, yAxis.labelSize)
+		x := getX(p.Timestamp, d.Header, s, pp.labelSize, opts.reverseX)
+		if opts.warmup > 0 && pp.warmup.Contains(i) {
+			// Excluded from the stats, so it's drawn but never labelled as the min or max.
+			window.set(getY(p.Duration, d.Header, s), x, pp.palette.warmup)
+			lastWasDropped = false
+			continue
+		}
+		if p.InternalError() {
+			// Not a network problem so this is only marked along the top, distinct from a dropped packet.
+			window.set(2, x, pp.palette.internalError)
+			lastWasDropped = false
+			continue
+		}
+		if p.DropReason == ping.OutOfOrder {
+			// A late reply, the request it was for has already been drawn as dropped so this is only marked
+			// along the top to show the network is re-ordering.
+			window.set(2, x, pp.palette.outOfOrder)
+			lastWasDropped = false
+			continue
+		}
 		if p.Dropped() {
-			ret += ansi.CursorPosition(2, x) + droppedBar
+			if pp.canvas != nil {
+				pp.canvas.lift()
+			}
+			drawDroppedColumn(window, x, pp.palette.dropped(p.DropReason))
 			if lastWasDropped {
+				filler := dropFiller
+				if p.DropReason == ping.DNSFailure {
+					filler = dnsDropFiller
+				}
 				for i := min(lastDroppedTerminalX, x) + 1; i < max(lastDroppedTerminalX, x); i++ {
-					ret += ansi.CursorPosition(2, i) + droppedFiller
+					drawDroppedColumn(window, i, filler)
 				}
 			}
 			lastWasDropped = true
@@ -166,55 +547,208 @@ func computeInnerFrame(s terminal.Size, d *data.Data, yAxis yAxis) string {
 			continue
 		}
 		lastWasDropped = false
+		if pp.canvas != nil {
+			pp.canvas.plot(xPosition(p.Timestamp, d.Header, s, pp.labelSize, opts.reverseX), yPosition(p.Duration, d.Header, s))
+			continue
+		}
 		y := getY(p.Duration, d.Header, s)
-		ret += drawPoint(p, d, x, y, centreX)
+		if opts.hideMarkers || pp.flat || opts.labels == NoLabels {
+			window.set(y, x, pp.palette.plain)
+		} else {
+			drawPoint(window, p, d, x, y, pp.centreX, opts.labels, pp.palette)
+		}
+	}
+}
+
+// placeAll draws every point into the window, in parallel if there are enough of them.
+func (pp pointPlacer) placeAll(window *drawWindow, braille bool) {
+	if braille || pp.d.TotalCount < parallelThreshold || runtime.GOMAXPROCS(0) == 1 {
+		pp.place(window, 0, pp.d.TotalCount)
+	} else {
+		pp.placeParallel(window)
+	}
+}
+
+// isDroppedColumn is true for the points [pointPlacer.place] draws as a column of dropped packets.
+func isDroppedColumn(p ping.PingDataPoint) bool {
+	return p.Dropped() && !p.OutOfOrder()
+}
+
+// parallelThreshold is the number of points above which they are placed in parallel, below this the cost of
+// the extra windows outweighs the gain.
+const parallelThreshold = 100_000
+
+// placeParallel splits the points into a contiguous chunk per CPU, each placed into its own part of the window
+// (see [drawWindow.parts]) which are then composited in order. The last point written to a cell wins just as
+// when placed serially, so the frame is the same either way.
+func (pp pointPlacer) placeParallel(window *drawWindow) {
+	workers := runtime.GOMAXPROCS(0)
+	if len(window.parts) < workers {
+		window.parts = make([]drawWindow, workers)
+	}
+	chunk := (pp.d.TotalCount + int64(workers) - 1) / int64(workers)
+	var wg sync.WaitGroup
+	for w := range workers {
+		from := int64(w) * chunk
+		to := min(from+chunk, pp.d.TotalCount)
+		part := &window.parts[w]
+		part.reset(pp.s)
+		if from >= to {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pp.place(part, from, to)
+		}()
+	}
+	wg.Wait()
+	for w := range workers {
+		window.composite(&window.parts[w])
+	}
+}
+
+// dropKeyWidth is the space the drop key (see [drawDropKey]) takes up.
+const dropKeyWidth = len("# dropped  # DNS failed")
+
+// drawDropKey explains the colours of the dropped columns, it's drawn in the bottom left of the graph when there
+// are DNS failures to tell apart from the other drops. It's left out if it would overlap the marker key, and
+// by the caller if it would hide a point (see [keyRowEmpty]).
+func drawDropKey(b *strings.Builder, s terminal.Size, labelSize int, palette palette) {
+	if labelSize+1+dropKeyWidth >= s.Width-markerKeyWidth {
+		return
+	}
+	b.WriteString(ansi.CursorPosition(s.Height-1, labelSize+1) +
+		palette.drop + ansi.Gray(" dropped  ") + palette.dnsDrop + ansi.Gray(" DNS failed"))
+}
+
+// hasDNSFailure is true if any packet was dropped because the URL couldn't be resolved.
+func hasDNSFailure(d *data.Data) bool {
+	for i := range d.TotalCount {
+		if d.Get(i).DropReason == ping.DNSFailure {
+			return true
+		}
+	}
+	return false
+}
+
+// markerKeyWidth is the space the marker key (see [palette]) takes up.
+const markerKeyWidth = 20
+
+// drawLatest highlights the most recent good point.
+func drawLatest(b *strings.Builder, d *data.Data, s terminal.Size, labelSize int, reverse bool, palette palette) {
+	for i := d.TotalCount - 1; i >= 0; i-- {
+		p := d.Get(i)
+		if p.Good() {
+			y, x := translate(s, p, d.Header, labelSize, reverse)
+			b.WriteString(ansi.CursorPosition(y, x) + palette.latest)
+			break
+		}
+	}
+}
+
+// keyRowEmpty is true when no point or label is drawn in the width from the column of the bottom row of the
+// graph, where the keys are drawn. A key is left out rather than hide a point. The canvas is nil unless the
+// points are drawn in braille.
+func keyRowEmpty(window *drawWindow, canvas *brailleCanvas, s terminal.Size, column, width int) bool {
+	row := s.Height - 1
+	return window.empty(row, column, column+width) && (canvas == nil || canvas.empty(row, column, column+width))
+}
+
+var gridHorizontal = ansi.Gray(typography.DottedHorizontal)
+var gridVertical = ansi.Gray(typography.DottedVertical)
+
+// drawGrid draws faint lines across the graph from each y-axis label, and down from each x-axis label.
+func drawGrid(b *strings.Builder, s terminal.Size, xAxis xAxis, yAxis yAxis) {
+	for _, row := range yAxis.labelRows {
+		b.WriteString(ansi.CursorPosition(row, yAxis.labelSize) + strings.Repeat(gridHorizontal, s.Width-yAxis.labelSize))
+	}
+	down := strings.Repeat(gridVertical+ansi.CursorDown(1)+ansi.CursorBack(1), max(s.Height-2, 0))
+	for _, column := range xAxis.labelColumns {
+		if column <= yAxis.labelSize {
+			continue // This would be drawn through the y-axis labels
+		}
+		b.WriteString(ansi.CursorPosition(2, column) + down)
 	}
+}
 
-	return ret
+// drawLongestOutage underlines the columns of the longest streak of dropped packets along the bottom of the
+// graph.
+func drawLongestOutage(b *strings.Builder, d *data.Data, s terminal.Size, labelSize int, reverse bool) {
+	events := d.DropEvents()
+	if len(events) == 0 {
+		return
+	}
+	longest := events[0]
+	for _, event := range events[1:] {
+		if event.Length > longest.Length {
+			longest = event
+		}
+	}
+	start := getX(longest.Start, d.Header, s, labelSize, reverse)
+	end := getX(longest.End, d.Header, s, labelSize, reverse)
+	b.WriteString(ansi.CursorPosition(s.Height-1, min(start, end)))
+	b.WriteString(ansi.Yellow(strings.Repeat(typography.BottomLine, numeric.Abs(end-start)+1)))
 }
 
-func drawGradients(d *data.Data, s terminal.Size, yAxis yAxis) string {
-	ret := ""
+// drawGradients draws the interpolated lines between each good point, each step of the line is coloured by the
+// line function from its latency, e.g. [grayLine].
+func drawGradients(
+	b *strings.Builder,
+	d *data.Data,
+	s terminal.Size,
+	yAxis yAxis,
+	reverse bool,
+	line func(latency time.Duration, s string) string,
+) {
 	g := gradientState{}
 	for i := range d.TotalCount {
 		p := d.Get(i)
+		if p.InternalError() || p.DropReason == ping.OutOfOrder {
+			continue
+		}
 		if p.Dropped() {
 			g = g.dropped()
 			continue
 		}
-		y, x := translate(s, p, d.Header, yAxis.labelSize)
+		y, x := translate(s, p, d.Header, yAxis.labelSize, reverse)
 		if g.draw() && !d.IsLast(i) {
-			ret += drawGradient(
-				d.Header, x, y, p, s, yAxis.labelSize,
-				d.Get(g.lastGoodIndex), g.lastGoodTerminalWidth, g.lastGoodTerminalHeight,
+			drawGradient(
+				b,
+				d.Header, x, y, p, s, yAxis.labelSize, reverse,
+				d.Get(g.lastGoodIndex), g.lastGoodTerminalWidth, g.lastGoodTerminalHeight, line,
 			)
 		}
 		g = g.set(i, x, y)
 	}
-	return ret
 }
 
-func makeDroppedPacketIndicators(d *data.Data, s terminal.Size) (string, string) {
-	droppedBar := ""
-	droppedFiller := ""
-	if d.Header.Stats.PacketsDropped > 0 {
-		droppedBar = strings.Repeat(drop+ansi.CursorDown(1)+ansi.CursorBack(1), s.Height-2)
-		droppedFiller = strings.Repeat(dropFiller+ansi.CursorDown(1)+ansi.CursorBack(1), s.Height-2)
+// grayLine is the colour of the lines between points unless they're coloured by latency, see
+// [Graph.SetTrueColour].
+func grayLine(_ time.Duration, s string) string {
+	return ansi.Gray(s)
+}
+
+// drawDroppedColumn fills the column of the graph, from the top to just above the x-axis.
+func drawDroppedColumn(window *drawWindow, x int, glyph string) {
+	for y := 2; y < window.height; y++ {
+		window.set(y, x, glyph)
 	}
-	return droppedBar, droppedFiller
 }
 
 func drawGradient(
+	b *strings.Builder,
 	header *data.Header,
 	x, y int,
 	current ping.PingDataPoint,
 	s terminal.Size,
 	labelSize int,
+	reverse bool,
 	lastGood ping.PingDataPoint,
 	lastGoodTerminalWidth int,
 	lastGoodTerminalHeight int,
-) string {
-	ret := ""
+	line func(latency time.Duration, s string) string,
+) {
 	gradientsToDrawX := float64(numeric.Abs(lastGoodTerminalWidth - x))
 	gradientsToDrawY := float64(numeric.Abs(lastGoodTerminalHeight - y))
 	gradientsToDraw := math.Sqrt(math.Pow(gradientsToDrawX, 2) + math.Pow(gradientsToDrawY, 2))
@@ -223,56 +757,71 @@ func drawGradient(
 
 	pointsX := make([]int, 0)
 	pointsY := make([]int, 0)
+	durations := make([]time.Duration, 0)
 	for toDraw := 1.5; toDraw < gradientsToDraw; toDraw++ {
 		interpolatedDuration := lastGood.Duration + time.Duration(toDraw*stepSizeY)
 		interpolatedStamp := lastGood.Timestamp.Add(time.Duration(toDraw * stepSizeX))
 		p := ping.PingDataPoint{Duration: interpolatedDuration, Timestamp: interpolatedStamp}
-		cursorY, cursorX := translate(s, p, header, labelSize)
+		cursorY, cursorX := translate(s, p, header, labelSize, reverse)
 		pointsX = append(pointsX, cursorX)
 		pointsY = append(pointsY, cursorY)
+		durations = append(durations, interpolatedDuration)
 	}
 	gradient := solve(pointsX, pointsY)
 	for i, g := range gradient {
-		ret += ansi.CursorPosition(pointsY[i], pointsX[i]) + ansi.Gray(g)
+		b.WriteString(ansi.CursorPosition(pointsY[i], pointsX[i]) + line(durations[i], g))
 	}
-	return ret
 }
 
-func drawPoint(p ping.PingDataPoint, d *data.Data, x, y, centreX int) string {
+func drawPoint(window *drawWindow, p ping.PingDataPoint, d *data.Data, x, y, centreX int, labels Labels, palette palette) {
 	leftJustify := x > centreX
 	isMin := p.Duration == d.Header.Stats.Min
 	isMax := p.Duration == d.Header.Stats.Max
+	// The point is drawn even when labelled, in case there's no room for the label.
+	window.set(y, x, palette.plain)
 	switch {
+	case isMin && labels == MarkerLabels:
+		window.addLabel(y, x, x, palette.min, palette.min, ansi.Green)
+	case isMax && labels == MarkerLabels:
+		window.addLabel(y, x, x, palette.max, palette.max, ansi.Red)
 	case isMin && leftJustify:
 		label := p.Duration.String()
-		return ansi.CursorPosition(y, x-len(label)) + ansi.Green(label+" "+typography.UpTriangle)
+		window.addLabel(y, x-len(label), x, label+" "+palette.min, palette.min, ansi.Green)
 	case isMin:
-		return ansi.CursorPosition(y, x) + ansi.Green(typography.UpTriangle+" "+p.Duration.String())
+		window.addLabel(y, x, x, palette.min+" "+p.Duration.String(), palette.min, ansi.Green)
 	case isMax && leftJustify:
 		label := p.Duration.String()
-		return ansi.CursorPosition(y, x-len(label)) + ansi.Red(label+" "+typography.DownTriangle)
+		window.addLabel(y, x-len(label), x, label+" "+palette.max, palette.max, ansi.Red)
 	case isMax:
-		return ansi.CursorPosition(y, x) + ansi.Red(typography.DownTriangle+" "+p.Duration.String())
-	default:
-		return ansi.CursorPosition(y, x) + plain
+		window.addLabel(y, x, x, palette.max+" "+p.Duration.String(), palette.max, ansi.Red)
 	}
 }
 
 func shouldGradient(s terminal.Size, d *data.Data, labelSize int) bool {
 	// TODO account for dropped packets in these positions
 	b := d.Blocks[0]
-	first := getX(b.Raw[0].Timestamp, d.Header, s, labelSize)
-	second := getX(b.Raw[1].Timestamp, d.Header, s, labelSize)
+	// The direction doesn't change the distance between the points
+	first := getX(b.Raw[0].Timestamp, d.Header, s, labelSize, false)
+	second := getX(b.Raw[1].Timestamp, d.Header, s, labelSize, false)
 	return numeric.Abs(first-second) > 0
 }
 
-func computeYAxis(size terminal.Size, stats *data.Stats, url string) yAxis {
+// computeYAxis draws the title and the y-axis, labelled evenly unless there are percentile lines to label
+// instead. The recent stats are shown in the title if there's a window, otherwise they're nil.
+func computeYAxis(
+	size terminal.Size,
+	stats *data.Stats,
+	recent *data.Stats,
+	url string,
+	durationFormat data.DurationFormat,
+	percentiles []percentileLine,
+) yAxis {
 	var b strings.Builder
 	// Making of a buffer of [size] will be too small because ansi + unicode will take up more bytes than the
 	// character space they take up
 	b.Grow(size.Height * 2)
 
-	finalTitle := makeTitle(size, stats, url)
+	finalTitle := makeTitle(size, stats, recent, url, durationFormat)
 	fmt.Fprint(&b, finalTitle)
 
 	gapSize := 3
@@ -283,10 +832,21 @@ func computeYAxis(size terminal.Size, stats *data.Stats, url string) yAxis {
 	}
 	durationSize := (gapSize * 3) / 2
 
+	flat := isFlat(stats)
+	var labelRows []int
 	for i := range size.Height - 2 {
 		h := i + 2
 		fmt.Fprint(&b, ansi.CursorPosition(h, 1))
-		if i%gapSize == 1 {
+		line := slices.IndexFunc(percentiles, func(l percentileLine) bool { return l.row == h })
+		if flat && h == flatRow(size) {
+			// Every label would be the same, so only the row with the points is labelled.
+			labelRows = append(labelRows, h)
+			fmt.Fprint(&b, ansi.Yellow(timeutils.HumanString(stats.Min, durationSize)))
+		} else if !flat && line != -1 {
+			labelRows = append(labelRows, h)
+			fmt.Fprint(&b, ansi.Yellow(timeutils.HumanString(percentiles[line].value, durationSize)))
+		} else if !flat && percentiles == nil && i%gapSize == 1 {
+			labelRows = append(labelRows, h)
 			scaledDuration := numeric.NormalizeToRange(float64(i), float64(size.Height-2), 0, float64(stats.Min), float64(stats.Max))
 			toPrint := timeutils.HumanString(time.Duration(scaledDuration), durationSize)
 			fmt.Fprint(&b, ansi.Yellow(toPrint))
@@ -295,90 +855,199 @@ func computeYAxis(size terminal.Size, stats *data.Stats, url string) yAxis {
 		}
 	}
 	return yAxis{
-		size:      size.Height,
-		stats:     stats,
-		axis:      b.String(),
-		labelSize: durationSize + 4,
+		size:        size.Height,
+		stats:       stats,
+		axis:        b.String(),
+		labelSize:   durationSize + 4,
+		labelRows:   labelRows,
+		percentiles: percentiles,
 	}
 }
 
-func makeTitle(size terminal.Size, stats *data.Stats, url string) string {
+func makeTitle(size terminal.Size, stats, recent *data.Stats, url string, durationFormat data.DurationFormat) string {
 	// TODO string builder, or larger buffer impl
 	const yAxisTitle = "Latency "
 	sizeStr := size.String()
-	titleBegin := ansi.Cyan(url)
-	titleEnd := ansi.Green(sizeStr)
 	remaining := size.Width - len(yAxisTitle) - len(url) - len(sizeStr)
-	statsStr := stats.PickString(remaining)
-	if len(statsStr) > 0 {
-		statsStr = " [" + statsStr + "] "
+	pick := func(remaining int) string {
+		statsStr := stats.PickFormattedString(remaining, durationFormat)
+		if recent != nil {
+			if windowStr := data.WindowString(recent, stats, durationFormat); len(windowStr)+4 < remaining {
+				statsStr = windowStr
+			}
+		}
+		if len(statsStr) > 0 {
+			statsStr = " [" + statsStr + "] "
+		}
+		return statsStr
+	}
+	width := func(statsStr string) int {
+		return utf8.RuneCountInString(yAxisTitle + url + statsStr + sizeStr)
+	}
+	statsStr := pick(remaining)
+	// The stats are picked by a rough guess of their width, pick shorter ones until they fit.
+	for remaining > 0 && width(statsStr) > size.Width {
+		remaining--
+		statsStr = pick(remaining)
 	}
-	title := titleBegin + statsStr + titleEnd
-	titleIndent := (size.Width / 2) - (len(title) / 2)
+	if over := width(statsStr) - size.Width; over > 0 {
+		// Even without the stats there's no room, the URL is cut short and then the size.
+		url = cutRunes(url, utf8.RuneCountInString(url)-over)
+		sizeStr = cutRunes(sizeStr, size.Width-utf8.RuneCountInString(yAxisTitle+url+statsStr))
+	}
+	title := ansi.Cyan(url) + statsStr + ansi.Green(sizeStr)
+	titleIndent := min((size.Width/2)-(len(title)/2), size.Width-width(statsStr))
 	finalTitle := ansi.Home + ansi.Magenta(yAxisTitle) + ansi.CursorForward(titleIndent) + title
 	return finalTitle
 }
 
+// cutRunes is the first n runes of s, none if n isn't positive.
+func cutRunes(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:max(n, 0)])
+	}
+	return s
+}
+
 type yAxis struct {
 	size      int
 	stats     *data.Stats
 	axis      string
 	labelSize int
+	// labelRows are the rows of each duration label.
+	labelRows []int
+	// percentiles are the lines drawn across the graph by [PercentileYLabels], nil otherwise.
+	percentiles []percentileLine
+}
+
+// defaultTimeFormat is the layout of the x-axis labels, see [Graph.SetTimeFormat].
+const defaultTimeFormat = "15:04:05.00"
+
+// timeFormatWidth is the widest label the layout can produce, so that every label takes the same space.
+func timeFormatWidth(layout string) int {
+	width := 0
+	for month := time.January; month <= time.December; month++ {
+		// Cover both single and double digit hours for 12 hour layouts, and every month and day name.
+		for _, hour := range []int{9, 22} {
+			t := time.Date(2006, month, 20+int(month%7), hour, 59, 59, 990_000_000, time.UTC)
+			width = max(width, utf8.RuneCountInString(t.Format(layout)))
+		}
+	}
+	return width
 }
 
-func computeXAxis(size int, span *data.TimeSpan) xAxis {
-	const format = "15:04:05.99"
-	const formatLen = 11
-	const spacePerItem = formatLen + 6
+// relativeLabelWidth fits any elapsed time label under ten hours, like "+9h59m40s". Each is rounded to three
+// significant figures (see [xAxisLabel]) which can be a column wider for a longer capture, like "+10h11m40s".
+const relativeLabelWidth = 9
+
+// xAxisLabel is the label for a time on the x-axis, either the time itself in the format or how long after the
+// capture began it is.
+func xAxisLabel(t time.Time, span *data.TimeSpan, format string, relative bool) string {
+	if relative {
+		return "+" + timeutils.HumanString(t.Sub(span.Begin), 3)
+	}
+	return t.Format(format)
+}
+
+// computeXAxis draws the x-axis for the size, the last reserve columns are left off the axis for something else
+// to be drawn after it. The labels are placed for the whole size, any which would be in the reserve are dropped.
+func computeXAxis(size, reserve int, span *data.TimeSpan, opts drawOptions) xAxis {
+	format := opts.timeFormat
+	if format == "" {
+		format = defaultTimeFormat
+	}
+	formatLen := timeFormatWidth(format)
+	if opts.relativeX {
+		formatLen = relativeLabelWidth
+	}
+	spacePerItem := formatLen + 6
 	padding := ansi.White(typography.Horizontal + typography.Horizontal)
 	var b strings.Builder
 	// Making of a buffer of [size] will be too small because ansi + unicode will take up more bytes than the
 	// character space they take up
 	b.Grow(size * 2)
-	fmt.Fprint(&b, ansi.Magenta(typography.Bullet)+" ")
+	before, after := opts.offScreen(span)
+	if before {
+		fmt.Fprint(&b, ansi.Magenta(typography.LeftTriangle)+" ")
+	} else {
+		fmt.Fprint(&b, ansi.Magenta(typography.Bullet)+" ")
+	}
 	remaining := size - 2
 	toPrint := max(remaining/spacePerItem, 1)
+	itemWidth, filler := spacePerItem, ""
+	if opts.maxXLabels > 0 && toPrint > opts.maxXLabels {
+		// Fewer labels than would fit, so they're spread evenly across the axis with more axis after each.
+		toPrint = opts.maxXLabels
+		itemWidth = remaining / toPrint
+		filler = ansi.White(strings.Repeat(typography.Horizontal, itemWidth-spacePerItem))
+	}
 	durationGap := span.Duration / time.Duration(toPrint)
+	labelColumns := make([]int, 0, toPrint)
 	// TODO don't repeat durations
 	for i := range toPrint {
+		if reserve > 0 && 2+(i+1)*itemWidth > size-reserve {
+			break
+		}
 		t := span.Begin.Add(durationGap * time.Duration(i))
-		timeStamp := t.Format(format)
-		if len(timeStamp) < formatLen {
-			if len(timeStamp) == 8 {
-				timeStamp += ".00"
-			} else if len(timeStamp) == 10 {
-				timeStamp += "0"
-			} else if len(timeStamp) == 9 {
-				timeStamp += "00"
-			}
+		if opts.reverseX {
+			t = span.End.Add(-durationGap * time.Duration(i))
+		}
+		timeStamp := xAxisLabel(t, span, format, opts.relativeX)
+		if pad := formatLen - utf8.RuneCountInString(timeStamp); pad > 0 {
+			timeStamp += strings.Repeat(" ", pad)
 		}
-		fmt.Fprint(&b, padding+" "+ansi.Yellow(timeStamp)+" "+padding)
-		remaining -= spacePerItem
+		// The bullet, the padding and a space come before the first label.
+		labelColumns = append(labelColumns, 6+i*itemWidth)
+		fmt.Fprint(&b, padding+" "+ansi.Yellow(timeStamp)+" "+padding+filler)
+		remaining -= itemWidth
 	}
-	if remaining > 1 {
+	remaining -= reserve
+	if remaining > 1 && after {
+		final := strings.Repeat(typography.Horizontal, remaining-2)
+		fmt.Fprint(&b, ansi.White(final)+ansi.Magenta(typography.RightTriangle))
+	} else if remaining > 1 {
 		// TODO also put some chars at the beginning of the axis
 		final := strings.Repeat(typography.Horizontal, remaining-1)
 		fmt.Fprint(&b, ansi.White(final))
 	}
 	return xAxis{
-		size:     size,
-		spanBase: span,
-		axis:     b.String(),
+		size:         size,
+		spanBase:     span,
+		axis:         b.String(),
+		labelColumns: labelColumns,
 	}
 }
 
+// offScreen is whether the capture (see [Graph.SetCaptureSpan]) carries on before the left and after the right
+// of the span drawn.
+func (opts drawOptions) offScreen(span *data.TimeSpan) (before, after bool) {
+	if opts.captureSpan == nil {
+		return false, false
+	}
+	earlier, later := opts.captureSpan.Begin.Before(span.Begin), opts.captureSpan.End.After(span.End)
+	if opts.reverseX {
+		return later, earlier
+	}
+	return earlier, later
+}
+
 type xAxis struct {
 	size     int
 	spanBase *data.TimeSpan
 	axis     string
+	// labelColumns are the columns where each time label begins.
+	labelColumns []int
 }
 
 // paint knows how to composite the parts of a frame and the spinner
 func paint(size terminal.Size, x, y, lines, spinner string) string {
-	ret := ansi.Clear
-	ret += lines + y
-	ret += ansi.CursorPosition(size.Height, 1)
-	ret += x
-	ret += spinner
-	return ret
+	var b strings.Builder
+	b.Grow(len(ansi.Clear) + len(lines) + len(y) + len(x) + len(spinner) + 16)
+	b.WriteString(ansi.Clear)
+	b.WriteString(lines)
+	b.WriteString(y)
+	b.WriteString(ansi.CursorPosition(size.Height, 1))
+	b.WriteString(x)
+	b.WriteString(spinner)
+	return b.String()
 }
@@ -0,0 +1,653 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package graph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/graph/terminal"
+	"github.com/Lexer747/AcciPing/graph/terminal/ansi"
+	"github.com/Lexer747/AcciPing/graph/terminal/th"
+	"github.com/Lexer747/AcciPing/gui"
+	"github.com/Lexer747/AcciPing/ping"
+	"github.com/Lexer747/AcciPing/utils/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitingForFirstPoint(t *testing.T) {
+	t.Parallel()
+	_, _, term, setTerm, err := th.NewTestTerminal()
+	require.NoError(t, err)
+	setTerm(terminal.Size{Height: 15, Width: 80})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	g, err := NewGraph(ctx, make(chan ping.PingResults), term, 0, "www.google.com")
+	require.NoError(t, err)
+
+	const timeBetweenFrames = 20 * time.Millisecond // spinner moves every 10 frames
+	require.Contains(t, g.computeFrame(timeBetweenFrames, true), "Waiting for first reply to www.google.com...")
+	drawn := 0
+	for range 30 {
+		if g.computeFrame(timeBetweenFrames, true) != "" {
+			drawn++
+		}
+	}
+	require.Equal(t, 3, drawn, "only the spinner changes should be drawn while waiting")
+
+	g.AddPoint(ping.PingResults{Data: ping.PingDataPoint{Duration: time.Second, Timestamp: time.Time{}.Add(time.Second)}})
+	require.NotContains(t, g.computeFrame(timeBetweenFrames, true), "Waiting", "the first point replaces the notice")
+}
+
+func TestIdleFramesOnlyDrawSpinnerChanges(t *testing.T) {
+	t.Parallel()
+	_, _, term, setTerm, err := th.NewTestTerminal()
+	require.NoError(t, err)
+	setTerm(terminal.Size{Height: 15, Width: 80})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	g, err := NewGraph(ctx, make(chan ping.PingResults), term, 0, "")
+	require.NoError(t, err)
+	g.AddPoint(ping.PingResults{Data: ping.PingDataPoint{Duration: time.Second, Timestamp: time.Time{}.Add(time.Second)}})
+	g.AddPoint(ping.PingResults{Data: ping.PingDataPoint{Duration: time.Second, Timestamp: time.Time{}.Add(2 * time.Second)}})
+
+	const timeBetweenFrames = 20 * time.Millisecond // spinner moves every 10 frames
+	require.NotEmpty(t, g.computeFrame(timeBetweenFrames, true), "first frame is drawn in full")
+	drawn := 0
+	for range 30 {
+		if g.computeFrame(timeBetweenFrames, true) != "" {
+			drawn++
+		}
+	}
+	require.Equal(t, 3, drawn, "only the spinner changes should be drawn")
+}
+
+func TestStaleNotice(t *testing.T) {
+	t.Parallel()
+	_, _, term, setTerm, err := th.NewTestTerminal()
+	require.NoError(t, err)
+	setTerm(terminal.Size{Height: 15, Width: 80})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	g, err := NewGraph(ctx, make(chan ping.PingResults), term, 6, "")
+	require.NoError(t, err)
+	start := time.Date(2024, 8, 2, 20, 0, 0, 0, time.UTC)
+	g.AddPoint(ping.PingResults{Data: ping.PingDataPoint{Duration: time.Second, Timestamp: start}})
+	g.AddPoint(ping.PingResults{Data: ping.PingDataPoint{Duration: time.Second, Timestamp: start.Add(10 * time.Second)}})
+	now := start.Add(20 * time.Second)
+	g.now = func() time.Time { return now }
+
+	require.NotContains(t, g.ComputeFrame(), "stale", "only shown when enabled")
+	g.SetDetectStale(true)
+	require.NotContains(t, g.ComputeFrame(), "stale", "one missing ping at 6 pings per minute isn't stale")
+	now = start.Add(70 * time.Second)
+	require.Contains(t, g.ComputeFrame(), "stale - no data for 1m0s", "five missing pings is stale")
+	now = start.Add(71 * time.Second)
+	require.Contains(t, g.ComputeFrame(), "stale - no data for 1m1s", "the notice is re-drawn as it ages")
+	require.Empty(t, g.ComputeFrame(), "nothing changed")
+
+	g.AddPoint(ping.PingResults{Data: ping.PingDataPoint{Duration: time.Second, Timestamp: start.Add(70 * time.Second)}})
+	require.NotContains(t, g.ComputeFrame(), "stale", "new data clears the notice")
+
+	d := data.NewData("")
+	d.AddPoint(ping.PingResults{Data: ping.PingDataPoint{Duration: time.Second, Timestamp: start}})
+	read, err := NewGraphWithData(ctx, make(chan ping.PingResults), term, 6, d)
+	require.NoError(t, err)
+	read.now = g.now
+	read.SetDetectStale(true)
+	require.NotContains(t, read.ComputeFrame(), "stale", "not before the first new point")
+	read.AddPoint(ping.PingResults{Data: ping.PingDataPoint{Duration: time.Second, Timestamp: start.Add(10 * time.Second)}})
+	require.Contains(t, read.ComputeFrame(), "stale")
+}
+
+func TestLogPing(t *testing.T) {
+	t.Parallel()
+	var b bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&b, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	timestamp := time.Date(2024, 8, 2, 20, 0, 0, 0, time.UTC)
+	logPing(context.Background(), logger, "www.google.com", ping.PingResults{
+		Data: ping.PingDataPoint{Duration: 12 * time.Millisecond, Timestamp: timestamp},
+		IP:   net.IPv4(142, 250, 200, 36),
+		Seq:  7, ReplySeq: 7,
+	})
+	logPing(context.Background(), logger, "www.google.com", ping.PingResults{
+		Data: ping.PingDataPoint{DropReason: ping.Timeout, Timestamp: timestamp},
+		IP:   net.IPv4(142, 250, 200, 36),
+		Seq:  8,
+	})
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	require.Len(t, lines, 2)
+	var good, dropped map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &good))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &dropped))
+	require.Equal(t, "DEBUG", good["level"])
+	require.Equal(t, "ping", good["msg"])
+	require.Equal(t, "142.250.200.36", good["ip"])
+	require.Equal(t, float64(7), good["seq"])
+	require.Equal(t, float64(12*time.Millisecond), good["latency"])
+	require.NotContains(t, good, "drop_reason")
+	require.Equal(t, "Timeout", dropped["drop_reason"])
+	require.NotContains(t, dropped, "latency")
+}
+
+func TestPromptForURL(t *testing.T) {
+	t.Parallel()
+	stdin, stdout, term, setTerm, err := th.NewTestTerminal()
+	require.NoError(t, err)
+	setTerm(terminal.Size{Height: 15, Width: 80})
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	g, err := NewGraph(ctx, make(chan ping.PingResults), term, 0, "www.google.com")
+	require.NoError(t, err)
+	switched := make(chan string, 2)
+	failed := false
+	g.SetSwitchURL(func(url string) error {
+		switched <- url
+		if !failed {
+			failed = true
+			return errors.New("no route")
+		}
+		return nil
+	})
+	_, err = term.StartRaw(ctx, cancel, g.listeners(ctx)...)
+	require.NoError(t, err)
+	_ = stdout.ReadString(t) // hide cursor
+
+	_, _ = stdin.Write([]byte("u"))
+	buffer := make([]byte, 4096)
+	for _, key := range []byte("1.1.1.1\r") {
+		_, err = stdout.Read(buffer) // wait for the prompt to be drawn
+		require.NoError(t, err)
+		_, _ = stdin.Write([]byte{key})
+	}
+	require.Equal(t, "1.1.1.1", <-switched)
+	// The failure is shown in the prompt, which starts with the URL again so it can be retried.
+	n, err := stdout.Read(buffer)
+	require.NoError(t, err)
+	require.Contains(t, string(buffer[:n]), "no route")
+	require.Contains(t, string(buffer[:n]), "1.1.1.1")
+	_, _ = stdin.Write([]byte("\r"))
+	require.Equal(t, "1.1.1.1", <-switched)
+	require.Eventually(t, func() bool { return !g.prompting.Load() }, time.Second, time.Millisecond)
+	g.dataMutex.Lock()
+	require.Equal(t, "1.1.1.1", g.url)
+	require.Equal(t, "1.1.1.1", g.data.URL)
+	require.Len(t, g.options.annotations, 1)
+	require.Equal(t, "1.1.1.1", g.options.annotations[0].Label)
+	g.dataMutex.Unlock()
+	g.Reset()
+	require.Equal(t, "1.1.1.1", g.data.URL, "a reset carries on with the new URL")
+}
+
+func TestPromptLayout(t *testing.T) {
+	t.Parallel()
+	_, _, term, setTerm, err := th.NewTestTerminal()
+	require.NoError(t, err)
+	size := terminal.Size{Height: 24, Width: 100}
+	setTerm(size)
+	g, err := NewGraph(context.Background(), make(chan ping.PingResults), term, 0, "www.google.com")
+	require.NoError(t, err)
+	g.AddPoint(ping.PingResults{Data: ping.PingDataPoint{Duration: time.Millisecond, Timestamp: time.Now()}, IP: []byte{}})
+	g.SetPanel(true)
+
+	panel := placePanel(frameLayout(size), size, len(g.readout(g.data, g.options)))
+	prompt, ok := g.promptLayout().Place(gui.Centre, 40, 3)
+	require.True(t, ok)
+	require.False(t, prompt.Overlaps(panel), "%+v covers the panel %+v", prompt, panel)
+	require.Greater(t, prompt.Row, 1, "below the title")
+}
+
+func TestPanelLines(t *testing.T) {
+	t.Parallel()
+	d := data.NewData("www.google.com")
+	origin := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, p := range []ping.PingDataPoint{
+		{Duration: 3 * time.Millisecond},
+		{DropReason: ping.TestDrop},
+		{Duration: time.Millisecond},
+		{DropReason: ping.OutOfOrder},
+	} {
+		p.Timestamp = origin.Add(time.Duration(i) * time.Minute)
+		d.AddPoint(ping.PingResults{Data: p, IP: net.IPv4allrouter})
+	}
+	var cache derived
+	latencies := cache.sortedLatencies(d)
+	require.Equal(t, data.Latencies{time.Millisecond, 3 * time.Millisecond}, latencies)
+	require.Equal(t, &latencies[0], &cache.sortedLatencies(d)[0], "sorted once until a point is added")
+	require.Same(t, cache.gapStats(d), cache.gapStats(d))
+	require.Equal(t, uint64(2), cache.gapStats(d).GoodCount, "the late reply isn't a gap")
+
+	lines := panelLines(d, latencies, data.DurationFormat{})
+	require.Contains(t, lines, [2]string{"last drop", origin.Add(time.Minute).Format(time.TimeOnly)},
+		"the late reply isn't another drop")
+
+	var b strings.Builder
+	box := gui.Box{Row: 1, Column: 1, Width: panelWidth, Height: 3}
+	drawPanel(&b, box, [][2]string{{"p99", strings.Repeat("9", box.Inner()-3-panelLabelWidth) + "µs"}})
+	require.Contains(t, b.String(), "9µ"+ansi.R, "cut by rune, not splitting the µ")
+}
+
+func TestSwitchRate(t *testing.T) {
+	t.Parallel()
+	stdin, stdout, term, setTerm, err := th.NewTestTerminal()
+	require.NoError(t, err)
+	setTerm(terminal.Size{Height: 15, Width: 80})
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	g, err := NewGraph(ctx, make(chan ping.PingResults), term, 60, "www.google.com")
+	require.NoError(t, err)
+	switched := make(chan float64, 1)
+	g.SetSwitchRate(func(pingsPerMinute float64) error {
+		switched <- pingsPerMinute
+		return nil
+	})
+	_, err = term.StartRaw(ctx, cancel, g.listeners(ctx)...)
+	require.NoError(t, err)
+	_ = stdout.ReadString(t) // hide cursor
+
+	g.dataMutex.Lock()
+	require.Equal(t, "www.google.com", g.title(), "unchanged rate isn't shown")
+	g.dataMutex.Unlock()
+	for _, tc := range []struct {
+		key      byte
+		expected float64
+	}{{key: ']', expected: 120}, {key: '[', expected: 60}, {key: '[', expected: 30}} {
+		expected := tc.expected
+		_, _ = stdin.Write([]byte{tc.key})
+		require.Equal(t, expected, <-switched, "%q", tc.key)
+		require.Eventually(t, func() bool {
+			g.dataMutex.Lock()
+			defer g.dataMutex.Unlock()
+			return g.pingsPerMinute == expected
+		}, time.Second, time.Millisecond)
+	}
+
+	_, _ = stdin.Write([]byte("2"))
+	buffer := make([]byte, 4096)
+	for _, key := range []byte("40\r") {
+		_, err = stdout.Read(buffer) // wait for the prompt to be drawn
+		require.NoError(t, err)
+		_, _ = stdin.Write([]byte{key})
+	}
+	require.Equal(t, float64(240), <-switched)
+	require.Eventually(t, func() bool { return !g.prompting.Load() }, time.Second, time.Millisecond)
+	g.dataMutex.Lock()
+	require.Equal(t, "www.google.com 240/min", g.title())
+	g.dataMutex.Unlock()
+
+	g.changeRate(func(float64) float64 { return 1_000_000 })
+	require.Equal(t, float64(ping.MaxPingsPerMinute), <-switched, "kept within the bounds")
+}
+
+func TestSwitchRate_fast(t *testing.T) {
+	t.Parallel()
+	stdin, stdout, term, setTerm, err := th.NewTestTerminal()
+	require.NoError(t, err)
+	setTerm(terminal.Size{Height: 15, Width: 80})
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	g, err := NewGraph(ctx, make(chan ping.PingResults), term, 20_000, "www.google.com")
+	require.NoError(t, err)
+	switched := make(chan float64, 1)
+	g.SetSwitchRate(func(pingsPerMinute float64) error {
+		switched <- pingsPerMinute
+		return nil
+	})
+	_, err = term.StartRaw(ctx, cancel, g.listeners(ctx)...)
+	require.NoError(t, err)
+	_ = stdout.ReadString(t) // hide cursor
+
+	for _, tc := range []struct {
+		key      byte
+		expected float64
+	}{
+		{key: ']', expected: 40_000},
+		{key: '[', expected: 20_000},
+		{key: '[', expected: 10_000},
+		{key: ']', expected: 20_000},
+		{key: ']', expected: 40_000},
+		{key: ']', expected: ping.MaxPingsPerMinute},
+	} {
+		expected := tc.expected
+		_, _ = stdin.Write([]byte{tc.key})
+		require.Equal(t, expected, <-switched, "%q", tc.key)
+		require.Eventually(t, func() bool {
+			g.dataMutex.Lock()
+			defer g.dataMutex.Unlock()
+			return g.pingsPerMinute == expected
+		}, time.Second, time.Millisecond)
+	}
+}
+
+func TestPlaceParallel(t *testing.T) {
+	t.Parallel()
+	d := data.NewData("www.google.com")
+	for i := range 10_000 {
+		p := ping.PingDataPoint{
+			Duration:  time.Duration(i%97) * time.Millisecond,
+			Timestamp: time.Time{}.Add(time.Duration(i) * time.Second),
+		}
+		if i%1000 > 900 {
+			// Long outages, so that the filler between dropped packets crosses the chunks.
+			p = ping.PingDataPoint{DropReason: ping.Timeout, Timestamp: p.Timestamp}
+		}
+		d.AddPoint(ping.PingResults{Data: p, IP: net.IPv4(8, 8, 8, 8)})
+	}
+	s := terminal.Size{Height: 30, Width: 120}
+	placer := pointPlacer{d: d, s: s, labelSize: 8, centreX: s.Width / 2}
+	var serial, parallel drawWindow
+	serial.reset(s)
+	placer.place(&serial, 0, d.TotalCount)
+	parallel.reset(s)
+	placer.placeParallel(&parallel)
+	require.Equal(t, serial.cells, parallel.cells)
+}
+
+func TestFrameIsDeterministic(t *testing.T) {
+	t.Parallel()
+	s := terminal.Size{Height: 15, Width: 80}
+	// Far more points than columns so that many points, markers and labels land on the same cells.
+	d := benchmarkData(5_000)
+	frame := func() string {
+		g := benchmarkGraph(t, s, d)
+		g.SetMarkLatest(true)
+		g.SetMarkLongestOutage(true)
+		g.SetAnnotations([]Annotation{
+			{Time: time.Time{}.Add(1000 * time.Second), Label: "overlapping"},
+			{Time: time.Time{}.Add(1010 * time.Second), Label: "labels"},
+		})
+		return g.ComputeFrame()
+	}
+	first := frame()
+	for range 10 {
+		require.Equal(t, first, frame(), "the same data must draw the same frame every time")
+	}
+}
+
+func TestDrawWindow(t *testing.T) {
+	t.Parallel()
+	var w drawWindow
+	w.reset(terminal.Size{Height: 2, Width: 4})
+	w.setText(2, 3, "xy", func(s string) string { return s })
+	w.set(1, 2, "a")
+	w.set(1, 1, "b")
+	w.set(1, 2, "c")
+	w.set(3, 1, "outside")
+	var b strings.Builder
+	w.draw(&b)
+	require.Equal(t, ansi.CursorPosition(1, 1)+"bc"+ansi.CursorPosition(2, 3)+"xy", b.String(),
+		"drawn in row then column order, the last write to a cell wins")
+}
+
+func TestShowDrops(t *testing.T) {
+	t.Parallel()
+	stdin, stdout, term, setTerm, err := th.NewTestTerminal()
+	require.NoError(t, err)
+	setTerm(terminal.Size{Height: 15, Width: 80})
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	g, err := NewGraph(ctx, make(chan ping.PingResults), term, 0, "www.google.com")
+	require.NoError(t, err)
+	start := time.Date(2024, 8, 2, 20, 0, 0, 0, time.UTC)
+	for i, reason := range []ping.Dropped{ping.NotDropped, ping.Timeout, ping.InternalError, ping.DNSFailure} {
+		g.AddPoint(ping.PingResults{
+			Data: ping.PingDataPoint{Duration: time.Millisecond, Timestamp: start.Add(time.Duration(i) * time.Second), DropReason: reason},
+			IP:   net.IPv4(8, 8, 8, 8),
+		})
+	}
+	_, err = term.StartRaw(ctx, cancel, g.listeners(ctx)...)
+	require.NoError(t, err)
+	_ = stdout.ReadString(t) // hide cursor
+
+	_, _ = stdin.Write([]byte("d"))
+	buffer := make([]byte, 4096)
+	n, err := stdout.Read(buffer)
+	require.NoError(t, err)
+	list := string(buffer[:n])
+	require.Contains(t, list, " Dropped packets 1-2 of 2 ")
+	require.Contains(t, list, "2024-08-02 20:00:01  Timeout           8.8.8.8")
+	require.Contains(t, list, "2024-08-02 20:00:03  DNS Query Failed  8.8.8.8")
+	require.True(t, g.prompting.Load(), "no frames drawn over the list")
+	_, _ = stdin.Write([]byte("\x1b"))
+	require.Eventually(t, func() bool { return !g.prompting.Load() }, time.Second, time.Millisecond)
+
+	require.Equal(t, []string{"No dropped packets"}, dropLines(data.NewData("www.google.com")))
+}
+
+func TestDrawWindowLabels(t *testing.T) {
+	t.Parallel()
+	var w drawWindow
+	w.reset(terminal.Size{Height: 7, Width: 10})
+	same := func(s string) string { return s }
+	w.set(2, 1, "o")
+	w.set(5, 1, "o")
+	w.set(5, 3, "x")
+	w.set(6, 2, "x")
+	w.addLabel(2, 1, 1, "first", "f", same)  // Covers only its own point
+	w.addLabel(2, 3, 3, "nudged", "n", same) // Even the marker touches, can't go up into the title so goes down
+	w.addLabel(2, 6, 6, "left", "l", same)   // Touches the first without a space, and the nudged label below
+	w.addLabel(3, 4, 4, "down", "d", same)   // Touches both, so is nudged down
+	w.addLabel(5, 1, 1, "hidden", "h", same) // Covers another point, so only the marker is drawn
+	var b strings.Builder
+	w.draw(&b)
+	require.Equal(t, ansi.CursorPosition(2, 1)+"first"+ansi.CursorPosition(3, 3)+"nudged"+ansi.CursorPosition(4, 4)+"down"+
+		ansi.CursorPosition(5, 1)+"h"+ansi.CursorPosition(5, 3)+"x"+ansi.CursorPosition(6, 2)+"x",
+		b.String())
+}
+
+func TestTTLLane(t *testing.T) {
+	t.Parallel()
+	d := data.NewData("www.google.com")
+	for i, ttl := range []uint8{57, 0, 57, 118} {
+		d.AddPoint(ping.PingResults{Data: ping.PingDataPoint{
+			Duration: time.Second, Timestamp: time.Time{}.Add(time.Duration(i) * time.Second), TTL: ttl,
+		}})
+	}
+	// The points are in columns 1, 3, 6 and 9.
+	s := terminal.Size{Height: minTTLLaneHeight, Width: 10}
+	var w drawWindow
+	w.reset(s)
+	placeTTLLane(&w, nil, d, s, 1, false)
+	old, changed := ttlBand(57), ttlBand(118)
+	require.NotEqual(t, old, changed)
+	require.Equal(t, []string{old, old, old, old, old, old, ttlChange, old, changed, ""}, w.lane,
+		"the point without a TTL is ignored, the change is highlighted next to the last point with the old TTL")
+
+	w.set(laneRow, 2, "x")
+	w.placeLane()
+	require.Equal(t, "x", w.cells[(laneRow-1)*s.Width+1], "never drawn over a point")
+
+	s.Height--
+	w.reset(s)
+	placeTTLLane(&w, nil, d, s, 1, false)
+	require.Equal(t, make([]string, s.Width), w.lane, "too short for the lane")
+}
+
+func TestInspectionCursor(t *testing.T) {
+	t.Parallel()
+	_, _, term, setTerm, err := th.NewTestTerminal()
+	require.NoError(t, err)
+	setTerm(terminal.Size{Height: 15, Width: 80})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	g, err := NewGraph(ctx, make(chan ping.PingResults), term, 0, "")
+	require.NoError(t, err)
+	for i := range 60 {
+		g.AddPoint(ping.PingResults{Data: ping.PingDataPoint{
+			Duration:  time.Duration(i+1) * time.Millisecond,
+			Timestamp: time.Time{}.Add(time.Duration(i+1) * time.Second),
+		}})
+	}
+	g.AddPoint(ping.PingResults{Data: ping.PingDataPoint{DropReason: ping.Timeout, Timestamp: time.Time{}.Add(61 * time.Second)}})
+
+	require.NotContains(t, g.ComputeFrame(), cursorLine, "hidden until an arrow key is pressed")
+	g.moveCursor(terminal.KeyLeft)
+	frame := g.ComputeFrame()
+	require.Contains(t, frame, cursorLine)
+	require.Contains(t, frame, " 00:01:01.00 UTC Timeout ", "starts on the newest point")
+	for range 5 {
+		g.moveCursor(terminal.KeyLeft)
+	}
+	require.Contains(t, g.ComputeFrame(), " 00:00:57.00 UTC 57ms ", "the columns are a little wider than a second")
+	for range 100 {
+		g.moveCursor(terminal.KeyLeft)
+	}
+	require.Contains(t, g.ComputeFrame(), " 00:00:01.00 UTC 1ms ", "stops at the oldest point")
+	g.moveCursor(escape)
+	require.NotContains(t, g.ComputeFrame(), cursorLine)
+}
+
+func TestCursorReadout_width(t *testing.T) {
+	t.Parallel()
+	d := data.NewData("")
+	stamp := time.Date(2024, time.July, 1, 12, 0, 0, 0, time.UTC)
+	d.AddPoint(ping.PingResults{Data: ping.PingDataPoint{Duration: 1500 * time.Nanosecond, Timestamp: stamp}})
+	var b strings.Builder
+	const column = 70
+	drawCursorReadout(&b, d, terminal.Size{Height: 15, Width: 80}, 5, column, drawOptions{readoutZone: UTCZone})
+	readout := " " + UTCZone.format(stamp, defaultTimeFormat, nil) + " 1.5µs "
+	require.Equal(t, ansi.CursorPosition(2, column-len([]rune(readout)))+ansi.Cyan(readout), b.String(),
+		"ends next to the cursor, the µ is one column")
+}
+
+func TestMakeTitle_fits(t *testing.T) {
+	t.Parallel()
+	stats := &data.Stats{}
+	stats.AddPoint(3 * time.Second)
+	stats.AddPoint(5 * time.Second)
+	colours := regexp.MustCompile(`\x1b\[[\d;]*m`)
+	for _, c := range []struct {
+		width    int
+		url      string
+		expected string
+	}{
+		{80, "www.google.com", "www.google.com [μ 4s | σ 1.414213562s | Packet Count 2] W: 80 H: 5"},
+		{40, "www.google.com", "W: 40 H: 5"},
+		{30, "a-very-long-host-name.example.com", "a-very-long-"},
+		{10, "www.google.com", "Latency W:"},
+	} {
+		size := terminal.Size{Height: 5, Width: c.width}
+		// Drawn onto a wider grid so that anything past the width is kept.
+		drawn := frameBlock(makeTitle(size, stats, nil, c.url, data.DurationFormat{}), terminal.Size{Height: 1, Width: 200})
+		title := strings.TrimSuffix(colours.ReplaceAllString(drawn, ""), "\n")
+		require.LessOrEqual(t, utf8.RuneCountInString(title), c.width, "%q", title)
+		require.Contains(t, title, c.expected)
+	}
+}
+
+func TestRelativeLabelWidth(t *testing.T) {
+	t.Parallel()
+	span := &data.TimeSpan{Begin: time.Date(2024, time.July, 1, 12, 0, 0, 0, time.UTC)}
+	require.Equal(t, "+2m3s", xAxisLabel(span.Begin.Add(123*time.Second), span, "", true), "rounded exactly")
+	for elapsed := time.Duration(0); elapsed < 10*time.Hour; elapsed += 7*time.Second + 130*time.Millisecond {
+		label := xAxisLabel(span.Begin.Add(elapsed), span, "", true)
+		require.LessOrEqual(t, utf8.RuneCountInString(label), relativeLabelWidth, "%s", label)
+	}
+}
+
+func TestReadoutZone(t *testing.T) {
+	t.Parallel()
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	// Drawn in Tokyo, as if that had been picked as the zone of the x-axis.
+	stamp := time.Date(2024, time.July, 1, 12, 0, 0, 0, time.UTC).In(tokyo)
+	const layout = "15:04"
+	local := stamp.Local().Format("15:04 MST") + " (local)"
+	zone := AxisZone
+	for _, expected := range []string{"21:00 JST", local, "12:00 UTC", "13:00 BST (file)"} {
+		require.Equal(t, expected, zone.format(stamp, layout, loadFileZone("Europe/London")), "%d", zone)
+		zone = zone.next()
+	}
+	require.Equal(t, AxisZone, zone, "cycles back round")
+	require.Equal(t, local, FileZone.format(stamp, layout, loadFileZone("")), "unknown file zone")
+	require.Equal(t, local, FileZone.format(stamp, layout, loadFileZone("Nowhere/Special")))
+
+	z, err := ParseReadoutZone("file")
+	require.NoError(t, err)
+	require.Equal(t, FileZone, z)
+	_, err = ParseReadoutZone("mars")
+	require.Error(t, err)
+}
+
+func TestOffScreen(t *testing.T) {
+	t.Parallel()
+	span := &data.TimeSpan{Begin: time.Time{}.Add(time.Minute), End: time.Time{}.Add(2 * time.Minute)}
+	opts := drawOptions{}
+	before, after := opts.offScreen(span)
+	require.False(t, before || after, "the whole capture is drawn")
+	opts.captureSpan = &data.TimeSpan{Begin: time.Time{}, End: span.End}
+	before, after = opts.offScreen(span)
+	require.True(t, before)
+	require.False(t, after)
+	opts.reverseX = true
+	before, after = opts.offScreen(span)
+	require.False(t, before)
+	require.True(t, after, "the older points are on the right when reversed")
+}
+
+func TestNearestPoint(t *testing.T) {
+	t.Parallel()
+	d := benchmarkData(1_000)
+	s := terminal.Size{Height: 15, Width: 80}
+	const labelSize = 8
+	for _, reverse := range []bool{false, true} {
+		for i := range d.TotalCount {
+			x := getX(d.Get(i).Timestamp, d.Header, s, labelSize, reverse)
+			nearest := nearestPoint(d, columnTime(x, d.Header, s, labelSize, reverse))
+			require.Equal(t, x, getX(d.Get(nearest).Timestamp, d.Header, s, labelSize, reverse),
+				"the nearest point to a column is in that column")
+		}
+	}
+	require.Equal(t, int64(0), nearestPoint(d, time.Time{}.Add(-time.Hour)))
+	require.Equal(t, d.TotalCount-1, nearestPoint(d, time.Time{}.Add(time.Hour)))
+}
+
+func TestHeatScale(t *testing.T) {
+	t.Parallel()
+	heat := newHeatScale(&data.Stats{Min: 10 * time.Millisecond, Max: 20 * time.Millisecond})
+	for _, test := range []struct {
+		latency time.Duration
+		r, g    uint8
+	}{
+		{latency: 10 * time.Millisecond, r: 0, g: 255},
+		{latency: 15 * time.Millisecond, r: 255, g: 255},
+		{latency: 20 * time.Millisecond, r: 255, g: 0},
+		{latency: time.Millisecond, r: 0, g: 255},
+		{latency: time.Second, r: 255, g: 0},
+	} {
+		r, g := heat.rgb(test.latency)
+		require.Equal(t, [2]uint8{test.r, test.g}, [2]uint8{r, g}, test.latency.String())
+	}
+	require.Equal(t, ansi.RGB("x", 255, 0, 0), heat.colour(20*time.Millisecond, "x"))
+	flat := newHeatScale(&data.Stats{Min: time.Millisecond, Max: time.Millisecond})
+	r, g := flat.rgb(time.Millisecond)
+	require.Equal(t, [2]uint8{0, 255}, [2]uint8{r, g}, "every point is the fastest")
+}
+
+func TestDroppedPalette(t *testing.T) {
+	t.Parallel()
+	p := DefaultGlyphs().palette()
+	require.Equal(t, p.drop, p.dropped(ping.Timeout))
+	require.Equal(t, p.drop, p.dropped(ping.TestDrop))
+	require.Equal(t, p.dnsDrop, p.dropped(ping.DNSFailure))
+	require.NotEqual(t, p.drop, p.dnsDrop, "DNS failures are drawn apart from the rest")
+
+	d := data.NewData("")
+	d.AddPoint(ping.PingResults{Data: ping.PingDataPoint{DropReason: ping.Timeout}, IP: net.IPv4zero})
+	require.False(t, hasDNSFailure(d))
+	d.AddPoint(ping.PingResults{Data: ping.PingDataPoint{DropReason: ping.DNSFailure}, IP: net.IPv4zero})
+	require.True(t, hasDNSFailure(d))
+}
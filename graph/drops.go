@@ -0,0 +1,41 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package graph
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/gui"
+)
+
+// showDrops lists every dropped packet over the graph until it's closed, it's run off the terminal's input
+// go-routine like [Graph.promptForURL].
+func (g *Graph) showDrops(ctx context.Context) {
+	defer g.overlay()()
+	g.dataMutex.Lock()
+	lines := dropLines(g.data)
+	g.dataMutex.Unlock()
+	_ = gui.ListBox{Title: "Dropped packets", Lines: lines, Layout: g.promptLayout(), ASCII: g.promptASCII()}.Run(ctx, g.Term)
+}
+
+// dropLines is a line for each dropped packet with when it was sent, why it was dropped and the IP it was sent
+// to, oldest first.
+func dropLines(d *data.Data) []string {
+	ret := []string{}
+	for i := range d.TotalCount {
+		if p := d.GetFull(i); p.Data.Dropped() {
+			ret = append(ret, fmt.Sprintf("%s  %-16s  %s", p.Data.Timestamp.Format(time.DateTime), p.Data.DropReason.String(), data.FormatIP(p.IP)))
+		}
+	}
+	if len(ret) == 0 {
+		ret = append(ret, "No dropped packets")
+	}
+	return ret
+}
@@ -0,0 +1,44 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package graph
+
+import (
+	"fmt"
+
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/graph/terminal/ansi"
+)
+
+// The packet loss (as a percentage) at which the loss gauge turns amber then red, see [Graph.SetLossGauge].
+const (
+	lossAmber = 1.0
+	lossRed   = 5.0
+)
+
+const (
+	// lossGaugeWidth is the space the gauge takes at the end of the x-axis, it's always the same width so the
+	// axis doesn't move as the loss changes.
+	lossGaugeWidth = len(" loss 100.00% ")
+	// minLossGaugeWidth is the narrowest graph the gauge is drawn on, leaving room for at least one time label.
+	minLossGaugeWidth = 40
+)
+
+// lossGauge is the packet loss so far, coloured green, amber or red by how bad it is.
+func lossGauge(stats *data.Stats) string {
+	loss := 0.0
+	if stats.GoodCount+stats.PacketsDropped > 0 {
+		loss = stats.PacketLoss() * 100
+	}
+	colour := ansi.Green
+	switch {
+	case loss >= lossRed:
+		colour = ansi.Red
+	case loss >= lossAmber:
+		colour = ansi.Yellow
+	}
+	return " " + colour(fmt.Sprintf("loss %6.2f%%", loss)) + " "
+}
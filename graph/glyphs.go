@@ -0,0 +1,159 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package graph
+
+import (
+	"bufio"
+	"cmp"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Lexer747/AcciPing/graph/terminal/ansi"
+	"github.com/Lexer747/AcciPing/graph/terminal/typography"
+	"github.com/Lexer747/AcciPing/ping"
+	"github.com/Lexer747/AcciPing/utils/errors"
+)
+
+// Glyphs are the characters the points and markers are drawn with, see [Graph.SetGlyphs]. Each is a single
+// character, an empty field is drawn with the default (see [DefaultGlyphs]).
+type Glyphs struct {
+	// Point is a good ping, it's also used for the warmup pings and internal errors in a different colour.
+	Point string
+	// OutOfOrder marks a reply which arrived after its request timed out.
+	OutOfOrder string
+	// Dropped fills the column of a dropped packet.
+	Dropped string
+	// Min, Max and Latest are the markers of those points.
+	Min, Max, Latest string
+}
+
+// DefaultGlyphs are the glyphs used unless others are picked.
+func DefaultGlyphs() Glyphs {
+	return Glyphs{
+		Point:      typography.Multiply,
+		OutOfOrder: typography.Interrobang,
+		Dropped:    typography.Block,
+		Min:        typography.UpTriangle,
+		Max:        typography.DownTriangle,
+		Latest:     typography.Diamond,
+	}
+}
+
+// ParseGlyphs overrides the glyphs with those in the spec, a comma separated list of name=glyph pairs e.g.
+// "point=●,latest=◉". The names are point, out-of-order, dropped, min, max and latest.
+func ParseGlyphs(spec string, g Glyphs) (Glyphs, error) {
+	if spec == "" {
+		return g, nil
+	}
+	for _, pair := range strings.Split(spec, ",") {
+		if err := g.set(pair); err != nil {
+			return g, err
+		}
+	}
+	return g, nil
+}
+
+// ReadGlyphs reads the glyphs from a config with a name=glyph pair on each line (see [ParseGlyphs] for the
+// names), any glyph not in the config is the default. Blank lines and lines beginning with '#' are ignored.
+func ReadGlyphs(r io.Reader) (Glyphs, error) {
+	g := DefaultGlyphs()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := g.set(line); err != nil {
+			return g, err
+		}
+	}
+	return g, errors.Wrap(scanner.Err(), "couldn't read glyphs")
+}
+
+// ReadGlyphsFile is [ReadGlyphs] for the file with the given name.
+func ReadGlyphsFile(name string) (Glyphs, error) {
+	f, err := os.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return Glyphs{}, errors.Wrap(err, "couldn't open glyphs")
+	}
+	defer f.Close()
+	return ReadGlyphs(f)
+}
+
+func (g *Glyphs) set(pair string) error {
+	name, glyph, ok := strings.Cut(pair, "=")
+	name, glyph = strings.TrimSpace(name), strings.TrimSpace(glyph)
+	if !ok || utf8.RuneCountInString(glyph) != 1 {
+		return errors.Errorf("Invalid glyph %q, expected name=glyph where the glyph is a single character", pair)
+	}
+	switch name {
+	case "point":
+		g.Point = glyph
+	case "out-of-order":
+		g.OutOfOrder = glyph
+	case "dropped":
+		g.Dropped = glyph
+	case "min":
+		g.Min = glyph
+	case "max":
+		g.Max = glyph
+	case "latest":
+		g.Latest = glyph
+	default:
+		return errors.Errorf("Unknown glyph %q, expected one of: point|out-of-order|dropped|min|max|latest", name)
+	}
+	return nil
+}
+
+// palette is the glyphs coloured for drawing, it's made once per frame rather than colouring each point.
+type palette struct {
+	plain, warmup, internalError, outOfOrder, drop, latest string
+	// dnsDrop is the column of a packet dropped because the URL couldn't be resolved, rather than lost on the
+	// network, see [palette.dropped].
+	dnsDrop string
+	// min and max are the uncoloured markers, they're coloured as part of their label.
+	min, max string
+	// markerKey explains the markers, it's drawn in the bottom right of the graph along with the latest marker
+	// when there are no points there.
+	markerKey string
+}
+
+func (g Glyphs) palette() palette {
+	defaults := DefaultGlyphs()
+	g.Point = cmp.Or(g.Point, defaults.Point)
+	g.OutOfOrder = cmp.Or(g.OutOfOrder, defaults.OutOfOrder)
+	g.Dropped = cmp.Or(g.Dropped, defaults.Dropped)
+	g.Min = cmp.Or(g.Min, defaults.Min)
+	g.Max = cmp.Or(g.Max, defaults.Max)
+	g.Latest = cmp.Or(g.Latest, defaults.Latest)
+	latest := ansi.Cyan(g.Latest)
+	return palette{
+		plain:         ansi.White(g.Point),
+		warmup:        ansi.Gray(g.Point),
+		internalError: ansi.Yellow(g.Point),
+		outOfOrder:    ansi.Magenta(g.OutOfOrder),
+		drop:          ansi.Red(g.Dropped),
+		dnsDrop:       ansi.Blue(g.Dropped),
+		latest:        latest,
+		min:           g.Min,
+		max:           g.Max,
+		markerKey: ansi.Green(g.Min) + ansi.Gray(" min ") +
+			ansi.Red(g.Max) + ansi.Gray(" max ") +
+			latest + ansi.Gray(" latest"),
+	}
+}
+
+// dropped is the glyph filling the column of a dropped packet, DNS failures are drawn apart from the rest since
+// the fix is different.
+func (p palette) dropped(reason ping.Dropped) string {
+	if reason == ping.DNSFailure {
+		return p.dnsDrop
+	}
+	return p.drop
+}
@@ -8,12 +8,18 @@ package graph
 
 import (
 	"context"
+	"io"
+	"log/slog"
 	"os"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Lexer747/AcciPing/graph/data"
 	"github.com/Lexer747/AcciPing/graph/terminal"
+	"github.com/Lexer747/AcciPing/graph/terminal/ansi"
+	"github.com/Lexer747/AcciPing/gui"
 	"github.com/Lexer747/AcciPing/ping"
 	"github.com/Lexer747/AcciPing/utils/errors"
 )
@@ -30,6 +36,40 @@ type Graph struct {
 	data      *data.Data
 	dataMutex *sync.Mutex
 	lastFrame frame
+	// window is re-used by every frame, guarded by the dataMutex.
+	window drawWindow
+	// derived is kept from one frame to the next until more points arrive, guarded by the dataMutex.
+	derived derived
+	// subscribers are sent every point added to the graph, see [Graph.Subscribe].
+	subscribers subscribers
+	// options are guarded by the dataMutex since they may be changed by a user while drawing.
+	options drawOptions
+
+	// synchronizedOutput wraps every frame written by [Graph.Run] in [ansi.BeginSync] and [ansi.EndSync].
+	synchronizedOutput bool
+	// clearScreen is false when [Graph.OneFrame] returns a block of lines, see [Graph.SetClearScreen].
+	clearScreen bool
+	// onFrame is called with everything [Graph.Run] draws, see [Graph.SetOnFrame].
+	onFrame func(drawn string)
+	// logPings emits a debug log for every point received by the sink, see [Graph.SetLogPings].
+	logPings atomic.Bool
+	// now is the clock used to decide if the data is stale, see [Graph.SetDetectStale].
+	now func() time.Time
+	// arrived is set once a point has been added to the graph rather than read with the data, only then can the
+	// data go stale. Guarded by the dataMutex.
+	arrived bool
+	// switchURL changes the URL being pinged, see [Graph.SetSwitchURL].
+	switchURL func(url string) error
+	// switchRate changes the pings per minute, see [Graph.SetSwitchRate]. rateChanged is set once it has.
+	switchRate  func(pingsPerMinute float64) error
+	rateChanged bool
+	// rateMutex is held while the rate is being changed, see [Graph.changeRate].
+	rateMutex sync.Mutex
+	// plotColumns are the first and last columns points are drawn in, updated with each frame. They bound the
+	// inspection cursor, see [Graph.moveCursor].
+	plotColumns [2]int
+	// prompting is set while a prompt or list is drawn over the graph and has the keyboard, no frames are drawn.
+	prompting atomic.Bool
 }
 
 func NewGraph(ctx context.Context, input chan ping.PingResults, t *terminal.Terminal, pingsPerMinute float64, URL string) (*Graph, error) {
@@ -51,7 +91,13 @@ func NewGraphWithData(
 		url:            data.URL,
 		pingsPerMinute: pingsPerMinute,
 		sinkAlive:      true,
+
+		synchronizedOutput: true,
+		clearScreen:        true,
+		now:                time.Now,
 	}
+	// The location is kept when the graph is reset, see [Graph.Reset].
+	g.options.fileZone = loadFileZone(data.Location)
 	go g.sink(ctx)
 	return g, nil
 }
@@ -59,7 +105,7 @@ func NewGraphWithData(
 func (g *Graph) Run(ctx context.Context, stop context.CancelCauseFunc, fps int) error {
 	timeBetweenFrames := getTimeBetweenFrames(fps, g.pingsPerMinute)
 	frameRate := time.NewTicker(timeBetweenFrames)
-	cleanup, err := g.Term.StartRaw(ctx, stop) // TODO add UI listeners, zooming, changing ping speed - etc
+	cleanup, err := g.Term.StartRaw(ctx, stop, g.listeners(ctx)...) // TODO add UI listeners, zooming - etc
 	defer cleanup()
 	if err != nil {
 		return err
@@ -71,7 +117,13 @@ func (g *Graph) Run(ctx context.Context, stop context.CancelCauseFunc, fps int)
 		toWrite := g.computeFrame(timeBetweenFrames, true)
 		// Currently no strong opinions on dropped frames this is fine
 		<-frameRate.C
-		g.Term.Print(toWrite)
+		if !g.prompting.Load() {
+			// Otherwise the frame would be drawn over the prompt, once it's finished the frame is re-drawn.
+			g.Term.Print(g.synchronize(toWrite))
+			if g.onFrame != nil && toWrite != "" {
+				g.onFrame(toWrite)
+			}
+		}
 		select {
 		case <-ctx.Done():
 			return context.Cause(ctx)
@@ -80,10 +132,510 @@ func (g *Graph) Run(ctx context.Context, stop context.CancelCauseFunc, fps int)
 	}
 }
 
-func (g *Graph) AddPoint(p ping.PingResults) {
+// SetSynchronizedOutput controls whether each frame drawn by [Graph.Run] is wrapped in the synchronized
+// output sequences ([ansi.BeginSync] and [ansi.EndSync]) which prevents tearing on terminals which support
+// it. Enabled by default, it should be disabled for terminals which echo unknown sequences.
+func (g *Graph) SetSynchronizedOutput(enabled bool) {
+	g.synchronizedOutput = enabled
+}
+
+// SetOnFrame sets a function which [Graph.Run] calls after drawing, with what was drawn to the terminal. For
+// automation, e.g. asserting on or recording what's drawn without reading the terminal. What's drawn is often
+// only the part of the frame which changed (the spinner), [Graph.LastFrame] is always the whole frame. The
+// function is called on the drawing go-routine so should be quick, it must be set before calling Run.
+func (g *Graph) SetOnFrame(onFrame func(drawn string)) {
+	g.onFrame = onFrame
+}
+
+// SetLogPings controls whether every point the graph receives is logged with [slog] at the debug level, with
+// the latency, drop reason, IP and sequence numbers as attributes.
+func (g *Graph) SetLogPings(enabled bool) {
+	g.logPings.Store(enabled)
+}
+
+// SetForceGradients controls whether the interpolated lines between points are always drawn, by default they
+// are only drawn when the first points are far enough apart to be drawn distinctly. Forcing them is useful
+// for reading the trend of sparse or bursty captures. Toggled live with the 'g' key.
+func (g *Graph) SetForceGradients(force bool) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.forceGradients = force
+	g.invalidateFrame()
+}
+
+// SetASCII controls whether frames are drawn using only ASCII characters instead of the unicode glyphs, for
+// terminals or fonts which can't draw them. See [typography.ASCII].
+func (g *Graph) SetASCII(enabled bool) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.ascii = enabled
+	g.invalidateFrame()
+}
+
+// promptASCII is true when the prompts drawn over the graph should be ASCII like the graph, see
+// [Graph.SetASCII].
+func (g *Graph) promptASCII() bool {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	return g.options.ascii
+}
+
+// SetTimeFormat sets the layout (see [time.Layout]) used for the labels on the x-axis, by default
+// "15:04:05.00". For example "03:04:05PM" for a 12 hour clock or "02/01 15:04" to include the date with the
+// day first. Every label is padded to the widest the layout can be so the axis always fits the terminal.
+func (g *Graph) SetTimeFormat(layout string) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.timeFormat = layout
+	g.invalidateFrame()
+}
+
+// SetMaxXLabels caps the number of time labels on the x-axis, on a wide terminal the labels are then spread
+// evenly across the axis rather than one every few columns. Zero, the default, draws as many as fit.
+func (g *Graph) SetMaxXLabels(n int) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.maxXLabels = n
+	g.invalidateFrame()
+}
+
+// SetCaptureSpan tells the graph that the data it draws is only part of a capture which spans longer, an arrow
+// at either end of the x-axis shows the side the capture carries on past what's drawn. Nil, the default, is
+// when the whole capture is drawn.
+func (g *Graph) SetCaptureSpan(span *data.TimeSpan) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.captureSpan = span
+	g.invalidateFrame()
+}
+
+// SetReverseX controls whether time runs right to left, with the newest points drawn at the left edge of the
+// graph and older points moving rightwards. By default the newest points are on the right.
+func (g *Graph) SetReverseX(reverse bool) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.reverseX = reverse
+	g.invalidateFrame()
+}
+
+// SetDurationFormat controls the units and precision of the durations in the statistics drawn in the title.
+func (g *Graph) SetDurationFormat(f data.DurationFormat) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.durationFormat = f
+	g.invalidateFrame()
+}
+
+// SetRelativeX controls whether the x-axis is labelled with the time elapsed since the first point (e.g.
+// "+30s") instead of the time of day. Toggled live with the 'r' key.
+func (g *Graph) SetRelativeX(relative bool) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.relativeX = relative
+	g.invalidateFrame()
+}
+
+// SetMarkLongestOutage controls whether the longest streak of dropped packets is underlined along the bottom
+// of the graph. Toggled live with the 'o' key.
+func (g *Graph) SetMarkLongestOutage(mark bool) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.markLongestOutage = mark
+	g.invalidateFrame()
+}
+
+// SetWarmup excludes the first n good packets after each DNS resolution from the statistics in the title
+// and the range of the y-axis, since these are often anomalously slow. The packets are still drawn, greyed out.
+// Zero (the default) includes every packet. See [data.Data.Warmup] for how a resolution is found in the points.
+func (g *Graph) SetWarmup(n int) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.warmup = n
+	g.invalidateFrame()
+}
+
+// SetMarkLatest controls whether the most recent good point is highlighted, along with a key explaining each
+// of the markers drawn in the bottom right of the graph.
+func (g *Graph) SetMarkLatest(mark bool) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.markLatest = mark
+	g.invalidateFrame()
+}
+
+// SetLossGauge controls whether the packet loss so far is drawn at the right end of the x-axis, coloured green,
+// amber from 1% and red from 5%. It's only drawn when the graph is wide enough.
+func (g *Graph) SetLossGauge(gauge bool) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.lossGauge = gauge
+	g.invalidateFrame()
+}
+
+// SetWindow compares the mean latency of the most recent points with the lifetime mean in the title, so that
+// recent degradation isn't hidden by a long capture. The zero window shows only the lifetime stats.
+func (g *Graph) SetWindow(window data.Window) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.window = window
+	g.invalidateFrame()
+}
+
+// SetGlyphs controls the characters the points and markers are drawn with, see [DefaultGlyphs].
+func (g *Graph) SetGlyphs(glyphs Glyphs) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.glyphSet = glyphs
+	g.invalidateFrame()
+}
+
+// SetReadoutZone controls which time zone the inspection cursor's readout is in, it can also be cycled while
+// running with 'z'.
+func (g *Graph) SetReadoutZone(zone ReadoutZone) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.readoutZone = zone
+	g.invalidateFrame()
+}
+
+// SetTrueColour controls whether the lines drawn between points (see [Graph.SetForceGradients]) are coloured by
+// their latency, on a scale from green at the fastest point through yellow to red at the slowest, instead of
+// gray. This uses 24-bit colour which not every terminal supports, so it's off by default.
+func (g *Graph) SetTrueColour(enabled bool) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.trueColour = enabled
+	g.invalidateFrame()
+}
+
+// SetShowMarkers controls whether the min, max and latest points are marked, when disabled every point is
+// drawn the same. Enabled by default, toggled live with the 'm' key.
+func (g *Graph) SetShowMarkers(show bool) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.hideMarkers = !show
+	g.invalidateFrame()
+}
+
+// SetRenderer controls how the points are drawn, by default each point is a single glyph joined by gradients.
+// The renderer is ignored when drawing in ASCII (see [Graph.SetASCII]).
+func (g *Graph) SetRenderer(r Renderer) {
 	g.dataMutex.Lock()
 	defer g.dataMutex.Unlock()
+	g.options.renderer = r
+	g.invalidateFrame()
+}
+
+// SetGrid controls whether faint grid lines are drawn behind the data, across from each y-axis label and up
+// from each x-axis label, making it easier to line points up with the axes. Toggled live with the '#' key.
+func (g *Graph) SetGrid(grid bool) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.grid = grid
+	g.invalidateFrame()
+}
+
+// SetDetectStale controls whether a notice is shown along the top of the graph when no data has arrived for
+// the time of a few pings, as the live graph or a followed file is expected to keep updating. The time between
+// pings is the pings per minute of the graph or if that's zero the average between the points so far. Data read
+// from a file is never stale until the first new point arrives.
+func (g *Graph) SetDetectStale(detect bool) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.detectStale = detect
+	g.invalidateFrame()
+}
+
+// SetAnnotations sets the moments of interest (e.g. deploys) to mark on the graph, each is drawn as a dashed
+// vertical line with the start of its label along the top. Annotations outside the span of the data aren't
+// drawn. See [ReadAnnotations].
+func (g *Graph) SetAnnotations(annotations []Annotation) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.annotations = annotations
+	g.invalidateFrame()
+}
+
+// SetSwitchURL enables the 'u' key, which prompts for a new URL and then calls the function to start pinging
+// it. The graph carries on with the same data, the switch is marked with an annotation and the new URL is shown
+// in the title and stored with the data. If the function fails the user is asked again, with the reason.
+func (g *Graph) SetSwitchURL(switchURL func(url string) error) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.switchURL = switchURL
+}
+
+// overlay stops frames being drawn while something else is drawn over the graph, the returned function
+// re-draws the graph.
+func (g *Graph) overlay() (release func()) {
+	g.prompting.Store(true)
+	return func() {
+		g.dataMutex.Lock()
+		defer g.dataMutex.Unlock()
+		g.prompting.Store(false)
+		// The overlay was drawn over the graph
+		g.invalidateFrame()
+	}
+}
+
+// promptForURL is run off the terminal's input go-routine, so that the prompt can hear what's typed.
+func (g *Graph) promptForURL(ctx context.Context) {
+	defer g.overlay()()
+	prompt := gui.InputBox{Prompt: "Switch to URL (escape to cancel)", Layout: g.promptLayout(), ASCII: g.promptASCII()}
+	for {
+		url, err := prompt.Run(ctx, g.Term)
+		if err != nil || url == "" {
+			return
+		}
+		g.dataMutex.Lock()
+		switchURL, current := g.switchURL, g.url
+		g.dataMutex.Unlock()
+		if url == current {
+			return
+		}
+		if err = switchURL(url); err != nil {
+			// Anything logged to the terminal would be drawn over the graph, so the failure is the next prompt.
+			prompt = gui.InputBox{
+				Prompt:  "Couldn't switch, " + err.Error() + " (escape to cancel)",
+				Initial: url,
+				Layout:  g.promptLayout(),
+				ASCII:   g.promptASCII(),
+			}
+			continue
+		}
+		g.dataMutex.Lock()
+		defer g.dataMutex.Unlock()
+		g.url = url
+		g.data.URL = url
+		g.options.annotations = append(slices.Clip(g.options.annotations), Annotation{Time: g.now(), Label: url})
+		return
+	}
+}
+
+// SetYLabels controls where the y-axis is labelled, by default evenly between the min and max. With
+// [PercentileYLabels] the labels are at the p50, p90 and p99 of the latency instead, each with a line across
+// the graph.
+func (g *Graph) SetYLabels(labels YLabels) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.yLabels = labels
+	g.invalidateFrame()
+}
+
+// SetLabels controls how much is drawn to mark the min and max points, by default both a marker and the
+// latency are drawn. On a small terminal the latency can cover a lot of the data. Cycled live with the 'l' key.
+func (g *Graph) SetLabels(labels Labels) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.labels = labels
+	g.invalidateFrame()
+}
+
+// SetPanel controls whether a panel of live numbers (the latest latency, stats, packet loss and time since the
+// last dropped packet) is drawn to the right of the graph, the graph is narrowed to make room. The panel is only
+// drawn when the terminal is wide enough. Toggled live with the 'p' key.
+func (g *Graph) SetPanel(panel bool) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.panel = panel
+	g.invalidateFrame()
+}
+
+// SetGapStats adds the mean and standard deviation of the time between pings to the panel (see [Graph.SetPanel]),
+// next to the interval they're configured to be sent at. A difference between the two shows the pings aren't
+// being sent when they should be, e.g. the scheduler is jittery or the pings are being throttled.
+func (g *Graph) SetGapStats(gapStats bool) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.options.gapStats = gapStats
+	g.invalidateFrame()
+}
+
+func (g *Graph) listeners(ctx context.Context) []terminal.Listener {
+	return []terminal.Listener{
+		{
+			Name: "switch URL",
+			Applicable: func(r rune) bool {
+				if r != 'u' || g.prompting.Load() {
+					return false
+				}
+				g.dataMutex.Lock()
+				defer g.dataMutex.Unlock()
+				return g.switchURL != nil
+			},
+			Action: func(rune) error {
+				go g.promptForURL(ctx)
+				return nil
+			},
+		},
+		{
+			Name: "inspection cursor",
+			Applicable: func(r rune) bool {
+				if r != escape {
+					return r == terminal.KeyLeft || r == terminal.KeyRight
+				}
+				g.dataMutex.Lock()
+				defer g.dataMutex.Unlock()
+				return g.options.cursor != 0
+			},
+			Action: func(r rune) error {
+				g.dataMutex.Lock()
+				defer g.dataMutex.Unlock()
+				g.moveCursor(r)
+				return nil
+			},
+		},
+		{
+			Name: "halve or double rate",
+			Applicable: func(r rune) bool {
+				return (r == '[' || r == ']') && !g.prompting.Load() && g.canSwitchRate()
+			},
+			Action: func(r rune) error {
+				factor := 2.0
+				if r == '[' {
+					factor = 0.5
+				}
+				go g.changeRate(func(current float64) float64 { return current * factor })
+				return nil
+			},
+		},
+		{
+			Name: "set rate",
+			Applicable: func(r rune) bool {
+				return r >= '0' && r <= '9' && !g.prompting.Load() && g.canSwitchRate()
+			},
+			Action: func(r rune) error {
+				go g.promptForRate(ctx, r)
+				return nil
+			},
+		},
+		{
+			Name:       "drop history",
+			Applicable: func(r rune) bool { return r == 'd' && !g.prompting.Load() },
+			Action: func(rune) error {
+				go g.showDrops(ctx)
+				return nil
+			},
+		},
+		{
+			// Shifted so that it isn't pressed by accident, it's also confirmed.
+			Name:       "reset graph",
+			Applicable: func(r rune) bool { return r == 'R' && !g.prompting.Load() },
+			Action: func(rune) error {
+				go g.promptForReset(ctx)
+				return nil
+			},
+		},
+		{
+			Name:       "cycle readout zone",
+			Applicable: func(r rune) bool { return r == 'z' },
+			Action: func(rune) error {
+				g.dataMutex.Lock()
+				defer g.dataMutex.Unlock()
+				g.options.readoutZone = g.options.readoutZone.next()
+				g.invalidateFrame()
+				return nil
+			},
+		},
+		{
+			Name:       "cycle labels",
+			Applicable: func(r rune) bool { return r == 'l' },
+			Action: func(rune) error {
+				g.dataMutex.Lock()
+				defer g.dataMutex.Unlock()
+				g.options.labels = g.options.labels.next()
+				g.invalidateFrame()
+				return nil
+			},
+		},
+		{
+			Name:       "toggle gradients",
+			Applicable: func(r rune) bool { return r == 'g' },
+			Action: func(rune) error {
+				g.dataMutex.Lock()
+				defer g.dataMutex.Unlock()
+				g.options.forceGradients = !g.options.forceGradients
+				g.invalidateFrame()
+				return nil
+			},
+		},
+		{
+			Name:       "toggle longest outage",
+			Applicable: func(r rune) bool { return r == 'o' },
+			Action: func(rune) error {
+				g.dataMutex.Lock()
+				defer g.dataMutex.Unlock()
+				g.options.markLongestOutage = !g.options.markLongestOutage
+				g.invalidateFrame()
+				return nil
+			},
+		},
+		{
+			Name:       "toggle markers",
+			Applicable: func(r rune) bool { return r == 'm' },
+			Action: func(rune) error {
+				g.dataMutex.Lock()
+				defer g.dataMutex.Unlock()
+				g.options.hideMarkers = !g.options.hideMarkers
+				g.invalidateFrame()
+				return nil
+			},
+		},
+		{
+			Name:       "toggle grid",
+			Applicable: func(r rune) bool { return r == '#' },
+			Action: func(rune) error {
+				g.dataMutex.Lock()
+				defer g.dataMutex.Unlock()
+				g.options.grid = !g.options.grid
+				g.invalidateFrame()
+				return nil
+			},
+		},
+		{
+			Name:       "toggle panel",
+			Applicable: func(r rune) bool { return r == 'p' },
+			Action: func(rune) error {
+				g.dataMutex.Lock()
+				defer g.dataMutex.Unlock()
+				g.options.panel = !g.options.panel
+				g.invalidateFrame()
+				return nil
+			},
+		},
+		{
+			Name:       "toggle relative x-axis",
+			Applicable: func(r rune) bool { return r == 'r' },
+			Action: func(rune) error {
+				g.dataMutex.Lock()
+				defer g.dataMutex.Unlock()
+				g.options.relativeX = !g.options.relativeX
+				g.invalidateFrame()
+				return nil
+			},
+		},
+	}
+}
+
+// invalidateFrame ensures the next frame is fully re-drawn, should be called with the dataMutex held when
+// anything other than the data changes how a frame is drawn.
+func (g *Graph) invalidateFrame() {
+	g.lastFrame.PacketCount = -1
+}
+
+func (g *Graph) synchronize(toWrite string) string {
+	if !g.synchronizedOutput || toWrite == "" {
+		return toWrite
+	}
+	return ansi.BeginSync + toWrite + ansi.EndSync
+}
+
+func (g *Graph) AddPoint(p ping.PingResults) {
+	g.dataMutex.Lock()
 	g.data.AddPoint(p)
+	g.arrived = true
+	g.dataMutex.Unlock()
+	g.subscribers.publish(p)
 }
 
 func (g *Graph) LastFrame() string {
@@ -106,6 +658,54 @@ func (g *Graph) ComputeFrame() string {
 	return g.computeFrame(0, false)
 }
 
+// OneFrame updates the terminal size and then computes a complete frame which clears the screen and draws
+// the graph from the top left, or a block of lines if [Graph.SetClearScreen] is disabled. Suitable for drawing
+// a static graph, e.g. from a file. If nothing has changed since the last frame then an empty string is
+// returned.
+func (g *Graph) OneFrame() (string, error) {
+	if err := g.Term.UpdateCurrentTerminalSize(); err != nil {
+		return "", err
+	}
+	frame := g.computeFrame(0, false)
+	if frame == "" {
+		return "", nil
+	}
+	if !g.clearScreen {
+		// The frame may only be what changed, a block is printed after the last so it must be whole.
+		return frameBlock(g.render(g.Term.Size()), g.Term.Size()), nil
+	}
+	return ansi.Home + frame, nil
+}
+
+// RenderTo writes a complete frame of the graph drawn for the size to w. Unlike the other ways of drawing, this
+// doesn't need a terminal and is always drawn in full, it doesn't affect what [Graph.Run] or
+// [Graph.OneFrame] draw next. Useful for drawing a graph headless or into something other than a terminal.
+func (g *Graph) RenderTo(w io.Writer, size terminal.Size) error {
+	_, err := io.WriteString(w, g.render(size))
+	return err
+}
+
+func (g *Graph) render(s terminal.Size) string {
+	if tooSmallToDraw(s) {
+		return drawTooSmall(s)
+	}
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	if g.data.TotalCount == 0 {
+		return drawWaiting(s, g.url)
+	}
+	x, y, innerFrame := g.computeFrameParts(s, g.options)
+	return paint(s, x.axis, y.axis, innerFrame, "")
+}
+
+// Snapshot returns the URL being pinged along with a snapshot of the current stats and a copy of the time span
+// of the data, safe to use while the graph carries on receiving points.
+func (g *Graph) Snapshot() (string, data.StatsSnapshot, data.TimeSpan) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	return g.url, g.data.Header.Stats.Snapshot(), *g.data.Header.TimeSpan
+}
+
 func (g *Graph) Summarize() string {
 	g.dataMutex.Lock()
 	defer g.dataMutex.Unlock()
@@ -132,25 +732,121 @@ func (g *Graph) sink(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			g.sinkAlive = false
+			g.subscribers.close()
 			return
 		case p, ok := <-g.dataChannel:
 			if !ok {
 				g.sinkAlive = false
+				g.subscribers.close()
 				return
 			}
 			g.dataMutex.Lock()
 			g.data.AddPoint(p)
+			g.arrived = true
+			url := g.url
 			g.dataMutex.Unlock()
+			g.subscribers.publish(p)
+			if g.logPings.Load() {
+				logPing(ctx, slog.Default(), url, p)
+			}
 		}
 	}
 }
 
+func logPing(ctx context.Context, logger *slog.Logger, url string, p ping.PingResults) {
+	attrs := []slog.Attr{
+		slog.String("url", url),
+		slog.Time("timestamp", p.Data.Timestamp),
+		slog.String("ip", p.IP.String()),
+		slog.Int("seq", int(p.Seq)),
+		slog.Int("reply_seq", int(p.ReplySeq)),
+	}
+	if p.Data.Good() {
+		attrs = append(attrs, slog.Duration("latency", p.Data.Duration))
+	} else {
+		attrs = append(attrs, slog.String("drop_reason", p.Data.DropReason.String()))
+	}
+	if p.InternalErr != nil {
+		attrs = append(attrs, slog.String("error", p.InternalErr.Error()))
+	}
+	logger.LogAttrs(ctx, slog.LevelDebug, "ping", attrs...)
+}
+
 type frame struct {
 	PacketCount  int64
 	yAxis        yAxis
 	xAxis        xAxis
 	insideFrame  string
 	spinnerIndex int
+	// spinner is the last spinner (and any stale notice) which was drawn, it only needs re-drawing when it
+	// changes.
+	spinner string
+	// tooSmall is set when the last frame was only a notice that the terminal is too small to draw in.
+	tooSmall bool
+	// waiting is set when the last frame was only a notice that there is no data yet.
+	waiting bool
+}
+
+// drawOptions are the user configurable parts of how a frame is drawn.
+type drawOptions struct {
+	// forceGradients draws the interpolated lines between points regardless of [shouldGradient].
+	forceGradients bool
+	// ascii replaces every glyph drawn with an ASCII fallback.
+	ascii bool
+	// timeFormat is the layout of the x-axis labels, empty for the [defaultTimeFormat].
+	timeFormat string
+	// captureSpan is the span of the whole capture when only part of it is drawn, see [Graph.SetCaptureSpan].
+	captureSpan *data.TimeSpan
+	// maxXLabels caps the number of x-axis labels, zero for as many as fit. See [Graph.SetMaxXLabels].
+	maxXLabels int
+	// readoutZone is the zone of the inspection cursor's readout, see [Graph.SetReadoutZone].
+	readoutZone ReadoutZone
+	// fileZone is the zone the data was captured in, nil if it's not known. See [FileZone].
+	fileZone *time.Location
+	// reverseX draws the newest points on the left of the graph instead of the right.
+	reverseX bool
+	// markLongestOutage underlines the longest streak of dropped packets.
+	markLongestOutage bool
+	// relativeX labels the x-axis with the time elapsed since the first point instead of the time of day.
+	relativeX bool
+	// durationFormat is how the durations in the title's statistics are written.
+	durationFormat data.DurationFormat
+	// warmup is how many of the first good packets after each DNS resolution are excluded from the stats, see
+	// [Graph.SetWarmup].
+	warmup int
+	// hideMarkers draws every point plainly, without the min, max or latest markers.
+	hideMarkers bool
+	// markLatest highlights the most recent good point and draws a key for the markers.
+	markLatest bool
+	// lossGauge draws the packet loss at the end of the x-axis, see [Graph.SetLossGauge].
+	lossGauge bool
+	// glyphSet is what the points and markers are drawn with, see [Graph.SetGlyphs].
+	glyphSet Glyphs
+	// window is the recent stretch of data compared with the lifetime stats in the title, see [Graph.SetWindow].
+	window data.Window
+	// renderer is how the points are drawn, see [Graph.SetRenderer].
+	renderer Renderer
+	// yLabels is where the y-axis is labelled, see [Graph.SetYLabels].
+	yLabels YLabels
+	// grid draws faint lines from each axis label across the graph.
+	grid bool
+	// detectStale shows a notice when the newest point is older than a few pings.
+	detectStale bool
+	// annotations are drawn as vertical lines behind the points, see [Graph.SetAnnotations].
+	annotations []Annotation
+	// panel draws a readout of live numbers to the right of the graph, see [Graph.SetPanel].
+	panel bool
+	// compare is another capture drawn over the graph, named by the compareNames. See [Graph.SetCompare].
+	compare      *data.Data
+	compareNames [2]string
+	// trueColour colours the gradients by latency with 24-bit colour, see [Graph.SetTrueColour].
+	trueColour bool
+	// gapStats adds the time between pings to the panel, see [Graph.SetGapStats].
+	gapStats bool
+	// labels is how much is drawn to mark the min and max points, see [Graph.SetLabels].
+	labels Labels
+	// cursor is the column of the inspection cursor, moved by the arrow keys. Zero when it's hidden.
+	cursor int
 }
 
 func (f frame) Match(s terminal.Size) bool {
@@ -158,5 +854,5 @@ func (f frame) Match(s terminal.Size) bool {
 }
 
 func (f frame) Size() terminal.Size {
-	return terminal.Size{Height: f.xAxis.size, Width: f.yAxis.size}
+	return terminal.Size{Height: f.yAxis.size, Width: f.xAxis.size}
 }
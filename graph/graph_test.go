@@ -9,6 +9,7 @@ package graph_test
 import (
 	"context"
 	"fmt"
+	"io"
 	"math/rand/v2"
 	"os"
 	"strconv"
@@ -17,6 +18,7 @@ import (
 	"time"
 
 	"github.com/Lexer747/AcciPing/graph"
+	"github.com/Lexer747/AcciPing/graph/data"
 	"github.com/Lexer747/AcciPing/graph/terminal"
 	"github.com/Lexer747/AcciPing/graph/terminal/ansi"
 	"github.com/Lexer747/AcciPing/graph/terminal/th"
@@ -188,10 +190,496 @@ func TestThousandsDrawing(t *testing.T) {
 	drawingTest(t, test)
 }
 
+func TestFlatDrawing(t *testing.T) {
+	t.Parallel()
+	values := []ping.PingDataPoint{
+		{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second)},
+		{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(2 * time.Second)},
+		{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(3 * time.Second)},
+	}
+	drawingTest(t, DrawingTest{
+		Size:         terminal.Size{Height: 5, Width: 20},
+		Values:       values,
+		ExpectedFile: "testdata/flat-small.frame",
+	})
+	drawingTest(t, DrawingTest{
+		Size:         terminal.Size{Height: 35, Width: 160},
+		Values:       values,
+		ExpectedFile: "testdata/flat-large.frame",
+	})
+	// Two identical points, at the same time and latency, so neither axis has a range.
+	drawingTest(t, DrawingTest{
+		Size: terminal.Size{Height: 10, Width: 40},
+		Values: []ping.PingDataPoint{
+			{Duration: 5 * time.Millisecond, Timestamp: time.Time{}.Add(1 * time.Second)},
+			{Duration: 5 * time.Millisecond, Timestamp: time.Time{}.Add(1 * time.Second)},
+		},
+		ExpectedFile: "testdata/flat-identical.frame",
+	})
+}
+
+func TestAllDroppedDrawing(t *testing.T) {
+	t.Parallel()
+	test := DrawingTest{
+		Size: terminal.Size{Height: 15, Width: 80},
+		Values: []ping.PingDataPoint{
+			{DropReason: ping.TestDrop, Timestamp: time.Time{}.Add(1 * time.Second)},
+			{DropReason: ping.TestDrop, Timestamp: time.Time{}.Add(2 * time.Second)},
+			{DropReason: ping.TestDrop, Timestamp: time.Time{}.Add(3 * time.Second)},
+		},
+		ExpectedFile: "testdata/all-dropped.frame",
+	}
+	drawingTest(t, test)
+}
+
+func TestRenderTo(t *testing.T) {
+	t.Parallel()
+	size := terminal.Size{Height: 15, Width: 80}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// No terminal is needed
+	g, err := graph.NewGraph(ctx, make(chan ping.PingResults), nil, 0, "")
+	require.NoError(t, err)
+	for _, p := range []ping.PingDataPoint{
+		{DropReason: ping.TestDrop, Timestamp: time.Time{}.Add(1 * time.Second)},
+		{DropReason: ping.TestDrop, Timestamp: time.Time{}.Add(2 * time.Second)},
+		{DropReason: ping.TestDrop, Timestamp: time.Time{}.Add(3 * time.Second)},
+	} {
+		g.AddPoint(ping.PingResults{Data: p, IP: []byte{}})
+	}
+	var b strings.Builder
+	require.NoError(t, g.RenderTo(&b, size))
+	expectedBytes, err := os.ReadFile("testdata/all-dropped.frame")
+	require.NoError(t, err)
+	actual := playAnsiOntoStringBuffer(b.String(), makeBuffer(size), size)
+	require.Equal(t, string(expectedBytes), strings.Join(actual, "\n"))
+}
+
+func TestOneFrameBlock(t *testing.T) {
+	t.Parallel()
+	size := terminal.Size{Height: 15, Width: 80}
+	g, closer, err := initTestGraph(t, size)
+	require.NoError(t, err)
+	defer closer()
+	g.SetClearScreen(false)
+	for _, p := range []ping.PingDataPoint{
+		{DropReason: ping.TestDrop, Timestamp: time.Time{}.Add(1 * time.Second)},
+		{DropReason: ping.TestDrop, Timestamp: time.Time{}.Add(2 * time.Second)},
+		{DropReason: ping.TestDrop, Timestamp: time.Time{}.Add(3 * time.Second)},
+	} {
+		g.AddPoint(ping.PingResults{Data: p, IP: []byte{}})
+	}
+	block, err := g.OneFrame()
+	require.NoError(t, err)
+	require.NotContains(t, block, ansi.Clear)
+	require.NotContains(t, block, ansi.Home)
+	require.True(t, strings.HasSuffix(block, "\n"))
+	// Without its colours the block is the frame, line by line.
+	expectedBytes, err := os.ReadFile("testdata/all-dropped.frame")
+	require.NoError(t, err)
+	expected := strings.Split(string(expectedBytes), "\n")
+	for i := range expected {
+		expected[i] = strings.TrimRight(expected[i], " ")
+	}
+	require.Equal(t, expected, strings.Split(stripColours(strings.TrimSuffix(block, "\n")), "\n"))
+
+	again, err := g.OneFrame()
+	require.NoError(t, err)
+	require.Empty(t, again, "nothing changed")
+}
+
+func stripColours(s string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(s, ansi.CSI)
+		if start < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		b.WriteString(s[:start])
+		s = s[start+len(ansi.CSI):]
+		s = s[strings.Index(s, "m")+1:]
+	}
+}
+
+func TestOnFrame(t *testing.T) {
+	t.Parallel()
+	_, _, term, setTerm, err := th.NewTestTerminal()
+	require.NoError(t, err)
+	size := terminal.Size{Height: 15, Width: 80}
+	setTerm(size)
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	input := make(chan ping.PingResults)
+	g, err := graph.NewGraph(ctx, input, term, 0, "www.example.com")
+	require.NoError(t, err)
+	frames := make(chan string, 100)
+	g.SetOnFrame(func(drawn string) {
+		select {
+		case frames <- drawn:
+		default:
+		}
+	})
+	go func() { _ = g.Run(ctx, cancel, 100) }()
+	input <- ping.PingResults{Data: ping.PingDataPoint{Duration: time.Millisecond, Timestamp: time.Now()}, IP: []byte{}}
+	for drawn := range frames {
+		if strings.Contains(drawn, "Latency") {
+			require.Contains(t, drawn, "www.example.com")
+			require.Contains(t, drawn, g.LastFrame(), "the whole frame was drawn")
+			return
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	t.Parallel()
+	size := terminal.Size{Height: 15, Width: 80}
+	g, _, err := initTestGraph(t, size)
+	require.NoError(t, err)
+	fresh, _, err := initTestGraph(t, size)
+	require.NoError(t, err)
+	empty := fresh.ComputeFrame()
+	drawn := eval(t, g, []ping.PingDataPoint{
+		{Duration: 5 * time.Millisecond, Timestamp: time.Time{}.Add(1 * time.Second)},
+		{Duration: 9 * time.Millisecond, Timestamp: time.Time{}.Add(2 * time.Second)},
+	})
+	require.NotEqual(t, empty, drawn)
+	g.Reset()
+	require.Equal(t, int64(0), g.Size())
+	require.Equal(t, empty, g.ComputeFrame(), "the frame is re-drawn as if nothing had been added")
+	_, stats, _ := g.Snapshot()
+	require.Equal(t, uint64(0), stats.Count)
+}
+
+func TestTrueColourDrawing(t *testing.T) {
+	t.Parallel()
+	size := terminal.Size{Height: 15, Width: 80}
+	values := []ping.PingDataPoint{
+		{Duration: 6 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second)},
+		{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(60 * time.Second)},
+		{Duration: 4 * time.Second, Timestamp: time.Time{}.Add(90 * time.Second)},
+		{Duration: 2 * time.Second, Timestamp: time.Time{}.Add(120 * time.Second)},
+	}
+	trueColour := func(g *graph.Graph) { g.SetTrueColour(true) }
+	require.Equal(t, drawGraph(t, size, values), drawGraph(t, size, values, trueColour),
+		"only the colours of the lines change")
+	g, _, err := initTestGraph(t, size)
+	require.NoError(t, err)
+	trueColour(g)
+	require.Contains(t, eval(t, g, values), "\033[38;2;255;0;0m", "the line to the slowest point is red")
+}
+
+func TestCompareDrawing(t *testing.T) {
+	t.Parallel()
+	other := data.NewData("www.example.com")
+	for _, p := range []ping.PingDataPoint{
+		{Duration: 9 * time.Millisecond, Timestamp: time.Time{}.Add(30 * time.Second)},
+		{Duration: 12 * time.Millisecond, Timestamp: time.Time{}.Add(60 * time.Second)},
+		{DropReason: ping.TestDrop, Timestamp: time.Time{}.Add(90 * time.Second)},
+		{Duration: 10 * time.Millisecond, Timestamp: time.Time{}.Add(120 * time.Second)},
+	} {
+		other.AddPoint(ping.PingResults{Data: p, IP: []byte{}})
+	}
+	test := DrawingTest{
+		Size: terminal.Size{Height: 15, Width: 80},
+		Values: []ping.PingDataPoint{
+			{Duration: 3 * time.Millisecond, Timestamp: time.Time{}.Add(1 * time.Second)},
+			{Duration: 5 * time.Millisecond, Timestamp: time.Time{}.Add(40 * time.Second)},
+			{Duration: 2 * time.Millisecond, Timestamp: time.Time{}.Add(80 * time.Second)},
+		},
+		ExpectedFile: "testdata/compare.frame",
+		Configure:    func(g *graph.Graph) { g.SetCompare(other, "wired.pings", "wifi.pings") },
+	}
+	drawingTest(t, test)
+}
+
+func TestDNSFailureDrawing(t *testing.T) {
+	t.Parallel()
+	test := DrawingTest{
+		Size: terminal.Size{Height: 15, Width: 80},
+		Values: []ping.PingDataPoint{
+			{Duration: 6 * time.Millisecond, Timestamp: time.Time{}.Add(1 * time.Second)},
+			{DropReason: ping.Timeout, Timestamp: time.Time{}.Add(2 * time.Second)},
+			{Duration: 5 * time.Millisecond, Timestamp: time.Time{}.Add(3 * time.Second)},
+			{DropReason: ping.DNSFailure, Timestamp: time.Time{}.Add(4 * time.Second)},
+			{DropReason: ping.DNSFailure, Timestamp: time.Time{}.Add(6 * time.Second)},
+			{Duration: 4 * time.Millisecond, Timestamp: time.Time{}.Add(7 * time.Second)},
+			{Duration: 7 * time.Millisecond, Timestamp: time.Time{}.Add(8 * time.Second)},
+		},
+		ExpectedFile: "testdata/dns-failure.frame",
+	}
+	// The key is left out since the timeout's column runs through it.
+	drawingTest(t, test)
+	test.Values = []ping.PingDataPoint{
+		{Duration: 6 * time.Millisecond, Timestamp: time.Time{}.Add(1 * time.Second)},
+		{Duration: 5 * time.Millisecond, Timestamp: time.Time{}.Add(2 * time.Second)},
+		{Duration: 7 * time.Millisecond, Timestamp: time.Time{}.Add(3 * time.Second)},
+		{DropReason: ping.Timeout, Timestamp: time.Time{}.Add(6 * time.Second)},
+		{DropReason: ping.DNSFailure, Timestamp: time.Time{}.Add(7 * time.Second)},
+		{Duration: 4 * time.Millisecond, Timestamp: time.Time{}.Add(8 * time.Second)},
+	}
+	test.ExpectedFile = "testdata/dns-failure-key.frame"
+	drawingTest(t, test)
+}
+
+func TestInternalErrorDrawing(t *testing.T) {
+	t.Parallel()
+	test := DrawingTest{
+		Size: terminal.Size{Height: 15, Width: 80},
+		Values: []ping.PingDataPoint{
+			{Duration: 6 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second)},
+			{DropReason: ping.InternalError, Timestamp: time.Time{}.Add(2 * time.Second)},
+			{DropReason: ping.TestDrop, Timestamp: time.Time{}.Add(3 * time.Second)},
+			{Duration: 4 * time.Second, Timestamp: time.Time{}.Add(4 * time.Second)},
+		},
+		ExpectedFile: "testdata/internal-error.frame",
+	}
+	drawingTest(t, test)
+}
+
+func TestReverseXDrawing(t *testing.T) {
+	t.Parallel()
+	test := DrawingTest{
+		Size: terminal.Size{Height: 15, Width: 80},
+		Values: []ping.PingDataPoint{
+			{Duration: 6 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second)},
+			{DropReason: ping.TestDrop, Timestamp: time.Time{}.Add(30 * time.Second)},
+			{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(60 * time.Second)},
+			{Duration: 4 * time.Second, Timestamp: time.Time{}.Add(90 * time.Second)},
+		},
+		ExpectedFile: "testdata/reverse-x.frame",
+		Configure: func(g *graph.Graph) {
+			g.SetForceGradients(true)
+			g.SetReverseX(true)
+		},
+	}
+	drawingTest(t, test)
+}
+
+func TestRelativeXDrawing(t *testing.T) {
+	t.Parallel()
+	test := DrawingTest{
+		Size: terminal.Size{Height: 15, Width: 80},
+		Values: []ping.PingDataPoint{
+			{Duration: 6 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second)},
+			{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(60 * time.Second)},
+			{Duration: 4 * time.Second, Timestamp: time.Time{}.Add(90 * time.Second)},
+		},
+		ExpectedFile: "testdata/relative-x.frame",
+		Configure:    func(g *graph.Graph) { g.SetRelativeX(true) },
+	}
+	drawingTest(t, test)
+}
+
+func TestLongestOutageDrawing(t *testing.T) {
+	t.Parallel()
+	test := DrawingTest{
+		Size: terminal.Size{Height: 15, Width: 80},
+		Values: []ping.PingDataPoint{
+			{Duration: 6 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second)},
+			{DropReason: ping.TestDrop, Timestamp: time.Time{}.Add(10 * time.Second)},
+			{Duration: 2 * time.Second, Timestamp: time.Time{}.Add(20 * time.Second)},
+			{DropReason: ping.TestDrop, Timestamp: time.Time{}.Add(30 * time.Second)},
+			{DropReason: ping.TestDrop, Timestamp: time.Time{}.Add(40 * time.Second)},
+			{DropReason: ping.TestDrop, Timestamp: time.Time{}.Add(50 * time.Second)},
+			{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(60 * time.Second)},
+			{Duration: 4 * time.Second, Timestamp: time.Time{}.Add(90 * time.Second)},
+		},
+		ExpectedFile: "testdata/longest-outage.frame",
+		Configure:    func(g *graph.Graph) { g.SetMarkLongestOutage(true) },
+	}
+	drawingTest(t, test)
+}
+
+func TestTimeFormatDrawing(t *testing.T) {
+	t.Parallel()
+	test := DrawingTest{
+		Size: terminal.Size{Height: 15, Width: 80},
+		Values: []ping.PingDataPoint{
+			{Duration: 6 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second)},
+			{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(13 * time.Hour)},
+			{Duration: 4 * time.Second, Timestamp: time.Time{}.Add(26 * time.Hour)},
+		},
+		ExpectedFile: "testdata/time-format.frame",
+		Configure:    func(g *graph.Graph) { g.SetTimeFormat("02/01 3:04PM") },
+	}
+	drawingTest(t, test)
+}
+
+func TestMaxXLabelsDrawing(t *testing.T) {
+	t.Parallel()
+	test := DrawingTest{
+		Size: terminal.Size{Height: 15, Width: 354},
+		Values: []ping.PingDataPoint{
+			{Duration: 6 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second)},
+			{Duration: 2 * time.Second, Timestamp: time.Time{}.Add(20 * time.Second)},
+			{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(60 * time.Second)},
+			{Duration: 4 * time.Second, Timestamp: time.Time{}.Add(80 * time.Second)},
+		},
+		ExpectedFile: "testdata/max-xlabels.frame",
+		Configure:    func(g *graph.Graph) { g.SetMaxXLabels(6) },
+	}
+	drawingTest(t, test)
+}
+
+func TestOffScreenDrawing(t *testing.T) {
+	t.Parallel()
+	test := DrawingTest{
+		Size: terminal.Size{Height: 15, Width: 80},
+		Values: []ping.PingDataPoint{
+			{Duration: 6 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second)},
+			{Duration: 2 * time.Second, Timestamp: time.Time{}.Add(20 * time.Second)},
+			{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(60 * time.Second)},
+			{Duration: 4 * time.Second, Timestamp: time.Time{}.Add(80 * time.Second)},
+		},
+		ExpectedFile: "testdata/off-screen.frame",
+		Configure: func(g *graph.Graph) {
+			g.SetCaptureSpan(&data.TimeSpan{Begin: time.Time{}, End: time.Time{}.Add(time.Hour), Duration: time.Hour})
+		},
+	}
+	drawingTest(t, test)
+}
+
+func TestOutOfOrderDrawing(t *testing.T) {
+	t.Parallel()
+	test := DrawingTest{
+		Size: terminal.Size{Height: 15, Width: 80},
+		Values: []ping.PingDataPoint{
+			{Duration: 6 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second)},
+			{DropReason: ping.Timeout, Timestamp: time.Time{}.Add(2 * time.Second)},
+			{DropReason: ping.OutOfOrder, Timestamp: time.Time{}.Add(3 * time.Second)},
+			{Duration: 4 * time.Second, Timestamp: time.Time{}.Add(4 * time.Second)},
+		},
+		ExpectedFile: "testdata/out-of-order.frame",
+	}
+	drawingTest(t, test)
+}
+
+func TestTTLDrawing(t *testing.T) {
+	t.Parallel()
+	test := DrawingTest{
+		Size: terminal.Size{Height: 20, Width: 80},
+		Values: []ping.PingDataPoint{
+			{Duration: 3 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second), TTL: 57},
+			{Duration: 5 * time.Second, Timestamp: time.Time{}.Add(2 * time.Second), TTL: 57},
+			{DropReason: ping.Timeout, Timestamp: time.Time{}.Add(3 * time.Second)},
+			{Duration: 2 * time.Second, Timestamp: time.Time{}.Add(4 * time.Second), TTL: 57},
+			{Duration: 6 * time.Second, Timestamp: time.Time{}.Add(5 * time.Second), TTL: 118},
+			{Duration: 4 * time.Second, Timestamp: time.Time{}.Add(6 * time.Second), TTL: 118},
+			{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(7 * time.Second), TTL: 118},
+		},
+		ExpectedFile: "testdata/ttl.frame",
+	}
+	drawingTest(t, test)
+}
+
+func TestASCIIDrawing(t *testing.T) {
+	t.Parallel()
+	test := DrawingTest{
+		Size: terminal.Size{Height: 15, Width: 80},
+		Values: []ping.PingDataPoint{
+			{Duration: 6 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second)},
+			{DropReason: ping.TestDrop, Timestamp: time.Time{}.Add(30 * time.Second)},
+			{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(60 * time.Second)},
+			{Duration: 4 * time.Second, Timestamp: time.Time{}.Add(90 * time.Second)},
+		},
+		ExpectedFile: "testdata/ascii.frame",
+		Configure: func(g *graph.Graph) {
+			g.SetForceGradients(true)
+			g.SetASCII(true)
+		},
+	}
+	drawingTest(t, test)
+}
+
+func TestForcedGradientDrawing(t *testing.T) {
+	t.Parallel()
+	test := DrawingTest{
+		Size: terminal.Size{Height: 15, Width: 80},
+		Values: []ping.PingDataPoint{
+			// The first two points are too close together to be drawn apart, so gradients are only drawn when
+			// forced.
+			{Duration: 6 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second)},
+			{Duration: 5 * time.Second, Timestamp: time.Time{}.Add(1*time.Second + time.Millisecond)},
+			{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(60 * time.Second)},
+			{Duration: 4 * time.Second, Timestamp: time.Time{}.Add(90 * time.Second)},
+		},
+		ExpectedFile: "testdata/forced-gradient.frame",
+		Configure:    func(g *graph.Graph) { g.SetForceGradients(true) },
+	}
+	drawingTest(t, test)
+}
+
+func TestTooSmallDrawing(t *testing.T) {
+	t.Parallel()
+	values := []ping.PingDataPoint{
+		{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second)},
+		{Duration: 2 * time.Second, Timestamp: time.Time{}.Add(2 * time.Second)},
+	}
+	for size, expected := range map[terminal.Size]string{
+		{Height: 4, Width: 80}:  "Terminal too small (need ≥20x5)",
+		{Height: 4, Width: 22}:  "Too small, need ≥20x5",
+		{Height: 25, Width: 10}: "Need ≥20x5",
+		{Height: 2, Width: 6}:   "≥20x5",
+		{Height: 2, Width: 3}:   "≥20",
+	} {
+		t.Run(size.String(), func(t *testing.T) {
+			t.Parallel()
+			actual := strings.Join(drawGraph(t, size, values), "")
+			require.Contains(t, actual, expected)
+		})
+	}
+}
+
+// TestSmallSizesDrawing draws with every option which takes up space at each size up to a little past the
+// smallest drawable, none of which should panic.
+func TestSmallSizesDrawing(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, values := range [][]ping.PingDataPoint{
+		{},
+		{{Duration: time.Millisecond, Timestamp: time.Time{}}},
+		{{Duration: time.Millisecond, Timestamp: time.Time{}}, {Duration: time.Millisecond, Timestamp: time.Time{}.Add(time.Second)}},
+		{{DropReason: ping.TestDrop, Timestamp: time.Time{}}, {DropReason: ping.TestDrop, Timestamp: time.Time{}.Add(time.Second)}},
+		{
+			{Duration: 6 * time.Millisecond, Timestamp: time.Time{}},
+			{DropReason: ping.TestDrop, Timestamp: time.Time{}.Add(time.Second)},
+			{Duration: time.Millisecond, Timestamp: time.Time{}.Add(2 * time.Second)},
+			{Duration: 3 * time.Millisecond, Timestamp: time.Time{}.Add(3 * time.Second)},
+		},
+	} {
+		for _, renderer := range []graph.Renderer{graph.DefaultRenderer, graph.BrailleRenderer} {
+			g, err := graph.NewGraph(ctx, make(chan ping.PingResults), nil, 0, "www.example.com")
+			require.NoError(t, err)
+			for _, p := range values {
+				g.AddPoint(ping.PingResults{Data: p})
+			}
+			g.SetRenderer(renderer)
+			g.SetPanel(true)
+			g.SetGrid(true)
+			g.SetMarkLatest(true)
+			g.SetMarkLongestOutage(true)
+			g.SetLossGauge(true)
+			g.SetYLabels(graph.PercentileYLabels)
+			g.SetWindow(data.Window{Points: 2})
+			g.SetCaptureSpan(&data.TimeSpan{Begin: time.Time{}.Add(-time.Hour), End: time.Time{}.Add(time.Hour)})
+			for height := range 8 {
+				for width := range 50 {
+					size := terminal.Size{Height: height, Width: width}
+					require.NotPanics(t, func() { _ = g.RenderTo(io.Discard, size) }, "%s %d points", size, len(values))
+				}
+			}
+		}
+	}
+}
+
 type DrawingTest struct {
 	Size         terminal.Size
 	Values       []ping.PingDataPoint
 	ExpectedFile string
+	// Configure is optional and is called on the graph before drawing.
+	Configure func(*graph.Graph)
 }
 
 // updateDrawingTest used for updating goldens.
@@ -199,7 +687,7 @@ type DrawingTest struct {
 //nolint:unused
 func updateDrawingTest(t *testing.T, test DrawingTest) {
 	t.Helper()
-	actual := drawGraph(t, test.Size, test.Values)
+	actual := drawGraph(t, test.Size, test.Values, test.Configure)
 	err := os.WriteFile(test.ExpectedFile, []byte(strings.Join(actual, "\n")), 0o777)
 	require.NoError(t, err)
 	t.Fatal("Only call update drawing once")
@@ -208,7 +696,7 @@ func updateDrawingTest(t *testing.T, test DrawingTest) {
 func drawingTest(t *testing.T, test DrawingTest) {
 	// updateDrawingTest(t, test)
 	t.Helper()
-	actualStrings := drawGraph(t, test.Size, test.Values)
+	actualStrings := drawGraph(t, test.Size, test.Values, test.Configure)
 	expectedBytes, err := os.ReadFile(test.ExpectedFile)
 	require.NoError(t, err)
 	actualJoined := strings.Join(actualStrings, "\n")
@@ -222,7 +710,7 @@ func drawingTest(t *testing.T, test DrawingTest) {
 	}
 }
 
-func drawGraph(t *testing.T, size terminal.Size, input []ping.PingDataPoint) []string {
+func drawGraph(t *testing.T, size terminal.Size, input []ping.PingDataPoint, configure ...func(*graph.Graph)) []string {
 	t.Helper()
 	if len(input) == 1 {
 		panic("drawGraph test doesn't work on inputs size 1")
@@ -230,6 +718,11 @@ func drawGraph(t *testing.T, size terminal.Size, input []ping.PingDataPoint) []s
 	g, closer, err := initTestGraph(t, size)
 	require.NoError(t, err)
 	defer closer()
+	for _, c := range configure {
+		if c != nil {
+			c(g)
+		}
+	}
 
 	actual := eval(t, g, input)
 	output := makeBuffer(size)
@@ -388,6 +881,13 @@ func (a *ansiState) handleControl(start int) {
 			// Both params present
 			a.consume()
 			col := a.consumeDigits()
+			if a.isNext(';') || a.isNext('m') {
+				// Not a position but a colour with more than one param, e.g. 24-bit colour
+				for !a.consumeIfNext('m') {
+					a.consume()
+				}
+				return
+			}
 			a.consumeExact("H")
 			a.changeCursor(col, d)
 		case 'H': // CursorPosition
@@ -442,3 +942,349 @@ func (a *ansiState) changeCursor(newC, newR int) {
 	}
 	check.Check(a.cursorColumn != 0 && a.cursorRow != 0, "cursor should not be 0")
 }
+
+func TestAnnotationsDrawing(t *testing.T) {
+	t.Parallel()
+	test := DrawingTest{
+		Size: terminal.Size{Height: 15, Width: 80},
+		Values: []ping.PingDataPoint{
+			{Duration: 6 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second)},
+			{Duration: 2 * time.Second, Timestamp: time.Time{}.Add(20 * time.Second)},
+			{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(60 * time.Second)},
+			{Duration: 4 * time.Second, Timestamp: time.Time{}.Add(90 * time.Second)},
+		},
+		ExpectedFile: "testdata/annotations.frame",
+		Configure: func(g *graph.Graph) {
+			g.SetAnnotations([]graph.Annotation{
+				{Time: time.Time{}, Label: "before the data"},
+				{Time: time.Time{}.Add(30 * time.Second), Label: "deploy v1.2 to production"},
+				{Time: time.Time{}.Add(33 * time.Second), Label: "rollback"},
+				{Time: time.Time{}.Add(75 * time.Second), Label: "restart"},
+			})
+		},
+	}
+	drawingTest(t, test)
+}
+
+func TestClusteredExtremesDrawing(t *testing.T) {
+	t.Parallel()
+	// Several points share the min and the max next to each other, each label would be drawn over the last.
+	test := DrawingTest{
+		Size: terminal.Size{Height: 12, Width: 80},
+		Values: []ping.PingDataPoint{
+			{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second)},
+			{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(2 * time.Second)},
+			{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(3 * time.Second)},
+			{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(4 * time.Second)},
+			{Duration: 5 * time.Second, Timestamp: time.Time{}.Add(30 * time.Second)},
+			{Duration: 9 * time.Second, Timestamp: time.Time{}.Add(58 * time.Second)},
+			{Duration: 9 * time.Second, Timestamp: time.Time{}.Add(59 * time.Second)},
+			{Duration: 9 * time.Second, Timestamp: time.Time{}.Add(60 * time.Second)},
+		},
+		ExpectedFile: "testdata/clustered-extremes.frame",
+	}
+	drawingTest(t, test)
+}
+
+func TestReadAnnotations(t *testing.T) {
+	t.Parallel()
+	annotations, err := graph.ReadAnnotations(strings.NewReader(
+		"# time,label\n2024-08-02T20:00:00Z,deploy v1.2\n2024-08-02T20:30:00Z, \"rollback, again\"\n",
+	))
+	require.NoError(t, err)
+	require.Equal(t, []graph.Annotation{
+		{Time: time.Date(2024, 8, 2, 20, 0, 0, 0, time.UTC), Label: "deploy v1.2"},
+		{Time: time.Date(2024, 8, 2, 20, 30, 0, 0, time.UTC), Label: "rollback, again"},
+	}, annotations)
+
+	_, err = graph.ReadAnnotations(strings.NewReader("yesterday,deploy\n"))
+	require.Error(t, err)
+	_, err = graph.ReadAnnotations(strings.NewReader("2024-08-02T20:00:00Z\n"))
+	require.Error(t, err)
+}
+
+func TestWarmupDrawing(t *testing.T) {
+	t.Parallel()
+	test := DrawingTest{
+		Size: terminal.Size{Height: 15, Width: 80},
+		Values: []ping.PingDataPoint{
+			{Duration: 9 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second)},
+			{Duration: 7 * time.Second, Timestamp: time.Time{}.Add(10 * time.Second)},
+			{Duration: 2 * time.Second, Timestamp: time.Time{}.Add(20 * time.Second)},
+			{Duration: 3 * time.Second, Timestamp: time.Time{}.Add(30 * time.Second)},
+			{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(60 * time.Second)},
+			{Duration: 4 * time.Second, Timestamp: time.Time{}.Add(90 * time.Second)},
+		},
+		ExpectedFile: "testdata/warmup.frame",
+		Configure:    func(g *graph.Graph) { g.SetWarmup(2) },
+	}
+	drawingTest(t, test)
+}
+
+func TestMarkLatestDrawing(t *testing.T) {
+	t.Parallel()
+	test := DrawingTest{
+		Size: terminal.Size{Height: 15, Width: 80},
+		Values: []ping.PingDataPoint{
+			{Duration: 6 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second)},
+			{Duration: 2 * time.Second, Timestamp: time.Time{}.Add(20 * time.Second)},
+			{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(60 * time.Second)},
+			{Duration: 4 * time.Second, Timestamp: time.Time{}.Add(80 * time.Second)},
+			{DropReason: ping.TestDrop, Timestamp: time.Time{}.Add(90 * time.Second)},
+		},
+		ExpectedFile: "testdata/mark-latest.frame",
+		Configure:    func(g *graph.Graph) { g.SetMarkLatest(true) },
+	}
+	// The key is left out since the dropped column runs through it.
+	drawingTest(t, test)
+	// With the min on the left the bottom right is empty for the key.
+	test.Values = []ping.PingDataPoint{
+		{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second)},
+		{Duration: 6 * time.Second, Timestamp: time.Time{}.Add(20 * time.Second)},
+		{Duration: 2 * time.Second, Timestamp: time.Time{}.Add(60 * time.Second)},
+		{Duration: 4 * time.Second, Timestamp: time.Time{}.Add(80 * time.Second)},
+	}
+	test.ExpectedFile = "testdata/mark-latest-key.frame"
+	drawingTest(t, test)
+}
+
+func TestNoMarkersDrawing(t *testing.T) {
+	t.Parallel()
+	test := DrawingTest{
+		Size: terminal.Size{Height: 15, Width: 80},
+		Values: []ping.PingDataPoint{
+			{Duration: 6 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second)},
+			{Duration: 2 * time.Second, Timestamp: time.Time{}.Add(20 * time.Second)},
+			{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(60 * time.Second)},
+			{Duration: 4 * time.Second, Timestamp: time.Time{}.Add(90 * time.Second)},
+		},
+		ExpectedFile: "testdata/no-markers.frame",
+		Configure: func(g *graph.Graph) {
+			g.SetMarkLatest(true)
+			g.SetShowMarkers(false)
+		},
+	}
+	drawingTest(t, test)
+}
+
+func TestGridDrawing(t *testing.T) {
+	t.Parallel()
+	test := DrawingTest{
+		Size: terminal.Size{Height: 15, Width: 80},
+		Values: []ping.PingDataPoint{
+			{Duration: 6 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second)},
+			{Duration: 2 * time.Second, Timestamp: time.Time{}.Add(20 * time.Second)},
+			{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(60 * time.Second)},
+			{Duration: 4 * time.Second, Timestamp: time.Time{}.Add(90 * time.Second)},
+		},
+		ExpectedFile: "testdata/grid.frame",
+		Configure:    func(g *graph.Graph) { g.SetGrid(true) },
+	}
+	drawingTest(t, test)
+}
+
+func TestBrailleDrawing(t *testing.T) {
+	t.Parallel()
+	test := DrawingTest{
+		Size: terminal.Size{Height: 15, Width: 80},
+		Values: []ping.PingDataPoint{
+			{Duration: 6 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second)},
+			{Duration: 2 * time.Second, Timestamp: time.Time{}.Add(20 * time.Second)},
+			{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(60 * time.Second)},
+			{DropReason: ping.TestDrop, Timestamp: time.Time{}.Add(70 * time.Second)},
+			{Duration: 3 * time.Second, Timestamp: time.Time{}.Add(75 * time.Second)},
+			{Duration: 4 * time.Second, Timestamp: time.Time{}.Add(90 * time.Second)},
+		},
+		ExpectedFile: "testdata/braille.frame",
+		Configure:    func(g *graph.Graph) { g.SetRenderer(graph.BrailleRenderer) },
+	}
+	drawingTest(t, test)
+}
+
+func TestPercentileYLabelsDrawing(t *testing.T) {
+	t.Parallel()
+	values := []ping.PingDataPoint{}
+	for i, ms := range []int{12, 14, 11, 13, 15, 12, 40, 13, 12, 14, 11, 90, 13, 12, 16, 13, 12, 25, 14, 13} {
+		values = append(values, ping.PingDataPoint{
+			Duration:  time.Duration(ms) * time.Millisecond,
+			Timestamp: time.Time{}.Add(time.Duration(i) * time.Second),
+		})
+	}
+	test := DrawingTest{
+		Size:         terminal.Size{Height: 20, Width: 80},
+		Values:       values,
+		ExpectedFile: "testdata/percentile-ylabels.frame",
+		Configure:    func(g *graph.Graph) { g.SetYLabels(graph.PercentileYLabels) },
+	}
+	drawingTest(t, test)
+}
+
+func TestLossGaugeDrawing(t *testing.T) {
+	t.Parallel()
+	test := DrawingTest{
+		Size: terminal.Size{Height: 15, Width: 80},
+		Values: []ping.PingDataPoint{
+			{Duration: 6 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second)},
+			{Duration: 2 * time.Second, Timestamp: time.Time{}.Add(20 * time.Second)},
+			{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(60 * time.Second)},
+			{Duration: 4 * time.Second, Timestamp: time.Time{}.Add(80 * time.Second)},
+			{DropReason: ping.TestDrop, Timestamp: time.Time{}.Add(90 * time.Second)},
+		},
+		ExpectedFile: "testdata/loss-gauge.frame",
+		Configure:    func(g *graph.Graph) { g.SetLossGauge(true) },
+	}
+	drawingTest(t, test)
+}
+
+func TestWindowTitleDrawing(t *testing.T) {
+	t.Parallel()
+	values := []ping.PingDataPoint{}
+	for i, ms := range []int{12, 14, 11, 13, 15, 12, 14, 13, 40, 45, 42} {
+		values = append(values, ping.PingDataPoint{
+			Duration:  time.Duration(ms) * time.Millisecond,
+			Timestamp: time.Time{}.Add(time.Duration(i) * time.Second),
+		})
+	}
+	test := DrawingTest{
+		Size:         terminal.Size{Height: 15, Width: 80},
+		Values:       values,
+		ExpectedFile: "testdata/window-title.frame",
+		Configure:    func(g *graph.Graph) { g.SetWindow(data.Window{Points: 3}) },
+	}
+	drawingTest(t, test)
+}
+
+func TestGlyphsDrawing(t *testing.T) {
+	t.Parallel()
+	glyphs, err := graph.ParseGlyphs("point=●,min=^,max=v,latest=@", graph.DefaultGlyphs())
+	require.NoError(t, err)
+	test := DrawingTest{
+		Size: terminal.Size{Height: 15, Width: 80},
+		Values: []ping.PingDataPoint{
+			{Duration: 6 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second)},
+			{Duration: 2 * time.Second, Timestamp: time.Time{}.Add(20 * time.Second)},
+			{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(60 * time.Second)},
+			{Duration: 4 * time.Second, Timestamp: time.Time{}.Add(80 * time.Second)},
+			{DropReason: ping.TestDrop, Timestamp: time.Time{}.Add(90 * time.Second)},
+		},
+		ExpectedFile: "testdata/glyphs.frame",
+		Configure: func(g *graph.Graph) {
+			g.SetGlyphs(glyphs)
+			g.SetMarkLatest(true)
+		},
+	}
+	drawingTest(t, test)
+}
+
+func TestReadGlyphs(t *testing.T) {
+	t.Parallel()
+	glyphs, err := graph.ReadGlyphs(strings.NewReader("# Rounder points\npoint = ●\n\ndropped=|\n"))
+	require.NoError(t, err)
+	expected := graph.DefaultGlyphs()
+	expected.Point = "●"
+	expected.Dropped = "|"
+	require.Equal(t, expected, glyphs)
+
+	glyphs, err = graph.ParseGlyphs("min=-", glyphs)
+	require.NoError(t, err)
+	require.Equal(t, "-", glyphs.Min)
+	require.Equal(t, "●", glyphs.Point, "the rest are kept")
+
+	for _, spec := range []string{"point", "point=ab", "point=", "shape=o"} {
+		_, err = graph.ParseGlyphs(spec, graph.DefaultGlyphs())
+		require.Error(t, err, "%q", spec)
+	}
+}
+
+func TestParseYLabels(t *testing.T) {
+	t.Parallel()
+	l, err := graph.ParseYLabels("percentile")
+	require.NoError(t, err)
+	require.Equal(t, graph.PercentileYLabels, l)
+	l, err = graph.ParseYLabels("even")
+	require.NoError(t, err)
+	require.Equal(t, graph.EvenYLabels, l)
+	_, err = graph.ParseYLabels("p99")
+	require.Error(t, err)
+}
+
+func TestParseRenderer(t *testing.T) {
+	t.Parallel()
+	r, err := graph.ParseRenderer("braille")
+	require.NoError(t, err)
+	require.Equal(t, graph.BrailleRenderer, r)
+	r, err = graph.ParseRenderer("default")
+	require.NoError(t, err)
+	require.Equal(t, graph.DefaultRenderer, r)
+	_, err = graph.ParseRenderer("Braille")
+	require.Error(t, err)
+}
+
+func TestLabelsDrawing(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		labels graph.Labels
+		file   string
+	}{
+		{labels: graph.MarkerLabels, file: "testdata/labels-markers.frame"},
+		{labels: graph.NoLabels, file: "testdata/labels-none.frame"},
+	} {
+		t.Run(tc.file, func(t *testing.T) {
+			t.Parallel()
+			test := DrawingTest{
+				Size: terminal.Size{Height: 15, Width: 80},
+				Values: []ping.PingDataPoint{
+					{Duration: 6 * time.Second, Timestamp: time.Time{}.Add(1 * time.Second)},
+					{Duration: 2 * time.Second, Timestamp: time.Time{}.Add(20 * time.Second)},
+					{Duration: 1 * time.Second, Timestamp: time.Time{}.Add(60 * time.Second)},
+					{Duration: 4 * time.Second, Timestamp: time.Time{}.Add(90 * time.Second)},
+				},
+				ExpectedFile: tc.file,
+				Configure:    func(g *graph.Graph) { g.SetLabels(tc.labels) },
+			}
+			drawingTest(t, test)
+		})
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	t.Parallel()
+	for name, expected := range map[string]graph.Labels{
+		"full":    graph.FullLabels,
+		"markers": graph.MarkerLabels,
+		"none":    graph.NoLabels,
+	} {
+		l, err := graph.ParseLabels(name)
+		require.NoError(t, err)
+		require.Equal(t, expected, l)
+	}
+	_, err := graph.ParseLabels("extremes")
+	require.Error(t, err)
+}
+
+func TestPanelDrawing(t *testing.T) {
+	t.Parallel()
+	values := []ping.PingDataPoint{
+		{Duration: 6 * time.Millisecond, Timestamp: time.Time{}.Add(1 * time.Second)},
+		{Duration: 2 * time.Millisecond, Timestamp: time.Time{}.Add(20 * time.Second)},
+		{DropReason: ping.TestDrop, Timestamp: time.Time{}.Add(30 * time.Second)},
+		{Duration: 1 * time.Millisecond, Timestamp: time.Time{}.Add(60 * time.Second)},
+		{Duration: 4 * time.Millisecond, Timestamp: time.Time{}.Add(90 * time.Second)},
+	}
+	panel := func(g *graph.Graph) { g.SetPanel(true) }
+	drawingTest(t, DrawingTest{
+		Size:         terminal.Size{Height: 15, Width: 100},
+		Values:       values,
+		ExpectedFile: "testdata/panel.frame",
+		Configure:    panel,
+	})
+	narrow := terminal.Size{Height: 15, Width: 60}
+	require.Equal(t, drawGraph(t, narrow, values), drawGraph(t, narrow, values, panel),
+		"the panel isn't drawn when the terminal is too narrow")
+	drawingTest(t, DrawingTest{
+		Size:         terminal.Size{Height: 15, Width: 100},
+		Values:       values,
+		ExpectedFile: "testdata/panel-gaps.frame",
+		Configure:    func(g *graph.Graph) { panel(g); g.SetGapStats(true) },
+	})
+}
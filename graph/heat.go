@@ -0,0 +1,42 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package graph
+
+import (
+	"time"
+
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/graph/terminal/ansi"
+)
+
+// heatScale colours by latency on a continuous scale from green at the fastest point, through yellow, to red at
+// the slowest. See [Graph.SetTrueColour].
+type heatScale struct {
+	min, max time.Duration
+}
+
+func newHeatScale(stats *data.Stats) *heatScale {
+	return &heatScale{min: stats.Min, max: stats.Max}
+}
+
+// colour is s drawn in the colour of the latency, latency outside the scale is clamped to either end.
+func (h *heatScale) colour(latency time.Duration, s string) string {
+	r, g := h.rgb(latency)
+	return ansi.RGB(s, r, g, 0)
+}
+
+func (h *heatScale) rgb(latency time.Duration) (r, g uint8) {
+	heat := 0.0
+	if h.max > h.min {
+		heat = min(max(float64(latency-h.min)/float64(h.max-h.min), 0), 1)
+	}
+	// Green to yellow is adding red, then yellow to red is taking away green.
+	if heat < 0.5 {
+		return uint8(heat * 2 * 255), 255
+	}
+	return 255, uint8((1 - heat) * 2 * 255)
+}
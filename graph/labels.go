@@ -0,0 +1,42 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package graph
+
+import (
+	"github.com/Lexer747/AcciPing/utils/errors"
+)
+
+// Labels is how much is drawn to mark the min and max points, see [Graph.SetLabels].
+type Labels int
+
+const (
+	// FullLabels marks the min and max points with a marker and their latency.
+	FullLabels Labels = iota
+	// MarkerLabels marks the min and max points with only a marker, which takes up a single cell.
+	MarkerLabels
+	// NoLabels draws the min and max points like every other point.
+	NoLabels
+)
+
+// ParseLabels parses the name of [Labels], one of "full", "markers" or "none".
+func ParseLabels(labels string) (Labels, error) {
+	switch labels {
+	case "full":
+		return FullLabels, nil
+	case "markers":
+		return MarkerLabels, nil
+	case "none":
+		return NoLabels, nil
+	default:
+		return FullLabels, errors.Errorf("Unknown labels %q, expected one of: full|markers|none", labels)
+	}
+}
+
+// next is the labels after these, from the most to the least cluttered and then back round.
+func (l Labels) next() Labels {
+	return (l + 1) % (NoLabels + 1)
+}
@@ -0,0 +1,151 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package graph
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/graph/terminal"
+	"github.com/Lexer747/AcciPing/graph/terminal/ansi"
+	"github.com/Lexer747/AcciPing/gui"
+	"github.com/Lexer747/AcciPing/ping"
+)
+
+const (
+	// panelWidth is the width of the readout panel including its border.
+	panelWidth = 26
+	// minPanelTerminalWidth leaves the graph enough room to still be useful once the panel is drawn.
+	minPanelTerminalWidth = 80
+	// panelLabelWidth is the space given to each label, the value is drawn after it.
+	panelLabelWidth = 10
+)
+
+// graphSize is the space the graph is drawn in, which is the whole terminal unless the readout panel is shown
+// in which case the graph is narrower to make room for it on the right.
+func (opts drawOptions) graphSize(s terminal.Size) terminal.Size {
+	if opts.showPanel(s) {
+		s.Width -= panelWidth
+	}
+	return s
+}
+
+// showPanel is true when the readout panel is enabled and the terminal is wide enough for it.
+func (opts drawOptions) showPanel(s terminal.Size) bool {
+	return opts.panel && s.Width >= minPanelTerminalWidth
+}
+
+// frameLayout is the terminal with the title along the top row taken, for placing boxes drawn over the graph.
+func frameLayout(s terminal.Size) *gui.Layout {
+	l := gui.NewLayout(s)
+	l.Reserve(gui.Box{Row: 1, Column: 1, Width: s.Width, Height: 1})
+	return l
+}
+
+// placePanel places the readout panel with the number of lines down the right hand side of the layout.
+func placePanel(l *gui.Layout, s terminal.Size, count int) gui.Box {
+	box, _ := l.Place(gui.TopRight, panelWidth, min(count+2, s.Height-2))
+	box.Title = "Live"
+	return box
+}
+
+// drawPanel draws the readout panel in the box (see [placePanel]), a list of live numbers about the data: the
+// latest latency, the stats, the packet loss and how long it's been since a packet was dropped, see
+// [panelLines].
+func drawPanel(b *strings.Builder, box gui.Box, lines [][2]string) {
+	box.Draw(b)
+	valueWidth := box.Inner() - 2 - panelLabelWidth
+	for i, line := range lines[:max(box.Height-2, 0)] {
+		value := line[1]
+		if utf8.RuneCountInString(value) > valueWidth {
+			value = string([]rune(value)[:valueWidth])
+		}
+		b.WriteString(ansi.CursorPosition(box.Row+1+i, box.Column+2))
+		b.WriteString(ansi.Gray(fmt.Sprintf("%-*s", panelLabelWidth, line[0])) + ansi.White(value))
+	}
+}
+
+// readout is every line of the readout panel, should be called with the dataMutex held.
+func (g *Graph) readout(d *data.Data, opts drawOptions) [][2]string {
+	lines := panelLines(d, g.derived.sortedLatencies(g.data), opts.durationFormat)
+	if opts.gapStats {
+		lines = append(lines, gapLines(g.derived.gapStats(g.data), ping.PingsPerMinuteToDuration(g.pingsPerMinute), opts.durationFormat)...)
+	}
+	return lines
+}
+
+// promptLayout is a layout of the terminal with the title and the readout panel (if it's drawn) taken, so that
+// a prompt placed in it doesn't cover them.
+func (g *Graph) promptLayout() *gui.Layout {
+	s := g.Term.Size()
+	l := frameLayout(s)
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	if g.data.TotalCount > 0 && g.options.showPanel(s) {
+		placePanel(l, s, len(g.readout(g.data, g.options)))
+	}
+	return l
+}
+
+// panelLines are each label and value of the readout panel, the p99 is taken from the latencies of the data.
+func panelLines(d *data.Data, latencies data.Latencies, f data.DurationFormat) [][2]string {
+	stats := d.Header.Stats
+	newest := d.Get(d.TotalCount - 1)
+	latest := f.Format(newest.Duration)
+	if !newest.Good() {
+		latest = newest.DropReason.String()
+	}
+	loss := 0.0
+	if stats.GoodCount+stats.PacketsDropped > 0 {
+		loss = stats.PacketLoss() * 100
+	}
+	lastDrop := "never"
+	since := d.Header.TimeSpan.Begin
+	for i := d.TotalCount - 1; i >= 0; i-- {
+		// A late reply isn't another packet the network dropped, see [data.Data.DropEvents].
+		if p := d.Get(i); p.Dropped() && !p.OutOfOrder() {
+			lastDrop = p.Timestamp.Format(time.TimeOnly)
+			since = p.Timestamp
+			break
+		}
+	}
+	return [][2]string{
+		{"latest", latest},
+		{"min", f.Format(stats.Min)},
+		{"max", f.Format(stats.Max)},
+		{"mean", f.Format(time.Duration(stats.Mean))},
+		{"p99", f.Format(latencies.Percentile(99))},
+		{"loss", fmt.Sprintf("%.2f%%", loss)},
+		// Up until the newest point, rather than now, so that the panel doesn't change without new data.
+		{"uptime", newest.Timestamp.Sub(since).Truncate(time.Second).String()},
+		{"last drop", lastDrop},
+	}
+}
+
+// gapLines are the extra lines of the readout panel when the gap stats are shown, the mean and standard
+// deviation of the actual time between pings (see [data.Data.GapStats]) and the interval they were configured to
+// be sent at, unknown if the pings per minute aren't.
+func gapLines(gaps *data.Stats, interval time.Duration, f data.DurationFormat) [][2]string {
+	mean, sd := "-", "-"
+	if gaps.GoodCount > 0 {
+		// Finer than a microsecond doesn't fit and is noise compared to the scheduling.
+		mean = f.Format(time.Duration(gaps.Mean).Round(time.Microsecond))
+		sd = f.Format(time.Duration(gaps.StandardDeviation).Round(time.Microsecond))
+	}
+	configured := "unknown"
+	if interval > 0 {
+		configured = f.Format(interval)
+	}
+	return [][2]string{
+		{"gap mean", mean},
+		{"gap sd", sd},
+		{"interval", configured},
+	}
+}
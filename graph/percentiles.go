@@ -0,0 +1,89 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package graph
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/graph/terminal"
+	"github.com/Lexer747/AcciPing/graph/terminal/ansi"
+	"github.com/Lexer747/AcciPing/utils/errors"
+)
+
+// YLabels is where the y-axis is labelled, see [Graph.SetYLabels].
+type YLabels int
+
+const (
+	// EvenYLabels labels the y-axis every few rows, evenly spaced between the min and max.
+	EvenYLabels YLabels = iota
+	// PercentileYLabels labels the y-axis at the p50, p90 and p99 of the latency, each with a line across the
+	// graph.
+	PercentileYLabels
+)
+
+// ParseYLabels parses the name of [YLabels], one of "even" or "percentile".
+func ParseYLabels(labels string) (YLabels, error) {
+	switch labels {
+	case "even":
+		return EvenYLabels, nil
+	case "percentile":
+		return PercentileYLabels, nil
+	default:
+		return EvenYLabels, errors.Errorf("Unknown y-axis labels %q, expected one of: even|percentile", labels)
+	}
+}
+
+// percentiles are drawn by [PercentileYLabels], in order of importance.
+var percentiles = []struct {
+	name       string
+	percentile float64
+}{{"p99", 99}, {"p90", 90}, {"p50", 50}}
+
+// percentileLine is a row of the graph at a percentile of the latency.
+type percentileLine struct {
+	name  string
+	row   int
+	value time.Duration
+}
+
+// percentileLines are the rows of each percentile of the data, taken from its sorted latencies, sorted top to
+// bottom. When percentiles share a row only the most important is kept. Nil if there are no good points.
+func percentileLines(d *data.Data, latencies data.Latencies, s terminal.Size) []percentileLine {
+	if d.Header.Stats.GoodCount == 0 {
+		return nil
+	}
+	var ret []percentileLine
+	for _, p := range percentiles {
+		value := latencies.Percentile(p.percentile)
+		row := getY(value, d.Header, s)
+		if slices.ContainsFunc(ret, func(l percentileLine) bool { return l.row == row }) {
+			continue
+		}
+		ret = append(ret, percentileLine{name: p.name, row: row, value: value})
+	}
+	slices.SortFunc(ret, func(a, b percentileLine) int { return a.row - b.row })
+	return ret
+}
+
+// drawPercentileLines draws a faint line across the graph at each percentile, like the grid they're drawn first
+// so that everything else is drawn over them.
+func drawPercentileLines(b *strings.Builder, s terminal.Size, yAxis yAxis) {
+	for _, line := range yAxis.percentiles {
+		b.WriteString(ansi.CursorPosition(line.row, yAxis.labelSize) + strings.Repeat(gridHorizontal, s.Width-yAxis.labelSize))
+	}
+}
+
+// namePercentileLines names each percentile line with its latency, in the first gap between the points on its
+// row so that no point is covered (see [drawWindow.addGapText]).
+func namePercentileLines(window *drawWindow, yAxis yAxis, f data.DurationFormat) {
+	for _, line := range yAxis.percentiles {
+		window.addGapText(line.row, yAxis.labelSize+1, " "+line.name+" "+f.Format(line.value)+" ", ansi.Gray)
+	}
+}
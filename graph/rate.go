@@ -0,0 +1,93 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package graph
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+
+	"github.com/Lexer747/AcciPing/gui"
+	"github.com/Lexer747/AcciPing/ping"
+)
+
+// minPingsPerMinute is the slowest rate which can be picked live, see [Graph.SetSwitchRate]. The fastest is
+// [ping.MaxPingsPerMinute], the same as can be given on the command line.
+const minPingsPerMinute = 1
+
+// SetSwitchRate enables the '[' and ']' keys, which halve and double the pings per minute, and the digit keys
+// which prompt for an exact pings per minute starting with that digit. The function is called to start pinging
+// at the new rate. Once the rate has been changed it's shown in the title next to the URL.
+func (g *Graph) SetSwitchRate(switchRate func(pingsPerMinute float64) error) {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.switchRate = switchRate
+}
+
+func (g *Graph) canSwitchRate() bool {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	return g.switchRate != nil
+}
+
+// changeRate switches to the pings per minute picked from the current rate, kept within the bounds. Switching
+// restarts the pinging so this is run off the terminal's input go-routine, the rateMutex makes one change at a
+// time so each starts from the rate the last one left.
+func (g *Graph) changeRate(pick func(current float64) float64) {
+	g.rateMutex.Lock()
+	defer g.rateMutex.Unlock()
+	g.dataMutex.Lock()
+	switchRate, current := g.switchRate, g.pingsPerMinute
+	g.dataMutex.Unlock()
+	from := current
+	if from == 0 {
+		// As fast as possible, halving or doubling it is meaningless so start from the default instead.
+		from = 60
+	}
+	pingsPerMinute := min(max(pick(from), minPingsPerMinute), ping.MaxPingsPerMinute)
+	if pingsPerMinute == current {
+		return
+	}
+	if err := switchRate(pingsPerMinute); err != nil {
+		slog.Error("couldn't switch rate", "pings_per_minute", pingsPerMinute, "err", err)
+		return
+	}
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	g.pingsPerMinute = pingsPerMinute
+	g.rateChanged = true
+	g.invalidateFrame()
+}
+
+// promptForRate is run off the terminal's input go-routine, so that the prompt can hear what's typed.
+func (g *Graph) promptForRate(ctx context.Context, first rune) {
+	defer g.overlay()()
+	text, err := gui.InputBox{
+		Prompt:  "Pings per minute (escape to cancel)",
+		Initial: string(first),
+		Layout:  g.promptLayout(),
+		ASCII:   g.promptASCII(),
+	}.Run(ctx, g.Term)
+	if err != nil || text == "" {
+		return
+	}
+	pingsPerMinute, err := strconv.ParseFloat(text, 64)
+	if err != nil || pingsPerMinute <= 0 {
+		slog.Warn("invalid pings per minute", "input", text)
+		return
+	}
+	g.changeRate(func(float64) float64 { return pingsPerMinute })
+}
+
+// title is the URL shown in the title, with the pings per minute once it's been changed live. Should be called
+// with the dataMutex held.
+func (g *Graph) title() string {
+	if !g.rateChanged {
+		return g.url
+	}
+	return g.url + " " + strconv.FormatFloat(g.pingsPerMinute, 'g', 4, 64) + "/min"
+}
@@ -0,0 +1,44 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package graph
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/gui"
+)
+
+// Reset throws away every point the graph has, so that it carries on from empty as if it had just started, e.g.
+// after a known bad period which would otherwise skew the axes and stats. The URL and time zone of the data are
+// kept. Only what's held in memory is reset, anything being written to a file is written from its own copy of
+// the pings so the file is unchanged and keeps every ping.
+func (g *Graph) Reset() {
+	g.dataMutex.Lock()
+	defer g.dataMutex.Unlock()
+	fresh := data.NewData(g.data.URL)
+	fresh.Location = g.data.Location
+	g.data = fresh
+	g.options.cursor = 0
+	g.invalidateFrame()
+}
+
+// promptForReset asks before calling [Graph.Reset] since there's no undo, it's run off the terminal's input
+// go-routine like [Graph.promptForURL].
+func (g *Graph) promptForReset(ctx context.Context) {
+	defer g.overlay()()
+	answer, err := gui.InputBox{
+		Prompt: "Clear the graph? The file is kept. Type y to confirm (escape to cancel)",
+		Layout: g.promptLayout(),
+		ASCII:  g.promptASCII(),
+	}.Run(ctx, g.Term)
+	if err != nil || !strings.EqualFold(strings.TrimSpace(answer), "y") {
+		return
+	}
+	g.Reset()
+}
@@ -0,0 +1,72 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package graph
+
+import (
+	"sync"
+
+	"github.com/Lexer747/AcciPing/ping"
+)
+
+// subscribers are the channels each point received by the graph is copied to, see [Graph.Subscribe].
+type subscribers struct {
+	m        sync.Mutex
+	channels map[chan ping.PingResults]struct{}
+	// closed is set once the graph stops receiving points, any later subscriber is given a closed channel.
+	closed bool
+}
+
+// Subscribe returns a channel which receives every point the graph receives from now on, in the same order,
+// so that the live data can be consumed elsewhere (e.g. mirrored to a web page). The channel has the buffer
+// size given, if a subscriber falls that far behind any further points are dropped for that subscriber rather
+// than slowing down the graph. The channel is closed when the returned unsubscribe function is called or once
+// the graph stops receiving points.
+func (g *Graph) Subscribe(buffer int) (<-chan ping.PingResults, func()) {
+	c := make(chan ping.PingResults, buffer)
+	s := &g.subscribers
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.closed {
+		close(c)
+		return c, func() {}
+	}
+	if s.channels == nil {
+		s.channels = map[chan ping.PingResults]struct{}{}
+	}
+	s.channels[c] = struct{}{}
+	return c, func() {
+		s.m.Lock()
+		defer s.m.Unlock()
+		if _, ok := s.channels[c]; ok {
+			delete(s.channels, c)
+			close(c)
+		}
+	}
+}
+
+// publish copies the point to every subscriber which has space for it.
+func (s *subscribers) publish(p ping.PingResults) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	for c := range s.channels {
+		select {
+		case c <- p:
+		default: // Too slow, drop it
+		}
+	}
+}
+
+// close closes every subscriber, no more points will be published.
+func (s *subscribers) close() {
+	s.m.Lock()
+	defer s.m.Unlock()
+	for c := range s.channels {
+		close(c)
+	}
+	s.channels = nil
+	s.closed = true
+}
@@ -0,0 +1,54 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package graph_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Lexer747/AcciPing/graph"
+	"github.com/Lexer747/AcciPing/graph/terminal/th"
+	"github.com/Lexer747/AcciPing/ping"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribe(t *testing.T) {
+	t.Parallel()
+	_, _, term, _, err := th.NewTestTerminal()
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	input := make(chan ping.PingResults)
+	g, err := graph.NewGraph(ctx, input, term, 0, "www.google.com")
+	require.NoError(t, err)
+
+	fast, unsubscribeFast := g.Subscribe(10)
+	slow, _ := g.Subscribe(1)
+	for i := range 3 {
+		input <- ping.PingResults{Data: ping.PingDataPoint{Duration: time.Duration(i+1) * time.Millisecond}}
+	}
+	require.Eventually(t, func() bool { return g.Size() == 3 }, time.Second, time.Millisecond)
+
+	for i := range 3 {
+		require.Equal(t, time.Duration(i+1)*time.Millisecond, (<-fast).Data.Duration)
+	}
+	require.Equal(t, time.Millisecond, (<-slow).Data.Duration, "the rest were dropped as the subscriber was full")
+	require.Empty(t, slow)
+
+	unsubscribeFast()
+	_, ok := <-fast
+	require.False(t, ok, "unsubscribing closes the channel")
+	unsubscribeFast() // Safe to call twice
+
+	close(input)
+	_, ok = <-slow
+	require.False(t, ok, "closed once the graph stops receiving points")
+	late, _ := g.Subscribe(1)
+	_, ok = <-late
+	require.False(t, ok)
+}
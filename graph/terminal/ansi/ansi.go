@@ -55,6 +55,14 @@ const (
 	R          = CSI + "0m"
 	HideCursor = CSI + "?25l"
 	ShowCursor = CSI + "?25h"
+
+	// BeginSync and EndSync are the "synchronized output" private mode (DEC 2026), a terminal which supports
+	// this will buffer everything written between the two and then display it atomically, preventing tearing.
+	// Terminals which don't support it should ignore it.
+	//
+	// https://gist.github.com/christianparpart/d8a62cc1ab659194337d73e399004036
+	BeginSync = CSI + "?2026h"
+	EndSync   = CSI + "?2026l"
 )
 
 // Compacted when defaults are passed, some chars may elided:
@@ -98,6 +106,12 @@ func Blue(s string) string    { return CSI + "94m" + s + R }
 func Magenta(s string) string { return CSI + "95m" + s + R }
 func Cyan(s string) string    { return CSI + "96m" + s + R }
 
+// RGB colours the string with a 24-bit "truecolor" foreground, not every terminal supports these so they
+// should be opt-in with one of the colours above as the fallback.
+func RGB(s string, r, g, b uint8) string {
+	return CSI + "38;2;" + strconv.Itoa(int(r)) + ";" + strconv.Itoa(int(g)) + ";" + strconv.Itoa(int(b)) + "m" + s + R
+}
+
 // Internal
 
 var s = strconv.Itoa
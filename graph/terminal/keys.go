@@ -0,0 +1,107 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package terminal
+
+import "unicode/utf8"
+
+// Keys which the terminal sends as an escape sequence rather than a single byte, they're given to a [Listener]
+// as a single rune from the unicode private use area so that they can't be mistaken for typed text.
+const (
+	KeyUp rune = 0xE000 + iota
+	KeyDown
+	KeyRight
+	KeyLeft
+	KeyPageUp
+	KeyPageDown
+	KeyHome
+	KeyEnd
+	KeyF1
+	KeyF2
+	KeyF3
+	KeyF4
+	KeyF5
+	KeyF6
+	KeyF7
+	KeyF8
+	KeyF9
+	KeyF10
+	KeyF11
+	KeyF12
+)
+
+var escapeSequences = map[string]rune{
+	"\x1b[A":   KeyUp,
+	"\x1b[B":   KeyDown,
+	"\x1b[C":   KeyRight,
+	"\x1b[D":   KeyLeft,
+	"\x1bOA":   KeyUp,
+	"\x1bOB":   KeyDown,
+	"\x1bOC":   KeyRight,
+	"\x1bOD":   KeyLeft,
+	"\x1b[5~":  KeyPageUp,
+	"\x1b[6~":  KeyPageDown,
+	"\x1b[H":   KeyHome,
+	"\x1b[F":   KeyEnd,
+	"\x1b[1~":  KeyHome,
+	"\x1b[4~":  KeyEnd,
+	"\x1bOP":   KeyF1,
+	"\x1bOQ":   KeyF2,
+	"\x1bOR":   KeyF3,
+	"\x1bOS":   KeyF4,
+	"\x1b[11~": KeyF1,
+	"\x1b[12~": KeyF2,
+	"\x1b[13~": KeyF3,
+	"\x1b[14~": KeyF4,
+	"\x1b[15~": KeyF5,
+	"\x1b[17~": KeyF6,
+	"\x1b[18~": KeyF7,
+	"\x1b[19~": KeyF8,
+	"\x1b[20~": KeyF9,
+	"\x1b[21~": KeyF10,
+	"\x1b[23~": KeyF11,
+	"\x1b[24~": KeyF12,
+}
+
+// decodeKeys splits a single read of input into the keys pressed. A read can hold more than one key when
+// typing quickly or pasting. Escape sequences for keys which aren't understood are skipped, rather than being
+// heard as an escape followed by text.
+func decodeKeys(input []byte) []rune {
+	var keys []rune
+	for len(input) > 0 {
+		if n := escapeLen(input); n > 0 {
+			if key, ok := escapeSequences[string(input[:n])]; ok {
+				keys = append(keys, key)
+			}
+			input = input[n:]
+			continue
+		}
+		r, size := utf8.DecodeRune(input)
+		keys = append(keys, r)
+		input = input[size:]
+	}
+	return keys
+}
+
+// escapeLen is the length of the escape sequence at the start of the input, zero if there isn't a complete
+// one. A CSI sequence ("\x1b[") ends with its first byte in the range '@' to '~', an SS3 sequence ("\x1bO")
+// is always a single byte more.
+func escapeLen(input []byte) int {
+	if len(input) < 3 || input[0] != '\x1b' {
+		return 0
+	}
+	switch input[1] {
+	case 'O':
+		return 3
+	case '[':
+		for i := 2; i < len(input); i++ {
+			if input[i] >= '@' && input[i] <= '~' {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
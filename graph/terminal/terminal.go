@@ -42,7 +42,11 @@ type Terminal struct {
 	// should be called if a panic occurs otherwise stacktraces are unreadable
 	cleanup func()
 
+	// listenMutex guards the listeners, the control C listener and the capture.
 	listenMutex *sync.Mutex
+	controlC    Listener
+	// capture when set receives every input instead of the listeners, except control C. See [Terminal.Capture].
+	capture *Listener
 }
 
 func NewTerminal() (*Terminal, error) {
@@ -69,7 +73,7 @@ type Listener struct {
 	// Name is used for if a listener errors for easier identification, it may be omitted.
 	Name string
 	// Applicable is the applicability of this listen, i.e. for which input runes do you want this action to
-	// be fired
+	// be fired. Keys which are sent as an escape sequence are a single rune, see [KeyUp].
 	Applicable func(rune) bool
 	// Action the callback which will be invoked when a user inputs the applicable rune, the rune passed is
 	// the same rune passed to applicable. Note the terminal size will have been updated before this called,
@@ -130,12 +134,42 @@ func (t *Terminal) StartRaw(ctx context.Context, stop context.CancelCauseFunc, l
 		Applicable: func(r rune) bool { return r == '\x03' },
 		Action:     ctrlCAction,
 	}
+	t.listenMutex.Lock()
+	t.controlC = controlCListener
 	t.listeners = slices.Concat(t.listeners, []Listener{controlCListener}, listeners)
+	t.listenMutex.Unlock()
 	t.Print(ansi.HideCursor)
 	go t.beingListening(ctx)
 	return t.cleanup, nil
 }
 
+// Capture temporarily routes all input to the listener, none of the listeners given to [Terminal.StartRaw]
+// will fire until the returned release function is called, except for the `ctrl+C` listener. This is for
+// widgets which take over the keyboard, like a text input. Only one capture can be held at a time, a second
+// capture replaces the first.
+func (t *Terminal) Capture(l Listener) (release func()) {
+	t.listenMutex.Lock()
+	defer t.listenMutex.Unlock()
+	t.capture = &l
+	return func() {
+		t.listenMutex.Lock()
+		defer t.listenMutex.Unlock()
+		if t.capture == &l {
+			t.capture = nil
+		}
+	}
+}
+
+// currentListeners are the listeners which should hear the next input.
+func (t *Terminal) currentListeners() []Listener {
+	t.listenMutex.Lock()
+	defer t.listenMutex.Unlock()
+	if t.capture != nil {
+		return []Listener{t.controlC, *t.capture}
+	}
+	return t.listeners
+}
+
 func (t *Terminal) ClearScreen(updateSize bool) error {
 	if updateSize {
 		if err := t.UpdateCurrentTerminalSize(); err != nil {
@@ -151,7 +185,13 @@ func (t *Terminal) Print(s string) error {
 	return err
 }
 
+// Write writes the bytes to the terminal in a single write. Callers should composite a whole frame before
+// writing it, each write is a syscall and partial frames can be seen by the user as flicker. An empty write is
+// skipped entirely.
 func (t *Terminal) Write(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
 	_, err := t.stdout.Write(b)
 	return err
 }
@@ -162,7 +202,8 @@ type listenResult struct {
 }
 
 func (t *Terminal) beingListening(ctx context.Context) {
-	buffer := make([]byte, 10)
+	// Big enough that an escape sequence is very unlikely to be split across reads, even when pasting.
+	buffer := make([]byte, 256)
 	// TODO should be buffered? Are we ok dropping inputs?
 	listenChannel := make(chan listenResult)
 	processingChannel := make(chan struct{})
@@ -191,16 +232,17 @@ func (t *Terminal) beingListening(ctx context.Context) {
 			if received.n <= 0 {
 				return // cancelled
 			}
-			r := rune(string(buffer[:received.n])[0])
-			// TODO pre-sort and order the listeners, then create a lookup instead of a linear search
-			// TODO document multiple valid listeners - especially ctrl-C interactions
-			for _, l := range t.listeners {
-				if !l.Applicable(r) {
-					continue
-				}
-				err := l.Action(r)
-				if err != nil {
-					panic(errors.Wrapf(err, "unexpected failure Action %q in terminal", l.Name))
+			for _, r := range decodeKeys(buffer[:received.n]) {
+				// TODO pre-sort and order the listeners, then create a lookup instead of a linear search
+				// TODO document multiple valid listeners - especially ctrl-C interactions
+				for _, l := range t.currentListeners() {
+					if !l.Applicable(r) {
+						continue
+					}
+					err := l.Action(r)
+					if err != nil {
+						panic(errors.Wrapf(err, "unexpected failure Action %q in terminal", l.Name))
+					}
 				}
 			}
 			// if we don't have the processing signal this clear would be racey against stdin.
@@ -81,8 +81,65 @@ func TestTerminalListener(t *testing.T) {
 	require.Equal(t, "c", c)
 }
 
+func TestTerminalSkipsEmptyWrites(t *testing.T) {
+	t.Parallel()
+	stdout := &countingWriter{}
+	term, err := terminal.NewTestTerminal(nil, stdout, func() terminal.Size { return terminal.Size{Height: 5, Width: 5} })
+	require.NoError(t, err)
+	require.NoError(t, term.Print(""))
+	require.NoError(t, term.Write(nil))
+	require.Equal(t, 0, stdout.writes)
+	require.NoError(t, term.Print("frame"))
+	require.Equal(t, 1, stdout.writes)
+}
+
+type countingWriter struct {
+	writes int
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	c.writes++
+	return len(b), nil
+}
+
 type testErr struct{}
 
 func (testErr) Error() string {
 	return "testErr"
 }
+
+func TestTerminalKeys(t *testing.T) {
+	t.Parallel()
+	stdin, _, term, _, err := th.NewTestTerminal()
+	require.NoError(t, err)
+	ctx, cancelFunc := context.WithCancelCause(context.Background())
+	defer cancelFunc(nil)
+	keys := make(chan rune, 16)
+	_, err = term.StartRaw(ctx, cancelFunc, terminal.Listener{
+		Applicable: func(rune) bool { return true },
+		Action: func(r rune) error {
+			keys <- r
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	for _, tc := range []struct {
+		input    string
+		expected []rune
+	}{
+		{input: "\x1b[A", expected: []rune{terminal.KeyUp}},
+		{input: "\x1bOB", expected: []rune{terminal.KeyDown}},
+		{input: "\x1b[6~", expected: []rune{terminal.KeyPageDown}},
+		{input: "\x1b[15~", expected: []rune{terminal.KeyF5}},
+		{input: "\x1b", expected: []rune{'\x1b'}},
+		{input: "\x1b[Zq", expected: []rune{'q'}}, // Not understood, so skipped
+		{input: "\x1b[1;5Cq", expected: []rune{'q'}},
+		{input: "ab\x1b[C\x1b[Cé", expected: []rune{'a', 'b', terminal.KeyRight, terminal.KeyRight, 'é'}},
+	} {
+		_, _ = stdin.Write([]byte(tc.input))
+		for _, expected := range tc.expected {
+			require.Equal(t, expected, <-keys, "%q", tc.input)
+		}
+	}
+	require.Empty(t, keys)
+}
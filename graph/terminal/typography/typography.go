@@ -6,19 +6,31 @@
 
 package typography
 
+import "strings"
+
 const (
 	Bullet       = "\u2022"
 	HollowBullet = "\u25E6"
 	Diamond      = "\u25C6"
 	Multiply     = "\u00D7"
+	Interrobang  = "\u203D"
+	Ellipsis     = "\u2026"
 
 	DownTriangle  = "\u25BC"
 	UpTriangle    = "\u25B2"
 	LeftTriangle  = "\u25C0"
 	RightTriangle = "\u25B6"
 
-	Vertical   = "\u2502"
-	Horizontal = "\u2500"
+	Vertical         = "\u2502"
+	Horizontal       = "\u2500"
+	DashedVertical   = "\u2506"
+	DottedVertical   = "\u250A"
+	DottedHorizontal = "\u2508"
+
+	TopLeftCorner     = "\u256D"
+	TopRightCorner    = "\u256E"
+	BottomLeftCorner  = "\u2570"
+	BottomRightCorner = "\u256F"
 
 	VerySteepUpSlope = "\u002F"
 	SteepUpSlope     = "\u2215"
@@ -48,4 +60,73 @@ const (
 	LowerLine  = "\u23BC"
 	UpperLine  = "\u23BB"
 	TopLine    = "\u23BA"
+
+	Mu    = "\u03BC"
+	Sigma = "\u03C3"
+	// Micro is the micro sign, which looks the same as [Mu] but is the one durations are formatted with, e.g.
+	// "µs".
+	Micro = "\u00B5"
+)
+
+// ascii is the fallback for every glyph, each is replaced with a single ASCII character so that the layout of
+// anything drawn is unchanged.
+var ascii = strings.NewReplacer(
+	Bullet, "*",
+	HollowBullet, "o",
+	Diamond, "+",
+	Multiply, "x",
+	Interrobang, "?",
+	Ellipsis, ".",
+
+	DownTriangle, "v",
+	UpTriangle, "^",
+	LeftTriangle, "<",
+	RightTriangle, ">",
+
+	Vertical, "|",
+	Horizontal, "-",
+	DashedVertical, ":",
+	DottedVertical, ".",
+	DottedHorizontal, ".",
+
+	TopLeftCorner, "+",
+	TopRightCorner, "+",
+	BottomLeftCorner, "+",
+	BottomRightCorner, "+",
+
+	SteepUpSlope, "/",
+	UpSlope, "/",
+	GentleUpSlope, "/",
+
+	DownSlope, "\\",
+	GentleDownSlope, "\\",
+
+	Block, "#",
+	LightBlock, ":",
+	MediumBlock, "%",
+	DarkBlock, "@",
+
+	BottomLeftSquare, ".",
+	TopLeftSquare, "'",
+	BottomRightSquare, ".",
+	TopRightSquare, "'",
+
+	UpperLeftQuadrantCircularArc, "|",
+	UpperRightQuadrantCircularArc, "/",
+	LowerRightQuadrantCircularArc, "-",
+	LowerLeftQuadrantCircularArc, "\\",
+
+	BottomLine, "_",
+	LowerLine, "_",
+	UpperLine, "-",
+	TopLine, "-",
+
+	Mu, "u",
+	Sigma, "s",
+	Micro, "u",
 )
+
+// ASCII replaces every glyph in s with an ASCII fallback, for terminals or fonts which can't draw them.
+func ASCII(s string) string {
+	return ascii.Replace(s)
+}
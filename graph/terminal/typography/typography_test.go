@@ -0,0 +1,19 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package typography_test
+
+import (
+	"testing"
+
+	"github.com/Lexer747/AcciPing/graph/terminal/typography"
+	"github.com/stretchr/testify/require"
+)
+
+func TestASCII(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, "u 12us s", typography.ASCII(typography.Mu+" 12µs "+typography.Sigma))
+}
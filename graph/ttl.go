@@ -0,0 +1,78 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package graph
+
+import (
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/graph/terminal"
+	"github.com/Lexer747/AcciPing/graph/terminal/ansi"
+	"github.com/Lexer747/AcciPing/graph/terminal/typography"
+)
+
+// minTTLLaneHeight is the shortest graph the TTL lane is drawn on (see [placeTTLLane]), on a shorter graph the
+// top row is better left to the points.
+const minTTLLaneHeight = 16
+
+// ttlColours are the colours of the TTL lane, each TTL is always the same colour so that a route change shows
+// as a change in colour.
+var ttlColours = [...]func(string) string{ansi.DarkCyan, ansi.DarkBlue, ansi.DarkMagenta, ansi.DarkGreen, ansi.DarkYellow}
+
+var ttlChange = ansi.Yellow(typography.Vertical)
+
+// placeTTLLane sets the lane along the top of the graph (see [drawWindow.setLane]) to a band coloured by the TTL
+// of the replies, a change in the TTL usually means the route to the destination changed so it's highlighted in
+// the first column after the last reply with the old TTL. Between two replies the band is the colour of the
+// earlier one. Nothing is drawn if no TTL was recorded, e.g. the data was captured before TTLs were recorded. The
+// canvas is nil unless the points are drawn in braille.
+func placeTTLLane(window *drawWindow, canvas *brailleCanvas, d *data.Data, s terminal.Size, labelSize int, reverse bool) {
+	if s.Height < minTTLLaneHeight {
+		return
+	}
+	set := func(column int, glyph string) {
+		if column < labelSize || column > s.Width || window.lane[column-1] == ttlChange ||
+			(canvas != nil && !canvas.empty(laneRow, column, column+1)) {
+			return
+		}
+		window.setLane(column, glyph)
+	}
+	var previous uint8
+	previousX := 0
+	for i := range d.TotalCount {
+		p := d.Get(i)
+		if p.TTL == 0 {
+			continue
+		}
+		x := getX(p.Timestamp, d.Header, s, labelSize, reverse)
+		if previous != 0 {
+			band := ttlBand(previous)
+			for column := min(previousX, x) + 1; column < max(previousX, x); column++ {
+				set(column, band)
+			}
+			if p.TTL != previous {
+				set(stepTowards(previousX, x), ttlChange)
+			}
+		}
+		set(x, ttlBand(p.TTL))
+		previous, previousX = p.TTL, x
+	}
+}
+
+func ttlBand(ttl uint8) string {
+	return ttlColours[int(ttl)%len(ttlColours)](typography.TopLine)
+}
+
+// stepTowards is the column next to from in the direction of to, or to if they're the same column.
+func stepTowards(from, to int) int {
+	switch {
+	case to > from:
+		return from + 1
+	case to < from:
+		return from - 1
+	default:
+		return to
+	}
+}
@@ -0,0 +1,256 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package graph
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Lexer747/AcciPing/graph/terminal"
+	"github.com/Lexer747/AcciPing/graph/terminal/ansi"
+)
+
+// drawWindow is every cell of the graph, the points are composited into it before being drawn so that many
+// points landing in the same cell are only drawn once, the last one written wins just as it would on the
+// terminal. The window is kept on the [Graph] and reset for each frame, so that the cells are re-used rather
+// than allocated for every frame.
+type drawWindow struct {
+	width, height int
+	cells         []string
+	// parts are the windows each worker draws into when the points are placed in parallel, they're also re-used
+	// between frames.
+	parts []drawWindow
+	// labels are drawn over the cells once every point is placed, see [drawWindow.addLabel].
+	labels []windowLabel
+	// gapTexts are drawn in the gaps left once the labels are drawn, see [drawWindow.addGapText].
+	gapTexts []windowLabel
+	// lane is the glyph of each column of the lane along the top row, drawn last in whichever cells are still
+	// empty, see [drawWindow.setLane].
+	lane []string
+}
+
+// windowLabel is a line of text drawn over the points, positioned like [drawWindow.setText].
+type windowLabel struct {
+	row, column int
+	text        string
+	colour      func(string) string
+	// point is the column of the labelled point on the row, which the label may cover. marker is drawn over
+	// just the point when there's no room for the text. Only set for labels, not gap texts.
+	point  int
+	marker string
+}
+
+// reset clears every cell and resizes the window for the frame.
+func (w *drawWindow) reset(s terminal.Size) {
+	size := s.Width * s.Height
+	if cap(w.cells) < size {
+		w.cells = make([]string, size)
+	} else {
+		w.cells = w.cells[:size]
+		clear(w.cells)
+	}
+	w.width, w.height = s.Width, s.Height
+	w.labels = w.labels[:0]
+	w.gapTexts = w.gapTexts[:0]
+	if cap(w.lane) < s.Width {
+		w.lane = make([]string, s.Width)
+	} else {
+		w.lane = w.lane[:s.Width]
+		clear(w.lane)
+	}
+}
+
+// set draws the glyph in the cell, positioned like [ansi.CursorPosition]. Cells outside the window are ignored.
+func (w *drawWindow) set(row, column int, glyph string) {
+	if row < 1 || row > w.height || column < 1 || column > w.width {
+		return
+	}
+	w.cells[(row-1)*w.width+column-1] = glyph
+}
+
+// setText draws each rune of the text into consecutive cells from the position.
+func (w *drawWindow) setText(row, column int, text string, colour func(string) string) {
+	// The terminal won't move the cursor before the first column, so neither does the window.
+	column = max(column, 1)
+	for _, r := range text {
+		w.set(row, column, colour(string(r)))
+		column++
+	}
+}
+
+// addLabel adds text for the point at the row and point column, to be drawn from the column once every point is
+// placed. A label only covers its own point, never another. When the text would cover another point or touch a
+// label added earlier just the marker is drawn over its point, failing that the text is nudged up or down a row,
+// or it's left out, so that every label drawn is readable. A nudged label would be on a row without its point, so
+// if the same text has already been placed it isn't nudged. Labels are kept off the first and last rows which
+// belong to the title and the x-axis.
+func (w *drawWindow) addLabel(row, column, point int, text, marker string, colour func(string) string) {
+	w.labels = append(w.labels, windowLabel{
+		row: row, column: max(column, 1), text: text, colour: colour, point: point, marker: marker,
+	})
+}
+
+// addGapText adds text to be drawn in the first gap of the row, at or right of the column, which is wide enough
+// for it once every point and label is drawn. Unlike a label it never covers anything, it's left out if there's
+// no gap.
+func (w *drawWindow) addGapText(row, column int, text string, colour func(string) string) {
+	w.gapTexts = append(w.gapTexts, windowLabel{row: row, column: max(column, 1), text: text, colour: colour})
+}
+
+// placeGapTexts draws each gap text in the first gap which fits it.
+func (w *drawWindow) placeGapTexts() {
+	for _, t := range w.gapTexts {
+		if t.row < 1 || t.row > w.height {
+			continue
+		}
+		width := utf8.RuneCountInString(t.text)
+		free := 0
+		for column := t.column; column <= w.width; column++ {
+			if w.cells[(t.row-1)*w.width+column-1] != "" {
+				free = 0
+				continue
+			}
+			free++
+			if free == width {
+				w.setText(t.row, column-width+1, t.text, t.colour)
+				break
+			}
+		}
+	}
+}
+
+// laneRow is the row of the lane, the top row of the graph below the title.
+const laneRow = 2
+
+// setLane draws the glyph in the column of the lane along the top of the graph. Unlike a cell the lane never
+// covers anything, it's only drawn where the top row is still empty once the labels and gap texts are drawn.
+func (w *drawWindow) setLane(column int, glyph string) {
+	if column < 1 || column > w.width {
+		return
+	}
+	w.lane[column-1] = glyph
+}
+
+// placeLane draws each column of the lane which is empty.
+func (w *drawWindow) placeLane() {
+	if w.height < laneRow {
+		return
+	}
+	for column, glyph := range w.lane {
+		if glyph != "" && w.empty(laneRow, column+1, column+2) {
+			w.set(laneRow, column+1, glyph)
+		}
+	}
+}
+
+// touches is true when the labels are on the same row with no space between them.
+func (l windowLabel) touches(other windowLabel) bool {
+	return l.row == other.row &&
+		l.column <= other.column+utf8.RuneCountInString(other.text) &&
+		other.column <= l.column+utf8.RuneCountInString(l.text)
+}
+
+// placeLabels draws every label which can be placed, in the order they were added.
+func (w *drawWindow) placeLabels() {
+	placed := make([]windowLabel, 0, len(w.labels))
+	for _, l := range w.labels {
+		up, down, marker := l, l, l
+		up.row--
+		down.row++
+		marker.column, marker.text = l.point, l.marker
+		for _, candidate := range [...]windowLabel{l, marker, up, down} {
+			if candidate.row != l.row && sameTextAs(l, placed) {
+				continue
+			}
+			if candidate.row < 2 || candidate.row > w.height-1 || touchesAny(candidate, placed) ||
+				w.coversPoint(candidate, l.row) {
+				continue
+			}
+			placed = append(placed, candidate)
+			w.setText(candidate.row, candidate.column, candidate.text, candidate.colour)
+			break
+		}
+	}
+}
+
+// coversPoint is true when the label would be drawn over anything other than its own point, which is on the row.
+func (w *drawWindow) coversPoint(l windowLabel, row int) bool {
+	column := l.column
+	for range utf8.RuneCountInString(l.text) {
+		own := l.row == row && column == l.point
+		if !own && !w.empty(l.row, column, column+1) {
+			return true
+		}
+		column++
+	}
+	return false
+}
+
+func sameTextAs(l windowLabel, others []windowLabel) bool {
+	for _, other := range others {
+		if l.text == other.text {
+			return true
+		}
+	}
+	return false
+}
+
+func touchesAny(l windowLabel, others []windowLabel) bool {
+	for _, other := range others {
+		if l.touches(other) {
+			return true
+		}
+	}
+	return false
+}
+
+// composite copies every cell which has been set in the other window over this one, they must be the same size.
+// The labels of the other window are added after those of this one.
+func (w *drawWindow) composite(other *drawWindow) {
+	for i, cell := range other.cells {
+		if cell != "" {
+			w.cells[i] = cell
+		}
+	}
+	w.labels = append(w.labels, other.labels...)
+}
+
+// empty is true when nothing is drawn in the columns [from, to) of the row, once the window has been drawn.
+func (w *drawWindow) empty(row, from, to int) bool {
+	if row < 1 || row > w.height {
+		return true
+	}
+	for column := max(from, 1); column < min(to, w.width+1); column++ {
+		if w.cells[(row-1)*w.width+column-1] != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// draw writes every cell which has been set in row then column order, so the same frame is always drawn the
+// same way. The labels are drawn over the cells first, then the gap texts and then the lane. The cursor is only
+// moved when there's a gap between cells.
+func (w *drawWindow) draw(b *strings.Builder) {
+	w.placeLabels()
+	w.placeGapTexts()
+	w.placeLane()
+	for row := 1; row <= w.height; row++ {
+		next := -1
+		for column := 1; column <= w.width; column++ {
+			cell := w.cells[(row-1)*w.width+column-1]
+			if cell == "" {
+				continue
+			}
+			if column != next {
+				b.WriteString(ansi.CursorPosition(row, column))
+			}
+			b.WriteString(cell)
+			next = column + 1
+		}
+	}
+}
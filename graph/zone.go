@@ -0,0 +1,83 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package graph
+
+import (
+	"time"
+
+	"github.com/Lexer747/AcciPing/utils/errors"
+)
+
+// ReadoutZone is the time zone the inspection cursor's readout is in, see [Graph.SetReadoutZone]. The readout
+// always ends the time with the zone's abbreviation so it's never ambiguous.
+type ReadoutZone int
+
+const (
+	// AxisZone is the zone the x-axis is drawn in, which is the local zone while capturing.
+	AxisZone ReadoutZone = iota
+	// LocalZone is the zone of this machine.
+	LocalZone
+	// UTCZone is UTC.
+	UTCZone
+	// FileZone is the zone the data was captured in (see [data.Data.Location]), or the local zone if that's not
+	// known.
+	FileZone
+)
+
+// ParseReadoutZone parses the name of [ReadoutZone], one of "axis", "local", "utc" or "file".
+func ParseReadoutZone(zone string) (ReadoutZone, error) {
+	switch zone {
+	case "axis":
+		return AxisZone, nil
+	case "local":
+		return LocalZone, nil
+	case "utc":
+		return UTCZone, nil
+	case "file":
+		return FileZone, nil
+	default:
+		return AxisZone, errors.Errorf("Unknown readout zone %q, expected one of: axis|local|utc|file", zone)
+	}
+}
+
+// next is the zone after this one, and then back round.
+func (z ReadoutZone) next() ReadoutZone {
+	return (z + 1) % (FileZone + 1)
+}
+
+// format writes the time in the zone with the layout, followed by the zone's abbreviation. The local and file
+// zones are also named, since their abbreviation alone doesn't say which was picked. fileZone is the zone the
+// data was captured in (see [loadFileZone]), nil if it's not known.
+func (z ReadoutZone) format(t time.Time, layout string, fileZone *time.Location) string {
+	suffix := ""
+	switch z {
+	case AxisZone:
+	case LocalZone:
+		t, suffix = t.In(time.Local), " (local)"
+	case UTCZone:
+		t = t.UTC()
+	case FileZone:
+		t, suffix = t.In(time.Local), " (local)"
+		if fileZone != nil {
+			t, suffix = t.In(fileZone), " (file)"
+		}
+	}
+	return t.Format(layout) + " " + t.Format("MST") + suffix
+}
+
+// loadFileZone loads the zone the data was captured in (see [data.Data.Location]), nil if it's not known or
+// can't be loaded. The zone is loaded once for the graph rather than for each frame.
+func loadFileZone(name string) *time.Location {
+	if name == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
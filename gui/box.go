@@ -0,0 +1,123 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package gui
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Lexer747/AcciPing/graph/terminal"
+	"github.com/Lexer747/AcciPing/graph/terminal/ansi"
+	"github.com/Lexer747/AcciPing/graph/terminal/typography"
+)
+
+// Box is a rectangle with a rounded border. The row and column are of the top left corner, 1-indexed like
+// [ansi.CursorPosition], the width and height include the border.
+type Box struct {
+	Row, Column   int
+	Width, Height int
+	// Title is drawn into the top border, cut short if the box isn't wide enough.
+	Title string
+	// Lines are the text drawn inside the box, padded by a space from the border. A line too long for the box is
+	// wrapped at the last space which fits, or cut if there isn't one. Lines which don't fit in the height are
+	// left out and the last line drawn ends in an ellipsis.
+	Lines []string
+}
+
+// CentredBox is a box of the size in the middle of the terminal, shrunk to fit if the terminal is smaller.
+func CentredBox(s terminal.Size, width, height int) Box {
+	width = min(width, s.Width)
+	height = min(height, s.Height)
+	return Box{
+		Row:    max((s.Height-height)/2, 0) + 1,
+		Column: max((s.Width-width)/2, 0) + 1,
+		Width:  width,
+		Height: height,
+	}
+}
+
+// Inner is the width of the space inside the border.
+func (b Box) Inner() int {
+	return max(b.Width-2, 0)
+}
+
+// Draw writes the border and blanks out the inside of the box, anything previously drawn there is covered.
+func (b Box) Draw(sb *strings.Builder) {
+	if b.Width < 2 || b.Height < 2 {
+		return
+	}
+	top := b.Title
+	if top != "" {
+		top = " " + top + " "
+	}
+	if utf8.RuneCountInString(top) > b.Inner() {
+		top = string([]rune(top)[:b.Inner()])
+	}
+	sb.WriteString(ansi.CursorPosition(b.Row, b.Column))
+	fill := b.Inner() - utf8.RuneCountInString(top)
+	sb.WriteString(typography.TopLeftCorner + ansi.Cyan(top) + strings.Repeat(typography.Horizontal, fill))
+	sb.WriteString(typography.TopRightCorner)
+	blank := strings.Repeat(" ", b.Inner())
+	for row := b.Row + 1; row < b.Row+b.Height-1; row++ {
+		sb.WriteString(ansi.CursorPosition(row, b.Column) + typography.Vertical + blank + typography.Vertical)
+	}
+	sb.WriteString(ansi.CursorPosition(b.Row+b.Height-1, b.Column))
+	sb.WriteString(typography.BottomLeftCorner + strings.Repeat(typography.Horizontal, b.Inner()) + typography.BottomRightCorner)
+	for i, line := range b.text() {
+		sb.WriteString(ansi.CursorPosition(b.Row+1+i, b.Column+2) + line)
+	}
+}
+
+// text is the lines wrapped to the width of the box and cut short to its height.
+func (b Box) text() []string {
+	width, height := b.Inner()-2, b.Height-2
+	if width < 1 || height < 1 {
+		return nil
+	}
+	lines := wrap(b.Lines, width)
+	if len(lines) <= height {
+		return lines
+	}
+	lines = lines[:height]
+	last := []rune(lines[height-1])
+	lines[height-1] = string(last[:min(len(last), width-1)]) + typography.Ellipsis
+	return lines
+}
+
+// wrap splits every line longer than the width, at the last space which fits or at the width if there is no
+// space.
+func wrap(lines []string, width int) []string {
+	if width < 1 {
+		return nil
+	}
+	ret := make([]string, 0, len(lines))
+	for _, line := range lines {
+		runes := []rune(line)
+		for len(runes) > width {
+			split := width
+			if space := lastSpace(runes[:width+1]); space > 0 {
+				split = space
+			}
+			ret = append(ret, strings.TrimRight(string(runes[:split]), " "))
+			runes = runes[split:]
+			for len(runes) > 0 && runes[0] == ' ' {
+				runes = runes[1:]
+			}
+		}
+		ret = append(ret, string(runes))
+	}
+	return ret
+}
+
+func lastSpace(runes []rune) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		if runes[i] == ' ' {
+			return i
+		}
+	}
+	return -1
+}
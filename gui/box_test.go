@@ -0,0 +1,169 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package gui_test
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/Lexer747/AcciPing/graph/terminal"
+	"github.com/Lexer747/AcciPing/graph/terminal/ansi"
+	"github.com/Lexer747/AcciPing/graph/terminal/typography"
+	"github.com/Lexer747/AcciPing/gui"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBox_wraps(t *testing.T) {
+	t.Parallel()
+	const long = `couldn't switch to "www.example.com" (DNS Query Failed) or go back to "www.google.com"`
+	cases := []struct {
+		Name     string
+		Width    int
+		Height   int
+		Expected []string
+	}{
+		{
+			Name:   "at the last space",
+			Width:  40,
+			Height: 5,
+			Expected: []string{
+				`couldn't switch to "www.example.com"`,
+				`(DNS Query Failed) or go back to`,
+				`"www.google.com"`,
+			},
+		},
+		{
+			Name:   "cut when there's no space",
+			Width:  14,
+			Height: 11,
+			Expected: []string{
+				`couldn't`,
+				`switch to`,
+				`"www.examp`,
+				`le.com"`,
+				`(DNS Query`,
+				`Failed) or`,
+				`go back to`,
+				`"www.googl`,
+				`e.com"`,
+			},
+		},
+		{
+			Name:   "ellipsis once out of height",
+			Width:  14,
+			Height: 4,
+			Expected: []string{
+				`couldn't`,
+				`switch to` + typography.Ellipsis,
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			t.Parallel()
+			box := gui.Box{Row: 1, Column: 1, Width: c.Width, Height: c.Height, Title: "Error", Lines: []string{long}}
+			out := draw(box)
+			for i, line := range c.Expected {
+				require.Contains(t, out, ansi.CursorPosition(box.Row+1+i, box.Column+2)+line+"\x1b", "line %d", i)
+				require.LessOrEqual(t, len([]rune(line)), box.Inner()-2)
+			}
+			require.NotContains(t, out, ansi.CursorPosition(box.Row+1+len(c.Expected), box.Column+2), "no more lines")
+		})
+	}
+}
+
+func TestBox_tooSmall(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, "\x1b", draw(gui.Box{Width: 1, Height: 5, Lines: []string{"text"}}))
+	out := draw(gui.Box{Row: 1, Column: 1, Width: 2, Height: 3, Lines: []string{"text"}})
+	require.NotContains(t, out, "t", "no room for any text")
+}
+
+func TestBox_title(t *testing.T) {
+	t.Parallel()
+	size := terminal.Size{Height: 3, Width: 8}
+	out := draw(gui.Box{Row: 1, Column: 1, Width: 8, Height: 3, Title: "µsµsµsµs"})
+	require.Equal(t, typography.TopLeftCorner+" µsµsµ"+typography.TopRightCorner, play(out, size)[0], "cut by rune")
+	out = draw(gui.Box{Row: 1, Column: 1, Width: 8, Height: 3, Title: "µs"})
+	require.Equal(t, typography.TopLeftCorner+" µs "+strings.Repeat(typography.Horizontal, 2)+typography.TopRightCorner,
+		play(out, size)[0], "filled by rune")
+}
+
+func draw(box gui.Box) string {
+	var b strings.Builder
+	box.Draw(&b)
+	// Terminate the output so that the last line can be matched exactly like the others.
+	return b.String() + "\x1b"
+}
+
+func TestBoxDrawing(t *testing.T) {
+	t.Parallel()
+	size := terminal.Size{Height: 8, Width: 30}
+	box := gui.CentredBox(size, 30, 5)
+	box.Title = "Help"
+	box.Lines = []string{"g toggles gradients", "o marks the longest outage", "u switches URL", "q quits"}
+	out := draw(box)
+	requireGolden(t, "testdata/box.frame", play(out, size))
+	requireGolden(t, "testdata/box-ascii.frame", play(typography.ASCII(out), size))
+}
+
+// requireGolden compares the frame to the golden file, on a difference the frame is written next to it with an
+// .actual suffix.
+func requireGolden(t *testing.T, golden string, frame []string) {
+	t.Helper()
+	expected, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	actual := strings.Join(frame, "\n")
+	if string(expected) != actual {
+		require.NoError(t, os.WriteFile(golden+".actual", []byte(actual), 0o666))
+		t.Fatalf("Diff in outputs see %s.actual", golden)
+	}
+}
+
+var csi = regexp.MustCompile(`^\x1b\[([\d;]*)([A-Za-z])`)
+
+// play draws the output of a box onto a blank screen, only the cursor position and colour sequences a box uses
+// are understood.
+func play(out string, size terminal.Size) []string {
+	screen := make([][]rune, size.Height)
+	for i := range screen {
+		screen[i] = []rune(strings.Repeat(" ", size.Width))
+	}
+	row, column := 1, 1
+	for len(out) > 0 {
+		if match := csi.FindStringSubmatch(out); match != nil {
+			out = out[len(match[0]):]
+			if match[2] == "H" {
+				row, column = 1, 1
+				params := strings.Split(match[1], ";")
+				if n, err := strconv.Atoi(params[0]); err == nil {
+					row = n
+				}
+				if len(params) > 1 {
+					column, _ = strconv.Atoi(params[1])
+				}
+			}
+			continue
+		}
+		r, n := utf8.DecodeRuneInString(out)
+		out = out[n:]
+		if r == '\x1b' {
+			continue // The terminator added by draw
+		}
+		screen[row-1][column-1] = r
+		column++
+	}
+	ret := make([]string, len(screen))
+	for i, line := range screen {
+		ret[i] = string(line)
+	}
+	return ret
+}
@@ -0,0 +1,127 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package gui
+
+import (
+	"context"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Lexer747/AcciPing/graph/terminal"
+	"github.com/Lexer747/AcciPing/graph/terminal/ansi"
+	"github.com/Lexer747/AcciPing/graph/terminal/typography"
+	"github.com/Lexer747/AcciPing/utils/errors"
+)
+
+// ErrInputCancelled is returned by [InputBox.Run] when the user presses escape.
+var ErrInputCancelled = errors.New("input cancelled")
+
+// InputBox is a single line text input drawn as a [Box] in the middle of the terminal.
+type InputBox struct {
+	// Prompt is the title of the box, telling the user what to type.
+	Prompt string
+	// Width of the box, zero for a default which fits the prompt.
+	Width int
+	// Initial is the text the input starts with.
+	Initial string
+	// Layout places the box, clear of anything already on the screen, nil for the middle of the terminal. See
+	// [Layout.Place], the box is placed in the centre once when Run starts.
+	Layout *Layout
+	// ASCII draws the box with only ASCII characters, see [typography.ASCII].
+	ASCII bool
+}
+
+const (
+	minInputWidth = 40
+	backspace     = '\x7f'
+	ctrlH         = '\b'
+	escape        = '\x1b'
+)
+
+// Run draws the input box and captures the keyboard (see [terminal.Terminal.Capture]) until the user presses
+// enter, returning the text, or escape, returning [ErrInputCancelled]. If the context is done first the cause
+// is returned. The terminal must already be started with [terminal.Terminal.StartRaw]. The box is left on the
+// screen, the caller should re-draw whatever was underneath. Run blocks until the input is finished, so it
+// must not be called directly from a [terminal.Listener] action, which would stop the terminal from reading
+// any more input.
+//
+// The keys the terminal decodes from escape sequences, like the arrow keys (see [terminal.KeyUp]), are ignored.
+func (ib InputBox) Run(ctx context.Context, t *terminal.Terminal) (string, error) {
+	// The text is only touched by the listener, which runs on the terminal's input go-routine.
+	text := []rune(ib.Initial)
+	finished := false
+	done := make(chan inputResult, 1)
+	box := ib.box(t.Size())
+	initial := ib.draw(box, text)
+	release := t.Capture(terminal.Listener{
+		Name:       "input " + ib.Prompt,
+		Applicable: func(rune) bool { return !finished },
+		Action: func(r rune) error {
+			switch {
+			case r == '\r' || r == '\n':
+				finished = true
+				done <- inputResult{text: string(text)}
+				return nil
+			case r == escape:
+				finished = true
+				done <- inputResult{err: ErrInputCancelled}
+				return nil
+			case r == backspace || r == ctrlH:
+				if len(text) > 0 {
+					text = text[:len(text)-1]
+				}
+			case unicode.IsPrint(r):
+				text = append(text, r)
+			default:
+				return nil // Some other control character, nothing changed
+			}
+			return t.Print(ib.draw(box, text))
+		},
+	})
+	defer release()
+	if err := t.Print(initial); err != nil {
+		return "", err
+	}
+	select {
+	case <-ctx.Done():
+		return "", context.Cause(ctx)
+	case result := <-done:
+		return result.text, result.err
+	}
+}
+
+type inputResult struct {
+	text string
+	err  error
+}
+
+func (ib InputBox) box(s terminal.Size) Box {
+	width := ib.Width
+	if width == 0 {
+		width = max(minInputWidth, utf8.RuneCountInString(ib.Prompt)+6)
+	}
+	return placeCentre(s, ib.Layout, width, 3)
+}
+
+// draw is the box with the text, when the text is too long only the end of it is shown.
+func (ib InputBox) draw(box Box, text []rune) string {
+	box.Title = ib.Prompt
+	var b strings.Builder
+	box.Draw(&b)
+	// One space of padding either side and one for the cursor.
+	space := box.Inner() - 3
+	if space <= 0 {
+		return b.String()
+	}
+	visible := text[max(len(text)-space, 0):]
+	b.WriteString(ansi.CursorPosition(box.Row+1, box.Column+2) + string(visible) + ansi.Gray("_"))
+	if ib.ASCII {
+		return typography.ASCII(b.String())
+	}
+	return b.String()
+}
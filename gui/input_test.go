@@ -0,0 +1,134 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package gui_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Lexer747/AcciPing/graph/terminal"
+	"github.com/Lexer747/AcciPing/graph/terminal/ansi"
+	"github.com/Lexer747/AcciPing/graph/terminal/th"
+	"github.com/Lexer747/AcciPing/gui"
+	"github.com/stretchr/testify/require"
+)
+
+type inputResult struct {
+	text string
+	err  error
+}
+
+// runInput types each of the keys into an input box, one at a time waiting for each to be drawn.
+func runInput(t *testing.T, keys string, listener terminal.Listener) inputResult {
+	t.Helper()
+	stdin, stdout, term, setTerm, err := th.NewTestTerminal()
+	require.NoError(t, err)
+	setTerm(terminal.Size{Height: 20, Width: 80})
+	ctx, cancelFunc := context.WithCancelCause(context.Background())
+	defer cancelFunc(nil)
+	_, err = term.StartRaw(ctx, cancelFunc, listener)
+	require.NoError(t, err)
+	_ = stdout.ReadString(t) // hide cursor
+
+	result := make(chan inputResult, 1)
+	go func() {
+		text, err := gui.InputBox{Prompt: "URL"}.Run(ctx, term)
+		result <- inputResult{text: text, err: err}
+	}()
+	require.Contains(t, readFrame(t, stdout), " URL ")
+	for i, key := range []byte(keys) {
+		_, _ = stdin.Write([]byte{key})
+		if i < len(keys)-1 {
+			_ = readFrame(t, stdout) // wait for the re-draw
+		}
+	}
+	return <-result
+}
+
+// readFrame blocks until the box is drawn, it's too large for [th.TestFile.ReadString].
+func readFrame(t *testing.T, stdout *th.TestFile) string {
+	t.Helper()
+	buffer := make([]byte, 4096)
+	n, err := stdout.Read(buffer)
+	require.NoError(t, err)
+	return string(buffer[:n])
+}
+
+func TestInputBox(t *testing.T) {
+	t.Parallel()
+	heard := false
+	listener := terminal.Listener{
+		Applicable: func(r rune) bool { return true },
+		Action: func(rune) error {
+			heard = true
+			return nil
+		},
+	}
+	result := runInput(t, "wwx\x7fw.google.com\r", listener)
+	require.NoError(t, result.err)
+	require.Equal(t, "www.google.com", result.text)
+	require.False(t, heard, "input is captured away from the other listeners")
+}
+
+func TestInputBox_escape(t *testing.T) {
+	t.Parallel()
+	result := runInput(t, "abc\x1b", terminal.Listener{Applicable: func(rune) bool { return false }})
+	require.ErrorIs(t, result.err, gui.ErrInputCancelled)
+}
+
+func TestInputBox_layout(t *testing.T) {
+	t.Parallel()
+	stdin, stdout, term, setTerm, err := th.NewTestTerminal()
+	require.NoError(t, err)
+	size := terminal.Size{Height: 20, Width: 80}
+	setTerm(size)
+	ctx, cancelFunc := context.WithCancelCause(context.Background())
+	defer cancelFunc(nil)
+	_, err = term.StartRaw(ctx, cancelFunc)
+	require.NoError(t, err)
+	_ = stdout.ReadString(t) // hide cursor
+
+	l := gui.NewLayout(size)
+	panel, ok := l.Place(gui.TopRight, 26, 12)
+	require.True(t, ok)
+	result := make(chan error, 1)
+	go func() {
+		_, err := gui.InputBox{Prompt: "URL", Layout: l}.Run(ctx, term)
+		result <- err
+	}()
+	drawn := readFrame(t, stdout)
+	expected := gui.Box{Row: panel.Row + panel.Height, Column: 21, Width: 40, Height: 3}
+	require.False(t, expected.Overlaps(panel))
+	require.Contains(t, drawn, ansi.CursorPosition(expected.Row, expected.Column), "placed below the panel")
+	_, _ = stdin.Write([]byte("\x1b"))
+	require.ErrorIs(t, <-result, gui.ErrInputCancelled)
+}
+
+func TestInputBox_ascii(t *testing.T) {
+	t.Parallel()
+	stdin, stdout, term, setTerm, err := th.NewTestTerminal()
+	require.NoError(t, err)
+	setTerm(terminal.Size{Height: 20, Width: 80})
+	ctx, cancelFunc := context.WithCancelCause(context.Background())
+	defer cancelFunc(nil)
+	_, err = term.StartRaw(ctx, cancelFunc)
+	require.NoError(t, err)
+	_ = stdout.ReadString(t) // hide cursor
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := gui.InputBox{Prompt: "Latency in µs", ASCII: true}.Run(ctx, term)
+		result <- err
+	}()
+	drawn := readFrame(t, stdout)
+	require.Contains(t, drawn, " Latency in us ")
+	for _, r := range drawn {
+		require.Less(t, r, rune(0x80), "%q isn't ASCII", r)
+	}
+	_, _ = stdin.Write([]byte("\x1b"))
+	require.ErrorIs(t, <-result, gui.ErrInputCancelled)
+}
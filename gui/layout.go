@@ -0,0 +1,105 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package gui
+
+import (
+	"github.com/Lexer747/AcciPing/graph/terminal"
+)
+
+// Anchor is the part of the terminal a box is placed against, see [Layout.Place].
+type Anchor int
+
+const (
+	Centre Anchor = iota
+	TopLeft
+	TopRight
+	BottomLeft
+	BottomRight
+)
+
+// Layout places boxes on the terminal so that none of them overlap. Boxes placed at the same anchor stack up
+// away from the edge they're anchored to (down from the top or the centre, up from the bottom), in the order
+// they're placed. A layout is for a single frame, make a new one for each frame.
+type Layout struct {
+	s      terminal.Size
+	placed []Box
+}
+
+// NewLayout is an empty layout of the terminal.
+func NewLayout(s terminal.Size) *Layout {
+	return &Layout{s: s}
+}
+
+// Reserve marks the box as taken, so that nothing placed later will overlap it. For boxes positioned some other
+// way.
+func (l *Layout) Reserve(b Box) {
+	l.placed = append(l.placed, b)
+}
+
+// Place positions a box of the size at the anchor, moved past any box it would overlap. The box is shrunk to
+// fit the terminal like [CentredBox]. False is returned if there's no space left for it, in which case nothing
+// is reserved.
+func (l *Layout) Place(a Anchor, width, height int) (Box, bool) {
+	width = min(width, l.s.Width)
+	height = min(height, l.s.Height)
+	b := CentredBox(l.s, width, height)
+	switch a {
+	case TopLeft, BottomLeft:
+		b.Column = 1
+	case TopRight, BottomRight:
+		b.Column = l.s.Width - width + 1
+	}
+	switch a {
+	case TopLeft, TopRight:
+		b.Row = 1
+	case BottomLeft, BottomRight:
+		b.Row = l.s.Height - height + 1
+	}
+	up := a == BottomLeft || a == BottomRight
+	for {
+		other, overlaps := l.overlapping(b)
+		if !overlaps {
+			break
+		}
+		if up {
+			b.Row = other.Row - height
+		} else {
+			b.Row = other.Row + other.Height
+		}
+		if b.Row < 1 || b.Row+height-1 > l.s.Height {
+			return Box{}, false
+		}
+	}
+	l.Reserve(b)
+	return b, true
+}
+
+// placeCentre places a box of the size in the centre of the layout, or if there's no layout or no space left in
+// it, in the middle of the terminal regardless of what's there.
+func placeCentre(s terminal.Size, l *Layout, width, height int) Box {
+	if l != nil {
+		if b, ok := l.Place(Centre, width, height); ok {
+			return b
+		}
+	}
+	return CentredBox(s, width, height)
+}
+
+func (l *Layout) overlapping(b Box) (Box, bool) {
+	for _, other := range l.placed {
+		if b.Overlaps(other) {
+			return other, true
+		}
+	}
+	return Box{}, false
+}
+
+// Overlaps is true when any cell of the box is also a cell of the other box.
+func (b Box) Overlaps(other Box) bool {
+	return b.Row < other.Row+other.Height && other.Row < b.Row+b.Height &&
+		b.Column < other.Column+other.Width && other.Column < b.Column+b.Width
+}
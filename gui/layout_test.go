@@ -0,0 +1,73 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package gui_test
+
+import (
+	"testing"
+
+	"github.com/Lexer747/AcciPing/graph/terminal"
+	"github.com/Lexer747/AcciPing/gui"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayout(t *testing.T) {
+	t.Parallel()
+	l := gui.NewLayout(terminal.Size{Height: 20, Width: 80})
+
+	panel, ok := l.Place(gui.TopRight, 26, 10)
+	require.True(t, ok)
+	require.Equal(t, gui.Box{Row: 1, Column: 55, Width: 26, Height: 10}, panel)
+
+	help, ok := l.Place(gui.BottomRight, 20, 8)
+	require.True(t, ok)
+	require.Equal(t, gui.Box{Row: 13, Column: 61, Width: 20, Height: 8}, help)
+
+	toast, ok := l.Place(gui.Centre, 40, 3)
+	require.True(t, ok)
+	require.Equal(t, gui.Box{Row: 11, Column: 21, Width: 40, Height: 3}, toast, "moved down below the panel")
+
+	second, ok := l.Place(gui.Centre, 40, 3)
+	require.True(t, ok)
+	require.Equal(t, gui.Box{Row: 14, Column: 21, Width: 40, Height: 3}, second, "stacked below the first toast")
+
+	_, ok = l.Place(gui.Centre, 40, 3)
+	require.True(t, ok)
+	_, ok = l.Place(gui.Centre, 40, 3)
+	require.False(t, ok, "no space left below the other toasts")
+
+	placed := []gui.Box{panel, help, toast, second}
+	for i, a := range placed {
+		for _, b := range placed[i+1:] {
+			require.False(t, a.Overlaps(b), "%+v overlaps %+v", a, b)
+		}
+	}
+}
+
+func TestLayout_bottomStacksUp(t *testing.T) {
+	t.Parallel()
+	l := gui.NewLayout(terminal.Size{Height: 10, Width: 40})
+	l.Reserve(gui.Box{Row: 1, Column: 1, Width: 40, Height: 2})
+	first, ok := l.Place(gui.BottomLeft, 20, 3)
+	require.True(t, ok)
+	require.Equal(t, gui.Box{Row: 8, Column: 1, Width: 20, Height: 3}, first)
+	second, ok := l.Place(gui.BottomLeft, 20, 3)
+	require.True(t, ok)
+	require.Equal(t, gui.Box{Row: 5, Column: 1, Width: 20, Height: 3}, second)
+	_, ok = l.Place(gui.BottomLeft, 20, 3)
+	require.False(t, ok, "would overlap the reserved box at the top")
+	big, ok := l.Place(gui.TopRight, 100, 100)
+	require.False(t, ok, "shrunk to the size of the terminal, which overlaps the reserved box")
+	require.Equal(t, gui.Box{}, big)
+}
+
+func TestBox_Overlaps(t *testing.T) {
+	t.Parallel()
+	a := gui.Box{Row: 1, Column: 1, Width: 10, Height: 5}
+	require.True(t, a.Overlaps(gui.Box{Row: 5, Column: 10, Width: 3, Height: 3}), "sharing the corner cell")
+	require.False(t, a.Overlaps(gui.Box{Row: 6, Column: 1, Width: 10, Height: 5}), "directly below")
+	require.False(t, a.Overlaps(gui.Box{Row: 1, Column: 11, Width: 10, Height: 5}), "directly to the right")
+}
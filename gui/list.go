@@ -0,0 +1,124 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package gui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Lexer747/AcciPing/graph/terminal"
+	"github.com/Lexer747/AcciPing/graph/terminal/typography"
+)
+
+// ListBox is a scrollable list of lines drawn as a [Box] in the middle of the terminal.
+type ListBox struct {
+	// Title of the box, the position in the list is added after it.
+	Title string
+	Lines []string
+	// Width and Height of the box, zero for a default which fits the lines in the terminal.
+	Width, Height int
+	// Layout places the box like [InputBox.Layout], nil for the middle of the terminal.
+	Layout *Layout
+	// ASCII draws the box with only ASCII characters, like [InputBox.ASCII].
+	ASCII bool
+}
+
+const (
+	minListWidth  = 40
+	maxListHeight = 20
+)
+
+// Run draws the list and captures the keyboard (see [terminal.Terminal.Capture]) until the user presses
+// escape or 'q'. The list is scrolled by the arrow keys, 'j' and 'k', page up and page down, home and end. If
+// the context is done first the cause is returned. Like [InputBox.Run] the box is left on the screen and Run
+// must not be called directly from a [terminal.Listener] action.
+func (lb ListBox) Run(ctx context.Context, t *terminal.Terminal) error {
+	// The offset is only touched by the listener, which runs on the terminal's input go-routine.
+	offset := 0
+	finished := false
+	done := make(chan struct{})
+	box := lb.box(t.Size())
+	initial := lb.draw(box, offset)
+	release := t.Capture(terminal.Listener{
+		Name:       "list " + lb.Title,
+		Applicable: func(rune) bool { return !finished },
+		Action: func(r rune) error {
+			page := max(box.Height-2, 1)
+			last := max(len(lb.Lines)-page, 0)
+			switch r {
+			case escape, 'q':
+				finished = true
+				close(done)
+				return nil
+			case terminal.KeyUp, 'k':
+				offset--
+			case terminal.KeyDown, 'j':
+				offset++
+			case terminal.KeyPageUp:
+				offset -= page
+			case terminal.KeyPageDown:
+				offset += page
+			case terminal.KeyHome:
+				offset = 0
+			case terminal.KeyEnd:
+				offset = last
+			default:
+				return nil
+			}
+			offset = min(max(offset, 0), last)
+			return t.Print(lb.draw(box, offset))
+		},
+	})
+	defer release()
+	if err := t.Print(initial); err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-done:
+		return nil
+	}
+}
+
+func (lb ListBox) box(s terminal.Size) Box {
+	width, height := lb.Width, lb.Height
+	if width == 0 {
+		width = max(minListWidth, utf8.RuneCountInString(lb.Title)+16)
+		for _, line := range lb.Lines {
+			width = max(width, len([]rune(line))+4)
+		}
+	}
+	if height == 0 {
+		height = min(len(lb.Lines), maxListHeight) + 2
+	}
+	return placeCentre(s, lb.Layout, width, height)
+}
+
+// draw is the box with the page of lines starting at the offset, lines too long for the box are cut short.
+func (lb ListBox) draw(box Box, offset int) string {
+	rows, width := box.Height-2, box.Inner()-2
+	end := min(offset+max(rows, 0), len(lb.Lines))
+	box.Title = lb.Title
+	if len(lb.Lines) > 0 {
+		box.Title += fmt.Sprintf(" %d-%d of %d", offset+1, end, len(lb.Lines))
+	}
+	for _, line := range lb.Lines[offset:end] {
+		if runes := []rune(line); width > 0 && len(runes) > width {
+			line = string(runes[:width-1]) + typography.Ellipsis
+		}
+		box.Lines = append(box.Lines, line)
+	}
+	var b strings.Builder
+	box.Draw(&b)
+	if lb.ASCII {
+		return typography.ASCII(b.String())
+	}
+	return b.String()
+}
@@ -0,0 +1,81 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package gui_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Lexer747/AcciPing/graph/terminal"
+	"github.com/Lexer747/AcciPing/graph/terminal/ansi"
+	"github.com/Lexer747/AcciPing/graph/terminal/th"
+	"github.com/Lexer747/AcciPing/gui"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListBox(t *testing.T) {
+	t.Parallel()
+	stdin, stdout, term, setTerm, err := th.NewTestTerminal()
+	require.NoError(t, err)
+	setTerm(terminal.Size{Height: 20, Width: 80})
+	ctx, cancelFunc := context.WithCancelCause(context.Background())
+	defer cancelFunc(nil)
+	_, err = term.StartRaw(ctx, cancelFunc)
+	require.NoError(t, err)
+	_ = stdout.ReadString(t) // hide cursor
+
+	lines := make([]string, 30)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+	result := make(chan error, 1)
+	go func() {
+		result <- gui.ListBox{Title: "Drops", Lines: lines, Height: 7}.Run(ctx, term)
+	}()
+	frame := readFrame(t, stdout)
+	require.Contains(t, frame, " Drops 1-5 of 30 ")
+	require.Contains(t, frame, "line 5")
+	require.NotContains(t, frame, "line 6")
+
+	press := func(key string) string {
+		_, _ = stdin.Write([]byte(key))
+		return readFrame(t, stdout)
+	}
+	require.Contains(t, press("\x1b[B"), " Drops 2-6 of 30 ")
+	require.Contains(t, press("j"), " Drops 3-7 of 30 ")
+	require.Contains(t, press("\x1b[6~"), " Drops 8-12 of 30 ")
+	require.Contains(t, press("\x1b[A"), " Drops 7-11 of 30 ")
+	frame = press("\x1b[F")
+	require.Contains(t, frame, " Drops 26-30 of 30 ")
+	require.Contains(t, frame, "line 30")
+	require.Contains(t, press("j"), " Drops 26-30 of 30 ", "can't scroll past the end")
+	require.Contains(t, press("\x1b[H"), " Drops 1-5 of 30 ")
+	require.Contains(t, press("k"), " Drops 1-5 of 30 ", "can't scroll before the start")
+	_, _ = stdin.Write([]byte("q"))
+	require.NoError(t, <-result)
+}
+
+func TestListBox_cutsLongLines(t *testing.T) {
+	t.Parallel()
+	stdin, stdout, term, setTerm, err := th.NewTestTerminal()
+	require.NoError(t, err)
+	setTerm(terminal.Size{Height: 10, Width: 20})
+	ctx, cancelFunc := context.WithCancelCause(context.Background())
+	defer cancelFunc(nil)
+	_, err = term.StartRaw(ctx, cancelFunc)
+	require.NoError(t, err)
+	_ = stdout.ReadString(t) // hide cursor
+
+	result := make(chan error, 1)
+	go func() {
+		result <- gui.ListBox{Title: "Drops", Lines: []string{"a line much longer than the terminal"}}.Run(ctx, term)
+	}()
+	require.Contains(t, readFrame(t, stdout), ansi.CursorPosition(5, 3)+"a line much lon…")
+	_, _ = stdin.Write([]byte("\x1b"))
+	require.NoError(t, <-result)
+}
@@ -0,0 +1,75 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package ping
+
+import (
+	"time"
+)
+
+// AdaptiveRate speeds up a capture while the link is misbehaving so that the event is recorded in detail, then
+// ramps back down to the normal rate once it has recovered. See [Ping.UseAdaptiveRate].
+type AdaptiveRate struct {
+	// PingsPerMinute is the rate while an event is happening, it should be faster than the normal rate.
+	PingsPerMinute float64
+	// SpikeThreshold is the latency above which a good packet is an event, zero for only dropped packets.
+	SpikeThreshold time.Duration
+	// Cooldown is how many healthy pings in a row end an event, after which the time between pings doubles
+	// with each healthy ping until it's back to the normal rate.
+	Cooldown int
+}
+
+// UseAdaptiveRate makes [Ping.CreateChannel] ping at the faster rate whenever a packet is dropped or is slower
+// than the spike threshold, the pings per minute given to the channel is the normal rate. The channel must
+// have a rate, adapting "as fast as possible" isn't meaningful.
+func (p *Ping) UseAdaptiveRate(rate AdaptiveRate) {
+	p.adaptive = &rate
+}
+
+// rateController adjusts the interval of the rate limiting ticker as each result is observed.
+type rateController struct {
+	AdaptiveRate
+	normal, fast, current time.Duration
+	healthy               int
+	ticker                *time.Ticker
+}
+
+func newRateController(rate AdaptiveRate, pingsPerMinute float64, ticker *time.Ticker) *rateController {
+	normal := PingsPerMinuteToDuration(pingsPerMinute)
+	return &rateController{
+		AdaptiveRate: rate,
+		normal:       normal,
+		fast:         min(PingsPerMinuteToDuration(rate.PingsPerMinute), normal),
+		current:      normal,
+		ticker:       ticker,
+	}
+}
+
+// observe updates the ticker for the result of the last ping.
+func (r *rateController) observe(result PingResults) {
+	if next := r.next(result); next != r.current {
+		r.current = next
+		r.ticker.Reset(next)
+	}
+}
+
+// next is the interval to use after the result.
+func (r *rateController) next(result PingResults) time.Duration {
+	if result.Data.InternalError() {
+		// Not a problem with the link, nothing to capture in detail.
+		return r.current
+	}
+	spike := r.SpikeThreshold > 0 && result.Data.Duration > r.SpikeThreshold
+	if result.Data.Dropped() || spike {
+		r.healthy = 0
+		return r.fast
+	}
+	r.healthy++
+	if r.current == r.normal || r.healthy <= r.Cooldown {
+		return r.current
+	}
+	return min(r.current*2, r.normal)
+}
@@ -0,0 +1,56 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package ping
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Lexer747/AcciPing/utils/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateController(t *testing.T) {
+	t.Parallel()
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	r := newRateController(AdaptiveRate{PingsPerMinute: 60, SpikeThreshold: 200 * time.Millisecond, Cooldown: 2}, 1, ticker)
+	good := goodPacket(nil, 10*time.Millisecond, time.Time{})
+	spike := goodPacket(nil, time.Second, time.Time{})
+	dropped := packetLoss(nil, time.Time{}, Timeout)
+	internal := internalErr(nil, time.Time{}, errors.New("no socket"))
+
+	steps := []struct {
+		result   PingResults
+		expected time.Duration
+	}{
+		{good, time.Minute},
+		{internal, time.Minute},
+		{dropped, time.Second},
+		{good, time.Second},
+		{good, time.Second},
+		{spike, time.Second},
+		{good, time.Second},
+		{good, time.Second},
+		{good, 2 * time.Second},
+		{good, 4 * time.Second},
+		{dropped, time.Second},
+		{good, time.Second},
+		{good, time.Second},
+		{good, 2 * time.Second},
+		{good, 4 * time.Second},
+		{good, 8 * time.Second},
+		{good, 16 * time.Second},
+		{good, 32 * time.Second},
+		{good, time.Minute},
+		{good, time.Minute},
+	}
+	for i, step := range steps {
+		r.observe(step.result)
+		require.Equal(t, step.expected, r.current, "step %d", i)
+	}
+}
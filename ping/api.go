@@ -9,11 +9,13 @@ package ping
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"math"
 	"net"
 	"os"
 	"time"
 
+	"github.com/Lexer747/AcciPing/utils/backoff"
 	"github.com/Lexer747/AcciPing/utils/bytes"
 	"github.com/Lexer747/AcciPing/utils/errors"
 
@@ -29,16 +31,55 @@ type Ping struct {
 
 	dnsCacheTrust uint
 	addresses     *queryCache
+
+	// monotonic when set will derive every timestamp from a single wall clock reading taken when the channel
+	// starts, advanced only by the monotonic clock. See [Ping.UseMonotonicClock].
+	monotonic bool
+	// recordOutOfOrder when set will report every reply received for a previous probe on the channel. See
+	// [Ping.RecordOutOfOrder].
+	recordOutOfOrder bool
+	// adaptive when set speeds up the rate of pings during events. See [Ping.UseAdaptiveRate].
+	adaptive *AdaptiveRate
+	// source is the local address and interface the pings are sent from. See [Ping.UseSourceAddress] and
+	// [Ping.UseInterface].
+	source source
+	// tos when non zero is the type of service byte of every echo request. See [Ping.UseTOS].
+	tos int
 }
 
+// DNSCacheTrust controls how many dropped packets each IP address returned from a DNS query is allowed before
+// the address is considered stale. Every time a ping to an address fails [queryCache.Dropped] is called for
+// that address, once the address has dropped more packets than the trust allows it is marked stale and will
+// no longer be used. When every address for a URL is stale a fresh DNS query is made. A higher trust is
+// better suited to flaky links where packets are dropped regardless of which address is used, a lower trust
+// reacts quickest to a service rotating it's addresses.
+//
+//   - [LowTrust] an address is stale after a single drop.
+//   - [NominalTrust] an address is stale after two drops.
+//   - [HighTrust] an address is stale after six drops.
 type DNSCacheTrust string
 
 const (
-	LowTrust     = "Low Trust"
-	NominalTrust = "Nominal Trust"
-	HighTrust    = "High Trust"
+	LowTrust     DNSCacheTrust = "Low Trust"
+	NominalTrust DNSCacheTrust = "Nominal Trust"
+	HighTrust    DNSCacheTrust = "High Trust"
 )
 
+// ParseDNSCacheTrust parses the short user facing name of a [DNSCacheTrust], one of "low", "nominal" or
+// "high".
+func ParseDNSCacheTrust(trust string) (DNSCacheTrust, error) {
+	switch trust {
+	case "low":
+		return LowTrust, nil
+	case "nominal":
+		return NominalTrust, nil
+	case "high":
+		return HighTrust, nil
+	default:
+		return "", errors.Errorf("Unknown DNS cache trust %q, expected one of: low|nominal|high", trust)
+	}
+}
+
 func (p *Ping) LastIP() string {
 	if p.addresses == nil {
 		return "<IP NOT YET FOUND>"
@@ -59,63 +100,74 @@ func NewPingWithTrust(trust DNSCacheTrust) *Ping {
 	}
 }
 
-func (p *Ping) OneShot(url string) (time.Duration, error) {
-	// first get the ip for a given url
-	cache, err := IPv4DNSQuery(url, p.dnsCacheTrust)
-	if err != nil {
-		return 0, err
-	}
-	// Don't handle this [!ok] case in OneShot
-	selectedIP, _ := cache.Get()
-
-	// Create a listener for the IP we will use
-	closer, err := p.startListening(url)
-	defer closer()
-	if err != nil {
-		return 0, err
-	}
-
-	raw, err := p.makeOutgoingPacket(1)
-	if err != nil {
-		return 0, errors.Wrapf(err, "couldn't create outgoing %q packet", url)
-	}
+// UseMonotonicClock switches the capture into a monotonic mode, all timestamps produced by [Ping.CreateChannel]
+// will be based on the wall clock time when the channel started plus the elapsed time measured by the
+// monotonic clock. This means that wall clock adjustments (NTP, DST, manual changes) during a capture cannot
+// cause the timestamps to jump or go backwards, so the gaps between consecutive points are always accurate and
+// the timestamps are guaranteed to be in non-decreasing order. The trade-off is that over a very long capture
+// the timestamps may slowly drift from the "real" wall clock.
+//
+// Note that the durations of each ping are always measured using the monotonic clock regardless of this mode.
+func (p *Ping) UseMonotonicClock() {
+	p.monotonic = true
+}
 
-	// Actually write the echo request onto the connection:
-	if err = p.writeEcho(selectedIP, raw); err != nil {
-		return 0, err
-	}
-	begin := time.Now()
+// RecordOutOfOrder makes [Ping.CreateChannel] report each reply which arrives for an earlier probe (one that
+// already timed out) as its own result with the [OutOfOrder] drop reason. Such replies are never used as the
+// round trip time of the current probe, without this option they are silently discarded. This is most useful
+// on lossy links where replies can arrive after the next probe has already been sent. These results are marked
+// on the graph but aren't counted as more packet loss, the probe they answer was already counted when it timed
+// out.
+func (p *Ping) RecordOutOfOrder() {
+	p.recordOutOfOrder = true
+}
 
-	// Now wait for the result
-	buffer := make([]byte, 255)
-	timeoutCtx, _ := context.WithTimeoutCause(context.Background(), time.Second, pingTimeout{Duration: time.Second})
-	n, err := p.pingRead(timeoutCtx, buffer)
-	duration := time.Since(begin)
-	if err != nil {
-		return duration, errors.Wrapf(err, "couldn't read packet from %q", url)
-	}
-	received, err := icmp.ParseMessage(protocolICMP, buffer[:n])
-	if err != nil {
-		return duration, errors.Wrapf(err, "couldn't parse raw packet from %q, %+v", url, received)
-	}
-	switch received.Type {
-	case ipv4.ICMPTypeEchoReply:
-		return duration, nil
-	default:
-		return duration, errors.Errorf("Didn't receive a good message back from %q, got Code: %d", url, received.Code)
+// UseTOS sets the type of service byte (the DSCP in the upper six bits, e.g. 0xb8 for expedited forwarding) on
+// every echo request, for testing QoS policies. Some values may need elevated privileges, if the OS rejects the
+// value a warning is logged and the pings are sent unmarked.
+func (p *Ping) UseTOS(tos int) error {
+	if tos < 0 || tos > 0xff {
+		return errors.Errorf("Invalid type of service %d, should be between 0 and 255", tos)
 	}
+	p.tos = tos
+	return nil
 }
 
 type PingResults struct {
 	Data        PingDataPoint
 	IP          net.IP
 	InternalErr error
+
+	// Seq is the ICMP sequence number of the echo request this result is for. It is only reported live by
+	// [Ping.CreateChannel] and is not persisted.
+	Seq uint16
+	// ReplySeq is the ICMP sequence number echoed back by the reply. For a good packet this always equals Seq,
+	// for an [OutOfOrder] result it is the sequence of the earlier request the late reply was for. Live only.
+	//
+	// Note the reply's ICMP identifier isn't reported, un-privileged ICMP sockets have their identifier
+	// re-written by the OS so it can't be compared against the identifier we sent.
+	ReplySeq uint16
+}
+
+// SeqMismatch is true if this result is a reply for a different request than the one we were waiting on. Only
+// meaningful for results received live from a [Ping.CreateChannel].
+func (p PingResults) SeqMismatch() bool {
+	return p.InternalErr == nil && p.Seq != p.ReplySeq
+}
+
+func (p PingResults) withSeq(seq uint16) PingResults {
+	p.Seq = seq
+	return p
 }
 
 type PingDataPoint struct {
 	Duration   time.Duration
 	Timestamp  time.Time
 	DropReason Dropped
+	// TTL is the time to live of the IP packet the reply arrived in, a change in the TTL usually means the
+	// route to the destination changed. Zero when unknown, i.e. the point was dropped, the OS doesn't report it
+	// or the point was read from a file written before the TTL was recorded.
+	TTL uint8
 }
 
 type Dropped byte
@@ -125,6 +177,16 @@ const (
 	Timeout
 	DNSFailure
 	BadResponse
+	// OutOfOrder is a reply which arrived for an earlier request than the one currently in flight, the earlier
+	// request will have already been reported as a [Timeout].
+	OutOfOrder
+	// ListenFailure is reported for each failed attempt to re-start listening, usually because the network
+	// interface has gone away.
+	ListenFailure
+	// InternalError marks a point where something went wrong on this machine rather than on the network (e.g.
+	// the echo request couldn't be written to the socket), see [PingResults.InternalErr]. Such a point is
+	// neither good nor [PingDataPoint.Dropped].
+	InternalError
 
 	TestDrop = 0xfe
 )
@@ -133,7 +195,11 @@ func (p PingResults) String() string {
 	if p.IP == nil && p.InternalErr != nil {
 		return "Internal Error " + p.Data.Timestamp.Format(timestampFormat) + " reason " + p.InternalErr.Error()
 	} else {
-		return p.IP.String() + " | " + p.Data.String()
+		ret := p.IP.String() + " | " + p.Data.String()
+		if p.SeqMismatch() {
+			ret += fmt.Sprintf(" | reply for seq %d, expected %d", p.ReplySeq, p.Seq)
+		}
+		return ret
 	}
 }
 
@@ -141,10 +207,16 @@ func (d Dropped) String() string {
 	switch d {
 	case BadResponse:
 		return "Bad Response"
+	case OutOfOrder:
+		return "Out Of Order Reply"
 	case Timeout:
 		return "Timeout"
 	case DNSFailure:
 		return "DNS Query Failed"
+	case ListenFailure:
+		return "Listening Failed"
+	case InternalError:
+		return "Internal Error"
 	case TestDrop:
 		return "Testing A Dropped Packet :)"
 
@@ -160,21 +232,41 @@ const timestampFormat = "15:04:05.99"
 func (p PingDataPoint) String() string {
 	if p.Good() {
 		return fmt.Sprintf("%s | %s", p.Timestamp.Format(timestampFormat), p.Duration.String())
+	} else if p.InternalError() {
+		return fmt.Sprintf("%s | INTERNAL ERROR", p.Timestamp.Format(timestampFormat))
 	}
 	return fmt.Sprintf("%s | DROPPED, reason %q", p.Timestamp.Format(timestampFormat), p.DropReason.String())
 }
 
 func (p PingDataPoint) Dropped() bool {
-	return p.DropReason != NotDropped
+	return p.DropReason != NotDropped && p.DropReason != InternalError
+}
+func (p PingDataPoint) InternalError() bool {
+	return p.DropReason == InternalError
+}
+
+// OutOfOrder is true for a late reply to an earlier probe, see [Ping.RecordOutOfOrder]. The earlier probe was
+// already recorded as dropped, so a late reply mustn't be counted again.
+func (p PingDataPoint) OutOfOrder() bool {
+	return p.DropReason == OutOfOrder
 }
 func (p PingDataPoint) Good() bool {
 	return p.DropReason == NotDropped
 }
 
+// Equal is true when both points have the same duration, drop reason and instant, regardless of the time zone
+// of the timestamps.
+func (p PingDataPoint) Equal(other PingDataPoint) bool {
+	return p.Duration == other.Duration && p.DropReason == other.DropReason && p.Timestamp.Equal(other.Timestamp)
+}
+
 func (p *Ping) CreateChannel(ctx context.Context, url string, pingsPerMinute float64, channelSize int) (chan PingResults, error) {
 	if pingsPerMinute < 0 {
 		return nil, errors.Errorf("Invalid pings per minute %f, should be larger than 0", pingsPerMinute)
 	}
+	if p.adaptive != nil && pingsPerMinute == 0 {
+		return nil, errors.Errorf("An adaptive rate needs a normal pings per minute to return to, got 0")
+	}
 
 	// Create a listener for the IP we will use
 	closer, err := p.startListening(url)
@@ -187,34 +279,53 @@ func (p *Ping) CreateChannel(ctx context.Context, url string, pingsPerMinute flo
 	p.addresses, _ = IPv4DNSQuery(url, p.dnsCacheTrust)
 
 	rateLimit := p.buildRateLimiting(pingsPerMinute)
+	var rate *rateController
+	if p.adaptive != nil {
+		rate = newRateController(*p.adaptive, pingsPerMinute, rateLimit)
+	}
 
 	client := make(chan PingResults, channelSize)
-	p.startChannel(ctx, client, closer, url, rateLimit)
+	p.startChannel(ctx, client, closer, url, rateLimit, rate)
 	return client, nil
 }
 
-func (p *Ping) startChannel(ctx context.Context, client chan PingResults, closer func(), url string, rateLimit *time.Ticker) {
+func (p *Ping) startChannel(
+	ctx context.Context,
+	client chan PingResults,
+	closer func(),
+	url string,
+	rateLimit *time.Ticker,
+	rate *rateController,
+) {
 	run := func() {
 		defer close(client)
 		defer closer()
 		var seq uint16
 		buffer := make([]byte, 255)
-		var errorDuringLoop bool
+		var result PingResults
+		now := p.clock()
 		for {
-			timestamp := time.Now()
+			timestamp := now()
 
-			ip, newCloser := p.dnsRetry(url, client, timestamp, rateLimit, closer)
+			ip, newCloser := p.dnsRetry(ctx, url, client, timestamp, rateLimit, closer, now)
 			if newCloser != nil {
 				defer newCloser()
 				closer = newCloser
 				// Reset the timestamp, we were stuck in DNS for too long
-				timestamp = time.Now()
+				timestamp = now()
+			}
+			if ip == nil {
+				// Cancelled while the network was down
+				return
 			}
 
-			if seq, errorDuringLoop = p.pingOnChannel(ctx, timestamp, ip, seq, client, buffer); errorDuringLoop {
+			if seq, result = p.pingOnChannel(ctx, timestamp, ip, seq, client, buffer); !result.Data.Good() {
 				// Keep track of this address as maybe being unreliable
 				p.addresses.Dropped(ip)
 			}
+			if rate != nil {
+				rate.observe(result)
+			}
 			select {
 			case <-ctx.Done():
 				return
@@ -229,9 +340,21 @@ func (p *Ping) startChannel(ctx context.Context, client chan PingResults, closer
 	go run()
 }
 
-func (p *Ping) dnsRetry(url string, client chan PingResults, timestamp time.Time, rateLimit *time.Ticker, closer func()) (net.IP, func()) {
+// dnsRetry returns the IP to ping next, re-querying DNS and re-starting the listener once every address has
+// gone stale. While the network is down each failed attempt is reported as a dropped packet. If the context is
+// cancelled while retrying a nil IP is returned.
+func (p *Ping) dnsRetry(
+	ctx context.Context,
+	url string,
+	client chan PingResults,
+	timestamp time.Time,
+	rateLimit *time.Ticker,
+	closer func(),
+	now func() time.Time,
+) (net.IP, func()) {
 	var err error
 	var newCloser func()
+	retry := backoff.NewExponentialBackoff(minRetryDelay, maxRetryDelay)
 HARD_RETRY:
 	if p.addresses == nil {
 		// Keeping doing a DNS query until we get a valid result, count each failure as a dropped packet
@@ -240,18 +363,26 @@ HARD_RETRY:
 			p.addresses, err = IPv4DNSQuery(url, p.dnsCacheTrust)
 			if err != nil {
 				client <- packetLoss(nil, timestamp, DNSFailure)
-				<-rateLimit.C
-				timestamp = time.Now()
+				if err = waitToRetry(ctx, rateLimit, retry); err != nil {
+					return nil, newCloser
+				}
+				timestamp = now()
 			}
 		}
 		// Reset our listening, it's a chance our NIC died in which case we need to restart this.
 		// I don't think we can tell that the inner listener died.
 		closer()
+		retry.Reset()
 		for {
 			newCloser, err = p.startListening(url)
 			if err == nil {
 				break
 			}
+			client <- packetLoss(nil, timestamp, ListenFailure)
+			if err = retry.Wait(ctx); err != nil {
+				return nil, nil
+			}
+			timestamp = now()
 		}
 	}
 	ip, ok := p.addresses.Get()
@@ -262,29 +393,73 @@ HARD_RETRY:
 	return ip, newCloser
 }
 
+const (
+	minRetryDelay = 100 * time.Millisecond
+	maxRetryDelay = 30 * time.Second
+)
+
+// waitToRetry paces retries by the rate limit if there is one, otherwise by the backoff.
+func waitToRetry(ctx context.Context, rateLimit *time.Ticker, retry *backoff.ExponentialBackoff) error {
+	if rateLimit == nil {
+		return retry.Wait(ctx)
+	}
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-rateLimit.C:
+		return nil
+	}
+}
+
+// clock returns the function which should be used to timestamp each ping for a capture, respecting
+// [Ping.UseMonotonicClock].
+func (p *Ping) clock() func() time.Time {
+	if !p.monotonic {
+		return time.Now
+	}
+	origin := time.Now()
+	return func() time.Time {
+		// [time.Since] uses the monotonic reading carried by origin, so this is immune to wall clock changes.
+		return origin.Add(time.Since(origin)).Round(0)
+	}
+}
+
 func (p *Ping) buildRateLimiting(pingsPerMinute float64) *time.Ticker {
 	p.timeout = time.Second
 	var rateLimit *time.Ticker
 	// Zero is the sentinel, go as fast as possible
 	if pingsPerMinute != 0 {
-		maxPingDuration := PingsPerMinuteToDuration(pingsPerMinute)
+		// A ticker needs a positive interval, anything faster than MaxPingsPerMinute is clamped to it.
+		maxPingDuration := max(PingsPerMinuteToDuration(pingsPerMinute), time.Millisecond)
 		rateLimit = time.NewTicker(maxPingDuration)
 		p.timeout = max(min(p.timeout, maxPingDuration), 500*time.Millisecond)
 	}
 	return rateLimit
 }
 
+// MaxPingsPerMinute is the fastest rate which can be given, a ping every millisecond. Zero is faster still, as
+// fast as possible.
+const MaxPingsPerMinute = 60 * 1000
+
 func PingsPerMinuteToDuration(pingsPerMinute float64) time.Duration {
 	if pingsPerMinute == 0 {
 		return 0
 	}
-	gapBetweenPings := math.Round((60 * 1000) / (pingsPerMinute))
-	return time.Millisecond * time.Duration(gapBetweenPings)
+	return time.Duration(math.Round(float64(time.Minute) / pingsPerMinute))
+}
+
+// DurationToPingsPerMinute is the inverse of [PingsPerMinuteToDuration], the pings per minute which sends a ping
+// every interval. Zero for an interval of zero, as fast as possible.
+func DurationToPingsPerMinute(interval time.Duration) float64 {
+	if interval == 0 {
+		return 0
+	}
+	return float64(time.Minute) / float64(interval)
 }
 
 func internalErr(IP net.IP, Timestamp time.Time, err error) PingResults {
 	return PingResults{
-		Data:        PingDataPoint{Timestamp: Timestamp},
+		Data:        PingDataPoint{Timestamp: Timestamp, DropReason: InternalError},
 		IP:          IP,
 		InternalErr: err,
 	}
@@ -311,9 +486,10 @@ func goodPacket(IP net.IP, Duration time.Duration, Timestamp time.Time) PingResu
 	}
 }
 
-// pingOnChannel performs a single ping to the already discovered IP, using the buffer as a scratch buffer,
-// and writes ALL results to the channel (including errors). It self limits it's execution if it was called
-// too recently compared to the desired rate.
+// pingOnChannel sends a single echo request with the given sequence number and waits for its reply, writing
+// the outcome to the client. The sequence number to use for the next request is returned along with the
+// outcome, the sequence always advances so that a late reply can never be mistaken for the reply to a later
+// request.
 func (p *Ping) pingOnChannel(
 	ctx context.Context,
 	timestamp time.Time,
@@ -321,46 +497,68 @@ func (p *Ping) pingOnChannel(
 	seq uint16,
 	client chan PingResults,
 	buffer []byte,
-) (uint16, bool) {
+) (uint16, PingResults) {
+	next := seq + 1 // Deliberate wrap-around
 	// Can gain some speed here by not remaking this each time, only to change the sequence number.
 	raw, err := p.makeOutgoingPacket(seq)
 	if err != nil {
-		client <- internalErr(selectedIP, timestamp, err)
-		return seq, true
+		result := internalErr(selectedIP, timestamp, err).withSeq(seq)
+		client <- result
+		return next, result
 	}
 
 	// Actually write the echo request onto the connection:
 	if err = p.writeEcho(selectedIP, raw); err != nil {
-		client <- internalErr(selectedIP, timestamp, err)
-		return seq, true
+		result := internalErr(selectedIP, timestamp, err).withSeq(seq)
+		client <- result
+		return next, result
 	}
 	begin := time.Now()
 	timeout := pingTimeout{Duration: p.timeout}
-	timeoutCtx, _ := context.WithTimeoutCause(ctx, p.timeout, timeout)
-	n, err := p.pingRead(timeoutCtx, buffer)
-	duration := time.Since(begin)
-	if err != nil && errors.Is(err, timeout) {
-		client <- packetLoss(selectedIP, timestamp, Timeout)
-		return seq, true
-	} else if err != nil {
-		client <- internalErr(selectedIP, timestamp, errors.Wrapf(err, "couldn't read packet from %q", p.currentURL))
-		return seq, true
-	}
-	received, err := icmp.ParseMessage(protocolICMP, buffer[:n])
-	if err != nil {
-		client <- internalErr(selectedIP, timestamp, errors.Wrapf(err, "couldn't parse raw packet from %q, %+v", p.currentURL, received))
-		return seq, true
-	}
-	switch received.Type {
-	case ipv4.ICMPTypeEchoReply:
+	timeoutCtx, cancel := context.WithTimeoutCause(ctx, p.timeout, timeout)
+	defer cancel()
+	// Keep reading until we get the reply to this request, any late replies to earlier requests share the
+	// same timeout.
+	for {
+		n, ttl, err := p.pingRead(timeoutCtx, buffer)
+		duration := time.Since(begin)
+		if err != nil && errors.Is(err, timeout) {
+			result := packetLoss(selectedIP, timestamp, Timeout).withSeq(seq)
+			client <- result
+			return next, result
+		} else if err != nil {
+			result := internalErr(selectedIP, timestamp, errors.Wrapf(err, "couldn't read packet from %q", p.currentURL)).withSeq(seq)
+			client <- result
+			return next, result
+		}
+		received, err := icmp.ParseMessage(protocolICMP, buffer[:n])
+		if err != nil {
+			result := internalErr(selectedIP, timestamp, errors.Wrapf(err, "couldn't parse raw packet from %q, %+v", p.currentURL, received)).withSeq(seq)
+			client <- result
+			return next, result
+		}
 		// Clear the buffer for next packet
 		bytes.Clear(buffer, n)
-		seq++ // Deliberate wrap-around
-		client <- goodPacket(selectedIP, duration, timestamp)
-		return seq, false
-	default:
-		client <- packetLoss(selectedIP, timestamp, BadResponse)
-		return seq, true
+		if received.Type != ipv4.ICMPTypeEchoReply {
+			result := packetLoss(selectedIP, timestamp, BadResponse).withSeq(seq)
+			client <- result
+			return next, result
+		}
+		// The ID of the reply isn't checked, the OS re-writes it for un-privileged sockets.
+		echo, ok := received.Body.(*icmp.Echo)
+		if ok && uint16(echo.Seq) != seq {
+			if p.recordOutOfOrder {
+				late := packetLoss(selectedIP, timestamp, OutOfOrder).withSeq(seq)
+				late.ReplySeq = uint16(echo.Seq)
+				client <- late
+			}
+			continue
+		}
+		result := goodPacket(selectedIP, duration, timestamp).withSeq(seq)
+		result.ReplySeq = seq
+		result.Data.TTL = uint8(ttl)
+		client <- result
+		return next, result
 	}
 }
 
@@ -370,18 +568,32 @@ type pingTimeout struct {
 
 func (pt pingTimeout) Error() string { return "PingTimeout {" + pt.String() + "}" }
 
-func (p *Ping) pingRead(ctx context.Context, buffer []byte) (n int, err error) {
-	c := make(chan struct{})
+type readResult struct {
+	n   int
+	ttl int
+	err error
+}
+
+// pingRead reads the next packet into the buffer, returning its length and the TTL it arrived with. The TTL
+// is zero if the OS didn't report it, see [Ping.startListening].
+func (p *Ping) pingRead(ctx context.Context, buffer []byte) (int, int, error) {
+	// Buffered so that the reader never blocks forever if we stopped waiting on it, it will be unblocked
+	// once the connection is closed.
+	c := make(chan readResult, 1)
 	go func() {
-		n, _, err = p.connect.ReadFrom(buffer)
-		c <- struct{}{}
+		n, cm, _, err := p.connect.IPv4PacketConn().ReadFrom(buffer)
+		r := readResult{n: n, err: err}
+		if cm != nil {
+			r.ttl = cm.TTL
+		}
+		c <- r
 	}()
 	select {
 	case <-ctx.Done():
-		err = context.Cause(ctx)
-	case <-c:
+		return 0, 0, context.Cause(ctx)
+	case r := <-c:
+		return r.n, r.ttl, r.err
 	}
-	return n, err
 }
 
 func (p *Ping) makeOutgoingPacket(seq uint16) ([]byte, error) {
@@ -404,7 +616,13 @@ func (p *Ping) makeOutgoingPacket(seq uint16) ([]byte, error) {
 
 func (p *Ping) writeEcho(selectedIP net.IP, raw []byte) error {
 	udpDst := &net.UDPAddr{IP: selectedIP}
-	if _, err := p.connect.WriteTo(raw, udpDst); err != nil {
+	var err error
+	if cm := p.source.controlMessage(); cm != nil {
+		_, err = p.connect.IPv4PacketConn().WriteTo(raw, cm, udpDst)
+	} else {
+		_, err = p.connect.WriteTo(raw, udpDst)
+	}
+	if err != nil {
 		return errors.Wrapf(err, "couldn't write packet to connection %q", p.currentURL)
 	}
 	return nil
@@ -412,11 +630,19 @@ func (p *Ping) writeEcho(selectedIP net.IP, raw []byte) error {
 
 func (p *Ping) startListening(url string) (closer func(), err error) {
 	// TODO supporting windows (privileges etc)
-	p.connect, err = icmp.ListenPacket("udp4", listenAddr.String())
+	p.connect, err = icmp.ListenPacket("udp4", p.source.listenAddr())
 	p.currentURL = url
 	if err != nil {
 		return nil, errors.Wrapf(err, "couldn't listen")
 	}
+	if p.tos != 0 {
+		if err := p.connect.IPv4PacketConn().SetTOS(p.tos); err != nil {
+			slog.Warn("couldn't set the type of service, sending unmarked pings", "tos", p.tos, "err", err)
+		}
+	}
+	if err := p.connect.IPv4PacketConn().SetControlMessage(ipv4.FlagTTL, true); err != nil {
+		slog.Warn("couldn't ask for the TTL of replies, it won't be recorded", "err", err)
+	}
 	return func() {
 		p.connect.Close()
 		p.currentURL = ""
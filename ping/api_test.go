@@ -24,6 +24,36 @@ func TestOneShot_google_com(t *testing.T) {
 	require.GreaterOrEqual(t, duration, time.Millisecond)
 }
 
+func TestOneShotContext_cancelled(t *testing.T) {
+	t.Parallel()
+	p := ping.NewPing()
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	cancelFunc()
+	_, err := p.OneShotContext(ctx, "www.google.com", ping.OneShotTimeout(time.Second), ping.OneShotRetries(3))
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestOneShotWithResult_cancelled(t *testing.T) {
+	t.Parallel()
+	p := ping.NewPing()
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	cancelFunc()
+	result := p.OneShotWithResult(ctx, "www.google.com")
+	require.ErrorIs(t, result.Err, context.Canceled)
+	require.False(t, result.Dropped())
+}
+
+func TestOneShotWithResult_dnsFailure(t *testing.T) {
+	t.Parallel()
+	p := ping.NewPing()
+	// The .invalid TLD is reserved to never resolve.
+	result := p.OneShotWithResult(context.Background(), "acciping.invalid")
+	require.Equal(t, ping.DNSFailure, result.DropReason)
+	require.ErrorContains(t, result.Err, "acciping.invalid", "the resolver's error is kept")
+	_, err := p.OneShotContext(context.Background(), "acciping.invalid")
+	require.ErrorContains(t, err, "couldn't resolve")
+}
+
 func TestChannel_google_com(t *testing.T) {
 	t.Parallel()
 	p := ping.NewPing()
@@ -47,3 +77,38 @@ func TestUint16Wrapping(t *testing.T) {
 	}
 	require.Equal(t, uint16(1), i+1)
 }
+
+func TestParseDNSCacheTrust(t *testing.T) {
+	t.Parallel()
+	for input, expected := range map[string]ping.DNSCacheTrust{
+		"low":     ping.LowTrust,
+		"nominal": ping.NominalTrust,
+		"high":    ping.HighTrust,
+	} {
+		actual, err := ping.ParseDNSCacheTrust(input)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	}
+	_, err := ping.ParseDNSCacheTrust("Low Trust")
+	require.Error(t, err)
+}
+
+func TestOneShotMany_cancelled(t *testing.T) {
+	t.Parallel()
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	cancelFunc()
+	results := ping.OneShotMany(ctx, []string{"www.google.com", "www.example.com"})
+	require.Len(t, results, 2)
+	for _, result := range results {
+		require.ErrorIs(t, result.Err, context.Canceled)
+	}
+}
+
+func TestUseTOS(t *testing.T) {
+	t.Parallel()
+	p := ping.NewPing()
+	require.NoError(t, p.UseTOS(0xb8))
+	require.NoError(t, p.UseTOS(0))
+	require.Error(t, p.UseTOS(-1))
+	require.Error(t, p.UseTOS(256))
+}
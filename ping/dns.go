@@ -72,11 +72,11 @@ func (q *queryCache) Dropped(IP net.IP) {
 
 	// Now perform the update
 	cur := q.store[index]
-	stale := cur.dropCount > q.maxDrops
-	q.store[q.index] = queryCacheItem{
+	dropCount := cur.dropCount + 1
+	q.store[index] = queryCacheItem{
 		ip:        cur.ip,
-		stale:     stale,
-		dropCount: cur.dropCount + 1,
+		stale:     dropCount > q.maxDrops,
+		dropCount: dropCount,
 	}
 }
 
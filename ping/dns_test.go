@@ -0,0 +1,38 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package ping
+
+import (
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryCacheDropped(t *testing.T) {
+	t.Parallel()
+	current := net.IPv4(1, 1, 1, 1)
+	dropping := net.IPv4(2, 2, 2, 2)
+	q := &queryCache{
+		m:        &sync.Mutex{},
+		store:    []queryCacheItem{{ip: current}, {ip: dropping}},
+		index:    0,
+		maxDrops: NominalTrust.asMaxDropped(),
+	}
+
+	q.Dropped(dropping)
+	require.False(t, q.store[1].stale, "one drop is within nominal trust")
+	require.Equal(t, uint(1), q.store[1].dropCount)
+	q.Dropped(dropping)
+	require.True(t, q.store[1].stale, "two drops exceeds nominal trust")
+	require.Equal(t, uint(2), q.store[1].dropCount)
+
+	require.False(t, q.store[0].stale, "the current IP didn't drop")
+	require.Equal(t, uint(0), q.store[0].dropCount)
+	require.Equal(t, current.String(), q.GetLastIP())
+}
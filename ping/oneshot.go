@@ -0,0 +1,211 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package ping
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Lexer747/AcciPing/utils/errors"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// OneShot pings the url once, returning how long the reply took. It gives up waiting for a reply after one
+// second. See [Ping.OneShotContext].
+func (p *Ping) OneShot(url string) (time.Duration, error) {
+	return p.OneShotContext(context.Background(), url)
+}
+
+// OneShotContext pings the url, returning how long the reply took. By default it makes a single attempt which
+// gives up waiting for a reply after one second, see [OneShotTimeout], [OneShotRetries] and [OneShotMedian] to
+// change this. Every attempt gives up early if the ctx is cancelled. The underlying listener is always closed
+// before this returns.
+func (p *Ping) OneShotContext(ctx context.Context, url string, opts ...OneShotOption) (time.Duration, error) {
+	result := p.OneShotWithResult(ctx, url, opts...)
+	if result.Err == nil && result.Dropped() {
+		return result.Duration, errors.Errorf("Ping to %q dropped, %s", url, result.DropReason.String())
+	}
+	return result.Duration, result.Err
+}
+
+// OneShotWithResult is [Ping.OneShotContext] but instead of folding a dropped ping into the error it reports
+// why the ping was dropped, using the same [Dropped] reasons as [Ping.CreateChannel]. This lets a caller treat
+// a host which didn't reply as a data point rather than a failure. The Err of the result is set when the ping
+// couldn't be made at all, or alongside the reason when there's more to say about why it was dropped.
+func (p *Ping) OneShotWithResult(ctx context.Context, url string, opts ...OneShotOption) OneShotResult {
+	o := oneShotOptions{timeout: time.Second}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return p.oneShotAttempts(ctx, url, o)
+}
+
+// OneShotOption configures [Ping.OneShotContext].
+type OneShotOption func(*oneShotOptions)
+
+type oneShotOptions struct {
+	timeout time.Duration
+	retries int
+	median  bool
+}
+
+// OneShotTimeout sets how long each attempt waits for a reply, the default is one second.
+func OneShotTimeout(timeout time.Duration) OneShotOption {
+	return func(o *oneShotOptions) { o.timeout = timeout }
+}
+
+// OneShotRetries makes a further number of attempts after the first. The result is taken from every attempt
+// which got a good reply, the fastest by default or the median with [OneShotMedian]. Only if every attempt
+// fails is a failure returned.
+func OneShotRetries(retries int) OneShotOption {
+	return func(o *oneShotOptions) { o.retries = max(retries, 0) }
+}
+
+// OneShotMedian reports the median of the good replies when retrying instead of the fastest, see
+// [OneShotRetries]. With an even number of good replies the faster of the middle two is used.
+func OneShotMedian() OneShotOption {
+	return func(o *oneShotOptions) { o.median = true }
+}
+
+// OneShotResult is the outcome of a single ping. A ping which got no good reply will have a [Dropped] reason,
+// if something went wrong which stopped the ping from happening at all (e.g. the socket couldn't be opened)
+// then Err is set. Err is also set with the cause of a [DNSFailure] or [BadResponse], alongside the reason.
+type OneShotResult struct {
+	Duration   time.Duration
+	DropReason Dropped
+	Err        error
+}
+
+func (r OneShotResult) Dropped() bool {
+	return r.DropReason != NotDropped
+}
+
+func (r OneShotResult) String() string {
+	switch {
+	case r.Dropped() && r.Err != nil:
+		return "DROPPED, reason " + r.DropReason.String() + ", " + r.Err.Error()
+	case r.Err != nil:
+		return "Error " + r.Err.Error()
+	case r.Dropped():
+		return "DROPPED, reason " + r.DropReason.String()
+	default:
+		return r.Duration.String()
+	}
+}
+
+// maxConcurrentOneShots bounds the number of pings [OneShotMany] will have in flight.
+const maxConcurrentOneShots = 8
+
+// OneShotMany pings every url once concurrently, returning the result for each url. A failure for one url
+// (e.g. a failed DNS query) is reported in that url's result and doesn't affect any other. Each ping gives up
+// waiting for a reply after one second.
+func OneShotMany(ctx context.Context, urls []string) map[string]OneShotResult {
+	results := make(map[string]OneShotResult, len(urls))
+	m := &sync.Mutex{}
+	wg := &sync.WaitGroup{}
+	limit := make(chan struct{}, maxConcurrentOneShots)
+	for _, url := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			// A [Ping] owns a single connection so each concurrent ping needs it's own.
+			result := NewPing().oneShot(ctx, url, time.Second)
+			m.Lock()
+			defer m.Unlock()
+			results[url] = result
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func (p *Ping) oneShotAttempts(ctx context.Context, url string, o oneShotOptions) OneShotResult {
+	var last OneShotResult
+	good := []time.Duration{}
+	for range o.retries + 1 {
+		last = p.oneShot(ctx, url, o.timeout)
+		if last.Err != nil && !last.Dropped() {
+			// Either we've been cancelled or we can't ping at all, no point trying again.
+			break
+		}
+		if !last.Dropped() {
+			good = append(good, last.Duration)
+		}
+	}
+	if len(good) == 0 {
+		return last
+	}
+	slices.Sort(good)
+	if o.median {
+		return OneShotResult{Duration: good[(len(good)-1)/2]}
+	}
+	return OneShotResult{Duration: good[0]}
+}
+
+func (p *Ping) oneShot(ctx context.Context, url string, timeout time.Duration) OneShotResult {
+	if ctx.Err() != nil {
+		return OneShotResult{Err: context.Cause(ctx)}
+	}
+	// first get the ip for a given url
+	cache, err := IPv4DNSQuery(url, p.dnsCacheTrust)
+	if err != nil {
+		return OneShotResult{DropReason: DNSFailure, Err: errors.Wrapf(err, "couldn't resolve %q", url)}
+	}
+	// Don't handle this [!ok] case in OneShot
+	selectedIP, _ := cache.Get()
+
+	// Create a listener for the IP we will use
+	closer, err := p.startListening(url)
+	if err != nil {
+		return OneShotResult{Err: err}
+	}
+	defer closer()
+
+	raw, err := p.makeOutgoingPacket(1)
+	if err != nil {
+		return OneShotResult{Err: errors.Wrapf(err, "couldn't create outgoing %q packet", url)}
+	}
+
+	// Actually write the echo request onto the connection:
+	if err = p.writeEcho(selectedIP, raw); err != nil {
+		return OneShotResult{Err: err}
+	}
+	begin := time.Now()
+
+	// Now wait for the result
+	buffer := make([]byte, 255)
+	timeoutErr := pingTimeout{Duration: timeout}
+	timeoutCtx, cancel := context.WithTimeoutCause(ctx, timeout, timeoutErr)
+	defer cancel()
+	n, _, err := p.pingRead(timeoutCtx, buffer)
+	duration := time.Since(begin)
+	if err != nil && errors.Is(err, timeoutErr) {
+		return OneShotResult{Duration: duration, DropReason: Timeout}
+	} else if err != nil {
+		return OneShotResult{Duration: duration, Err: errors.Wrapf(err, "couldn't read packet from %q", url)}
+	}
+	received, err := icmp.ParseMessage(protocolICMP, buffer[:n])
+	if err != nil {
+		return OneShotResult{Duration: duration, Err: errors.Wrapf(err, "couldn't parse raw packet from %q, %+v", url, received)}
+	}
+	switch received.Type {
+	case ipv4.ICMPTypeEchoReply:
+		return OneShotResult{Duration: duration}
+	default:
+		return OneShotResult{
+			Duration:   duration,
+			DropReason: BadResponse,
+			Err:        errors.Errorf("Didn't receive a good message back from %q, got Type: %v Code: %d", url, received.Type, received.Code),
+		}
+	}
+}
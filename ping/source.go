@@ -0,0 +1,79 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package ping
+
+import (
+	"net"
+
+	"github.com/Lexer747/AcciPing/utils/errors"
+
+	"golang.org/x/net/ipv4"
+)
+
+// source is the local end of the pings, the zero value lets the OS choose the address and interface.
+type source struct {
+	address net.IP
+	// ifIndex when non zero is sent as a control message with every echo, forcing the outgoing interface.
+	ifIndex int
+}
+
+// UseSourceAddress binds the pings to the given local IPv4 address, so that on a multi-homed machine they leave
+// from the interface which owns that address. The address must belong to one of this machine's interfaces.
+func (p *Ping) UseSourceAddress(address string) error {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return errors.Errorf("Invalid source address %q, expected an IPv4 address like 192.168.1.5", address)
+	}
+	if !isIpv4(ip) {
+		return errors.Errorf("Invalid source address %q, only IPv4 is supported", address)
+	}
+	addresses, err := net.InterfaceAddrs()
+	if err != nil {
+		return errors.Wrap(err, "couldn't list local addresses")
+	}
+	for _, addr := range addresses {
+		if local, ok := addr.(*net.IPNet); ok && local.IP.Equal(ip) {
+			p.source.address = ip
+			return nil
+		}
+	}
+	return errors.Errorf("Invalid source address %q, it doesn't belong to any local interface", address)
+}
+
+// UseInterface sends the pings out of the named interface (e.g. "eth1"), binding them to the first IPv4 address
+// of that interface and requesting the interface explicitly on every echo.
+func (p *Ping) UseInterface(name string) error {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid interface %q", name)
+	}
+	addresses, err := iface.Addrs()
+	if err != nil {
+		return errors.Wrapf(err, "couldn't list addresses of interface %q", name)
+	}
+	for _, addr := range addresses {
+		if local, ok := addr.(*net.IPNet); ok && isIpv4(local.IP) {
+			p.source = source{address: local.IP, ifIndex: iface.Index}
+			return nil
+		}
+	}
+	return errors.Errorf("Invalid interface %q, it has no IPv4 address", name)
+}
+
+func (s source) listenAddr() string {
+	if s.address == nil {
+		return listenAddr.String()
+	}
+	return s.address.String()
+}
+
+func (s source) controlMessage() *ipv4.ControlMessage {
+	if s.ifIndex == 0 {
+		return nil
+	}
+	return &ipv4.ControlMessage{IfIndex: s.ifIndex}
+}
@@ -0,0 +1,40 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package ping_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/Lexer747/AcciPing/ping"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUseSourceAddress(t *testing.T) {
+	t.Parallel()
+	p := ping.NewPing()
+	require.NoError(t, p.UseSourceAddress("127.0.0.1"))
+	// TEST-NET-1, reserved for documentation so never assigned to a local interface.
+	require.ErrorContains(t, p.UseSourceAddress("192.0.2.1"), "doesn't belong to any local interface")
+	require.ErrorContains(t, p.UseSourceAddress("::1"), "only IPv4 is supported")
+	require.ErrorContains(t, p.UseSourceAddress("eth0"), "Invalid source address")
+}
+
+func TestUseInterface(t *testing.T) {
+	t.Parallel()
+	p := ping.NewPing()
+	interfaces, err := net.Interfaces()
+	require.NoError(t, err)
+	for _, iface := range interfaces {
+		// The loopback is named differently per OS, "lo" on linux and "lo0" on darwin.
+		if iface.Flags&net.FlagLoopback != 0 {
+			require.NoError(t, p.UseInterface(iface.Name))
+		}
+	}
+	require.ErrorContains(t, p.UseInterface("not-an-interface0"), "Invalid interface")
+}
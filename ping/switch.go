@@ -0,0 +1,123 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package ping
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Lexer747/AcciPing/utils/errors"
+)
+
+// CreateSwitchableChannel is [Ping.CreateChannel] for a URL and rate which can be changed while running, see
+// [Switcher]. The results of every URL are sent on the same channel, any results of the old URL which arrive
+// after a switch are discarded. The channel is closed once the context is done.
+func (p *Ping) CreateSwitchableChannel(
+	ctx context.Context,
+	url string,
+	pingsPerMinute float64,
+	channelSize int,
+) (chan PingResults, *Switcher, error) {
+	s := &Switcher{
+		p:              p,
+		ctx:            ctx,
+		out:            make(chan PingResults, channelSize),
+		pingsPerMinute: pingsPerMinute,
+		channelSize:    channelSize,
+	}
+	if err := s.start(url); err != nil {
+		return nil, nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		s.m.Lock()
+		defer s.m.Unlock()
+		<-s.forwarded
+		close(s.out)
+	}()
+	return s.out, s, nil
+}
+
+// Switcher changes what a channel made by [Ping.CreateSwitchableChannel] is pinging. Each switch stops the
+// current channel and starts a new one, re-resolving and re-listening as if the channel was new.
+type Switcher struct {
+	p           *Ping
+	ctx         context.Context //nolint:containedctx
+	out         chan PingResults
+	channelSize int
+
+	// m guards the current URL and rate, only one channel of the [Ping] can be running at a time.
+	m              sync.Mutex
+	url            string
+	pingsPerMinute float64
+	// cancel stops the current channel, forwarded is closed once every result of it has been read.
+	cancel    context.CancelFunc
+	forwarded chan struct{}
+}
+
+// start creates the channel for the URL, it must be called with the mutex held or before the switcher is
+// shared.
+func (s *Switcher) start(url string) error {
+	ctx, cancel := context.WithCancel(s.ctx)
+	in, err := s.p.CreateChannel(ctx, url, s.pingsPerMinute, s.channelSize)
+	if err != nil {
+		cancel()
+		return err
+	}
+	s.url = url
+	s.cancel = cancel
+	s.forwarded = make(chan struct{})
+	go func(forwarded chan struct{}) {
+		defer close(forwarded)
+		// Keep reading until the channel is closed so that it's never stuck sending a result once cancelled.
+		for result := range in {
+			if ctx.Err() != nil {
+				continue
+			}
+			select {
+			case s.out <- result:
+			case <-ctx.Done():
+			}
+		}
+	}(s.forwarded)
+	return nil
+}
+
+// SwitchURL stops pinging the current URL and starts pinging the new one at the same rate.
+func (s *Switcher) SwitchURL(url string) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.restart(url, s.pingsPerMinute)
+}
+
+// SwitchRate carries on pinging the current URL at the new pings per minute.
+func (s *Switcher) SwitchRate(pingsPerMinute float64) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.restart(s.url, pingsPerMinute)
+}
+
+// restart replaces the current channel, it must be called with the mutex held. If the new channel can't be
+// started the old one is started again, rather than leaving nothing running.
+func (s *Switcher) restart(url string, pingsPerMinute float64) error {
+	if s.ctx.Err() != nil {
+		return context.Cause(s.ctx)
+	}
+	oldURL, oldRate := s.url, s.pingsPerMinute
+	s.cancel()
+	<-s.forwarded
+	s.pingsPerMinute = pingsPerMinute
+	if err := s.start(url); err != nil {
+		s.pingsPerMinute = oldRate
+		if restartErr := s.start(oldURL); restartErr != nil {
+			return errors.Wrapf(restartErr, "couldn't switch to %q at %g pings per minute (%s) or go back to %q",
+				url, pingsPerMinute, err.Error(), oldURL)
+		}
+		return errors.Wrapf(err, "couldn't switch to %q at %g pings per minute", url, pingsPerMinute)
+	}
+	return nil
+}
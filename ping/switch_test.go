@@ -0,0 +1,34 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package ping_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Lexer747/AcciPing/ping"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSwitchableChannel_cancelled(t *testing.T) {
+	t.Parallel()
+	p := ping.NewPing()
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	channel, switcher, err := p.CreateSwitchableChannel(ctx, "localhost", 0, 0)
+	if err != nil {
+		t.Skipf("can't listen for pings in this environment: %s", err.Error())
+	}
+	require.NoError(t, switcher.SwitchURL("127.0.0.1"))
+	require.NoError(t, switcher.SwitchRate(6000))
+	cancelFunc()
+	for range channel {
+		// Drained until it's closed
+	}
+	require.ErrorIs(t, switcher.SwitchURL("localhost"), context.Canceled)
+	require.ErrorIs(t, switcher.SwitchRate(60), context.Canceled)
+}
@@ -0,0 +1,84 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package main
+
+import (
+	"flag"
+	"time"
+
+	"github.com/Lexer747/AcciPing/ping"
+	"github.com/Lexer747/AcciPing/utils/errors"
+)
+
+// rateFlags are the ways to say how often to ping, at most one of them can be set. Internally the rate is always
+// pings per minute.
+type rateFlags struct {
+	pingsPerMinute float64
+	pingsPerSecond float64
+	interval       time.Duration
+}
+
+// setFlags are the names of the flags set on the command line, see [flag.Visit].
+func setFlags(fs *flag.FlagSet) map[string]bool {
+	ret := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { ret[f.Name] = true })
+	return ret
+}
+
+// pingsPerMinuteFrom is the rate from whichever flag was set, -pings-per-minute (or its default) if none were.
+// The rate can't be faster than a ping every millisecond, [ping.MaxPingsPerMinute].
+func (r rateFlags) pingsPerMinuteFrom(set map[string]bool) (float64, error) {
+	count := 0
+	for _, name := range []string{"pings-per-minute", "pings-per-second", "interval"} {
+		if set[name] {
+			count++
+		}
+	}
+	if count > 1 {
+		return 0, errors.New("-pings-per-minute, -pings-per-second and -interval are mutually exclusive")
+	}
+	switch {
+	case set["pings-per-second"]:
+		if r.pingsPerSecond < 0 {
+			return 0, errors.Errorf("-pings-per-second must not be negative, got %g", r.pingsPerSecond)
+		}
+		if r.pingsPerSecond*60 > ping.MaxPingsPerMinute {
+			return 0, errors.Errorf("-pings-per-second must be at most %g, got %g", ping.MaxPingsPerMinute/60.0, r.pingsPerSecond)
+		}
+		return r.pingsPerSecond * 60, nil
+	case set["interval"]:
+		if r.interval < 0 {
+			return 0, errors.Errorf("-interval must not be negative, got %s", r.interval)
+		}
+		if r.interval != 0 && r.interval < time.Millisecond {
+			return 0, errors.Errorf("-interval must be at least 1ms (or 0 for as fast as possible), got %s", r.interval)
+		}
+		return ping.DurationToPingsPerMinute(r.interval), nil
+	default:
+		if r.pingsPerMinute < 0 {
+			return 0, errors.Errorf("-pings-per-minute must not be negative, got %g", r.pingsPerMinute)
+		}
+		if r.pingsPerMinute > ping.MaxPingsPerMinute {
+			return 0, errors.Errorf("-pings-per-minute must be at most %d, got %g", ping.MaxPingsPerMinute, r.pingsPerMinute)
+		}
+		return r.pingsPerMinute, nil
+	}
+}
+
+// checkAdaptiveRate is an error unless the -adaptive-rate is a rate faster than the pings per minute, otherwise
+// -adaptive would never change how often the pings are sent.
+func checkAdaptiveRate(rate ping.AdaptiveRate, pingsPerMinute float64) error {
+	switch {
+	case pingsPerMinute == 0:
+		return errors.New("-adaptive needs a rate to adapt from, it can't be used with as fast as possible")
+	case rate.PingsPerMinute > ping.MaxPingsPerMinute:
+		return errors.Errorf("-adaptive-rate must be at most %d, got %g", ping.MaxPingsPerMinute, rate.PingsPerMinute)
+	case rate.PingsPerMinute <= pingsPerMinute:
+		return errors.Errorf("-adaptive-rate must be faster than the %g pings per minute, got %g", pingsPerMinute, rate.PingsPerMinute)
+	}
+	return nil
+}
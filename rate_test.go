@@ -0,0 +1,78 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package main
+
+import (
+	"flag"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Lexer747/AcciPing/ping"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPingsPerMinuteFrom(t *testing.T) {
+	t.Parallel()
+	parse := func(args ...string) (float64, error) {
+		rate := rateFlags{pingsPerMinute: 60}
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		fs.Float64Var(&rate.pingsPerMinute, "pings-per-minute", rate.pingsPerMinute, "")
+		fs.Float64Var(&rate.pingsPerSecond, "pings-per-second", 0, "")
+		fs.DurationVar(&rate.interval, "interval", 0, "")
+		require.NoError(t, fs.Parse(args))
+		return rate.pingsPerMinuteFrom(setFlags(fs))
+	}
+	for _, test := range []struct {
+		args     []string
+		expected float64
+	}{
+		{args: nil, expected: 60},
+		{args: []string{"-pings-per-minute", "120"}, expected: 120},
+		{args: []string{"-pings-per-minute", "0"}, expected: 0},
+		{args: []string{"-pings-per-second", "5"}, expected: 300},
+		{args: []string{"-interval", "200ms"}, expected: 300},
+		{args: []string{"-interval", "2s"}, expected: 30},
+		{args: []string{"-interval", "0s"}, expected: 0},
+		{args: []string{"-interval", "1ms"}, expected: 60000},
+		{args: []string{"-interval", "1500us"}, expected: 40000},
+		{args: []string{"-pings-per-second", "1000"}, expected: 60000},
+		{args: []string{"-pings-per-minute", "60000"}, expected: 60000},
+	} {
+		pingsPerMinute, err := parse(test.args...)
+		require.NoError(t, err, "%v", test.args)
+		require.InDelta(t, test.expected, pingsPerMinute, 1e-9, "%v", test.args)
+	}
+	for _, args := range [][]string{
+		{"-pings-per-minute", "60", "-interval", "1s"},
+		{"-pings-per-second", "1", "-interval", "1s"},
+		{"-pings-per-minute", "60", "-pings-per-second", "1"},
+		{"-interval", "-1s"},
+		{"-pings-per-second", "-1"},
+		{"-interval", "400us"},
+		{"-interval", "999us"},
+		{"-pings-per-second", "2001"},
+		{"-pings-per-minute", "60001"},
+	} {
+		_, err := parse(args...)
+		require.Error(t, err, "%v", args)
+	}
+	require.Equal(t, 200*time.Millisecond, ping.PingsPerMinuteToDuration(ping.DurationToPingsPerMinute(200*time.Millisecond)))
+	require.Equal(t, 1500*time.Microsecond, ping.PingsPerMinuteToDuration(ping.DurationToPingsPerMinute(1500*time.Microsecond)),
+		"not rounded to the millisecond")
+}
+
+func TestCheckAdaptiveRate(t *testing.T) {
+	t.Parallel()
+	rate := ping.AdaptiveRate{PingsPerMinute: 600, Cooldown: 5}
+	require.NoError(t, checkAdaptiveRate(rate, 60))
+	require.Error(t, checkAdaptiveRate(rate, 600), "no faster than the normal rate")
+	require.Error(t, checkAdaptiveRate(rate, 6000))
+	require.Error(t, checkAdaptiveRate(rate, 0), "as fast as possible")
+	require.Error(t, checkAdaptiveRate(ping.AdaptiveRate{PingsPerMinute: ping.MaxPingsPerMinute + 1, Cooldown: 5}, 60))
+}
@@ -0,0 +1,80 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package main
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lexer747/AcciPing/graph"
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/graph/terminal"
+	"github.com/Lexer747/AcciPing/ping"
+	"github.com/Lexer747/AcciPing/utils/errors"
+)
+
+// newReplayGraph is a live graph of the points from the file, drawn one at a time paced by the gaps between
+// their recorded timestamps which they keep. Nothing is written back to the file.
+func newReplayGraph(ctx context.Context, term *terminal.Terminal, file string, speed float64) *graph.Graph {
+	f, err := os.OpenFile(file, os.O_RDONLY, 0)
+	if err != nil {
+		panic(err.Error())
+	}
+	recorded, err := data.ReadData(f)
+	_ = f.Close()
+	if err != nil {
+		panic(err.Error())
+	}
+	const pingsPerMinute = 60.0
+	g, err := graph.NewGraph(ctx, replay(ctx, recorded, speed), term, pingsPerMinute, recorded.URL)
+	if err != nil {
+		panic(err.Error())
+	}
+	return g
+}
+
+// replay sends every point of the data onto the returned channel, waiting between each point for the gap
+// between their timestamps divided by the speed. The channel is closed once every point is sent or the ctx is
+// cancelled.
+func replay(ctx context.Context, d *data.Data, speed float64) chan ping.PingResults {
+	c := make(chan ping.PingResults)
+	go func() {
+		defer close(c)
+		var last time.Time
+		for i := range d.TotalCount {
+			p := d.GetFull(i)
+			if wait := time.Duration(float64(p.Data.Timestamp.Sub(last)) / speed); i > 0 && wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+			}
+			last = p.Data.Timestamp
+			select {
+			case <-ctx.Done():
+				return
+			case c <- p:
+			}
+		}
+	}()
+	return c
+}
+
+// parseSpeed parses a replay speed such as "10x" or "0.5".
+func parseSpeed(speed string) (float64, error) {
+	parsed, err := strconv.ParseFloat(strings.TrimSuffix(speed, "x"), 64)
+	if err != nil || parsed <= 0 {
+		return 0, errors.Errorf("Invalid -speed %q, expected a positive multiplier like 10x", speed)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,108 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package main
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/utils/errors"
+)
+
+// rotation is when the capture file is moved aside and started afresh, see the -rotate flag. The zero value
+// never rotates.
+type rotation struct {
+	daily    bool
+	maxBytes int64
+	// template is the name of the rotated file as a [time.Layout], formatted with the time of its first ping.
+	// Only the file name is formatted, any directory is left as it is.
+	template string
+}
+
+// parseRotation parses either "daily" or a size like "100MB" (KB, MB and GB are understood), the empty string
+// never rotates.
+func parseRotation(rotate, template string) (rotation, error) {
+	switch rotate {
+	case "":
+		return rotation{}, nil
+	case "daily":
+		return rotation{daily: true, template: template}, nil
+	}
+	for _, unit := range []struct {
+		suffix string
+		bytes  int64
+	}{{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}} {
+		size, ok := strings.CutSuffix(rotate, unit.suffix)
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseInt(size, 10, 64)
+		if err != nil || n <= 0 {
+			return rotation{}, errors.Errorf("Invalid rotate size %q, expected a positive whole number of KB, MB or GB", rotate)
+		}
+		return rotation{maxBytes: n * unit.bytes, template: template}, nil
+	}
+	return rotation{}, errors.Errorf("Unknown rotate %q, expected daily or a size such as 100MB", rotate)
+}
+
+// due is true when the data in the file (of size bytes) should be moved aside before the next ping is added.
+func (r rotation) due(d *data.Data, size int64, next time.Time) bool {
+	if d.TotalCount == 0 {
+		return false
+	}
+	if r.daily {
+		y, m, day := d.Header.TimeSpan.Begin.Local().Date()
+		nextY, nextM, nextDay := next.Local().Date()
+		return y != nextY || m != nextM || day != nextDay
+	}
+	return r.maxBytes > 0 && size >= r.maxBytes
+}
+
+// rotate writes the data to a new file named by the template and empties the capture file, returning the data
+// which continues the capture. An existing file is never overwritten. If the rotated file can't be written the
+// capture file is left alone and the data is returned as is.
+func (r rotation) rotate(f *os.File, d *data.Data) (*data.Data, error) {
+	dir, file := filepath.Split(r.template)
+	name := filepath.Join(dir, d.Header.TimeSpan.Begin.Local().Format(file))
+	out, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o777)
+	if err != nil {
+		return d, errors.Wrapf(err, "couldn't create rotated file %q", name)
+	}
+	err = d.AsCompact(out)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return d, errors.Wrapf(err, "couldn't write rotated file %q", name)
+	}
+	next := data.NewData(d.URL)
+	next.Location = d.Location
+	if err = f.Truncate(0); err != nil {
+		return d, errors.Wrap(err, "couldn't empty the capture file")
+	}
+	if _, err = f.Seek(0, 0); err != nil {
+		return d, errors.Wrap(err, "couldn't empty the capture file")
+	}
+	return next, errors.Wrap(next.AsCompact(f), "couldn't empty the capture file")
+}
+
+// tryRotate rotates the file like [rotation.rotate] but on a failure the capture carries on in the one file and
+// rotation is stopped. Trying again with the next ping would most likely fail the same way, e.g. once the rotated
+// file has been created it's never overwritten, and each failure would be logged.
+func (r *rotation) tryRotate(f *os.File, d *data.Data) *data.Data {
+	next, err := r.rotate(f, d)
+	if err != nil {
+		slog.Warn("couldn't rotate the capture file, no longer rotating", "err", err)
+		*r = rotation{}
+	}
+	return next
+}
@@ -0,0 +1,77 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/ping"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRotation(t *testing.T) {
+	t.Parallel()
+	for input, expected := range map[string]rotation{
+		"":      {},
+		"daily": {daily: true, template: "t"},
+		"100MB": {maxBytes: 100 << 20, template: "t"},
+		"2GB":   {maxBytes: 2 << 30, template: "t"},
+		"512KB": {maxBytes: 512 << 10, template: "t"},
+	} {
+		r, err := parseRotation(input, "t")
+		require.NoError(t, err, "%q", input)
+		require.Equal(t, expected, r, "%q", input)
+	}
+	for _, input := range []string{"weekly", "100", "0MB", "-1MB", "1.5GB"} {
+		_, err := parseRotation(input, "t")
+		require.Error(t, err, "%q", input)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	t.Parallel()
+	begin := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.Local)
+	d := data.NewData("www.example.com")
+	daily := rotation{daily: true}
+	size := rotation{maxBytes: 100}
+	require.False(t, daily.due(d, 1000, begin), "nothing to move aside")
+	require.False(t, size.due(d, 1000, begin), "nothing to move aside")
+
+	d.AddPoint(ping.PingResults{Data: ping.PingDataPoint{Duration: time.Millisecond, Timestamp: begin}})
+	require.False(t, daily.due(d, 0, begin.Add(11*time.Hour)))
+	require.True(t, daily.due(d, 0, begin.Add(12*time.Hour)))
+	require.False(t, size.due(d, 99, begin))
+	require.True(t, size.due(d, 100, begin))
+
+	dir := t.TempDir()
+	f, err := os.OpenFile(filepath.Join(dir, "capture.pings"), os.O_CREATE|os.O_RDWR, 0o777)
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, d.AsCompact(f))
+	r := rotation{daily: true, template: filepath.Join(dir, "capture.2006-01-02.pings")}
+	next, err := r.rotate(f, d)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), next.TotalCount)
+	require.Equal(t, d.URL, next.URL)
+
+	rotated, err := os.ReadFile(filepath.Join(dir, "capture.2024-03-01.pings"))
+	require.NoError(t, err)
+	read := &data.Data{}
+	_, err = read.FromCompact(rotated)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), read.TotalCount)
+
+	_, err = r.rotate(f, d)
+	require.Error(t, err, "never overwrites a rotated file")
+
+	require.Same(t, d, r.tryRotate(f, d), "the capture carries on")
+	require.False(t, r.due(d, 0, begin.Add(24*time.Hour)), "stops rotating after a failure")
+}
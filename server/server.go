@@ -0,0 +1,178 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+// Package server exposes a live graph over HTTP, so that a capture can be watched from a browser or another
+// program:
+//
+//   - GET /stats is the current statistics of the capture as JSON.
+//   - /live is a WebSocket which streams a JSON message for every new ping. Only pages served from this machine
+//     (a localhost or loopback Origin) may open it.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Lexer747/AcciPing/graph"
+	"github.com/Lexer747/AcciPing/graph/data"
+	"github.com/Lexer747/AcciPing/ping"
+	"github.com/Lexer747/AcciPing/utils/errors"
+
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// liveBuffer is how many pings a slow WebSocket client can fall behind before pings are dropped for it.
+	liveBuffer = 64
+	// shutdownTimeout is how long requests in flight are given to finish once the context is done.
+	shutdownTimeout = time.Second
+)
+
+// ListenAddr is the address to serve on, an address without a host (e.g. ":8080") is bound to localhost only
+// so that the capture isn't exposed to the network unless a host is given explicitly (e.g. "0.0.0.0:8080").
+func ListenAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", errors.Wrapf(err, "Invalid address to serve on %q, expected host:port or :port", addr)
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// Serve serves the graph on the listener until the context is done, at which point the server is shut down
+// and nil returned.
+func Serve(ctx context.Context, l net.Listener, g *graph.Graph) error {
+	srv := &http.Server{
+		Handler:           Handler(ctx, g),
+		ReadHeaderTimeout: 5 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(l) }()
+	select {
+	case err := <-served:
+		return errors.Wrap(err, "server stopped")
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+		return nil
+	}
+}
+
+// Handler is the HTTP handler of the endpoints, the WebSocket streams end once the context is done.
+func Handler(ctx context.Context, g *graph.Graph) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /stats", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(newStats(g))
+	})
+	mux.Handle("/live", websocket.Server{Handshake: localOrigin, Handler: func(ws *websocket.Conn) {
+		defer ws.Close()
+		pings, unsubscribe := g.Subscribe(liveBuffer)
+		defer unsubscribe()
+		// The client never sends anything, but reading is the only way to notice it's gone away.
+		gone := make(chan struct{})
+		go func() {
+			defer close(gone)
+			var discard []byte
+			for websocket.Message.Receive(ws, &discard) == nil {
+			}
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-gone:
+				return
+			case p, ok := <-pings:
+				if !ok {
+					return
+				}
+				if err := websocket.JSON.Send(ws, newPing(p)); err != nil {
+					return
+				}
+			}
+		}
+	}})
+	return mux
+}
+
+// localOrigin rejects a WebSocket handshake unless it has an Origin on this machine. A browser lets any page
+// open a WebSocket to any address, so without this any site the user visits could read the live capture.
+func localOrigin(config *websocket.Config, req *http.Request) error {
+	origin, err := websocket.Origin(config, req)
+	if err != nil {
+		return errors.Wrap(err, "invalid WebSocket Origin")
+	}
+	if origin == nil {
+		return errors.New("WebSocket handshake is missing an Origin")
+	}
+	host := origin.Hostname()
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return errors.Errorf("WebSocket Origin %q isn't local", origin.String())
+	}
+	config.Origin = origin
+	return nil
+}
+
+// Stats is the JSON body of GET /stats, every duration is in nanoseconds.
+type Stats struct {
+	URL               string    `json:"url"`
+	Begin             time.Time `json:"begin"`
+	End               time.Time `json:"end"`
+	Count             uint64    `json:"count"`
+	Min               int64     `json:"min_ns"`
+	Max               int64     `json:"max_ns"`
+	Mean              float64   `json:"mean_ns"`
+	StandardDeviation float64   `json:"standard_deviation_ns"`
+	PacketsDropped    uint64    `json:"packets_dropped"`
+	PacketLoss        float64   `json:"packet_loss"`
+}
+
+func newStats(g *graph.Graph) Stats {
+	url, stats, span := g.Snapshot()
+	return Stats{
+		URL:               url,
+		Begin:             span.Begin,
+		End:               span.End,
+		Count:             stats.Count,
+		Min:               stats.Min.Nanoseconds(),
+		Max:               stats.Max.Nanoseconds(),
+		Mean:              stats.Mean,
+		StandardDeviation: stats.StandardDeviation,
+		PacketsDropped:    stats.PacketsDropped,
+		PacketLoss:        stats.PacketLoss,
+	}
+}
+
+// Ping is the JSON message sent on /live for each ping, the latency is in nanoseconds and the drop reason is
+// omitted for a good ping.
+type Ping struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Latency    int64     `json:"latency_ns,omitempty"`
+	DropReason string    `json:"drop_reason,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	Seq        uint16    `json:"seq"`
+}
+
+func newPing(p ping.PingResults) Ping {
+	ret := Ping{Timestamp: p.Data.Timestamp, Seq: p.Seq}
+	if p.Data.Good() {
+		ret.Latency = p.Data.Duration.Nanoseconds()
+	} else {
+		ret.DropReason = p.Data.DropReason.String()
+	}
+	if p.IP != nil {
+		ret.IP = data.FormatIP(p.IP)
+	}
+	return ret
+}
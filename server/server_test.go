@@ -0,0 +1,149 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lexer747/AcciPing/graph"
+	"github.com/Lexer747/AcciPing/graph/terminal/th"
+	"github.com/Lexer747/AcciPing/ping"
+	"github.com/Lexer747/AcciPing/server"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func TestListenAddr(t *testing.T) {
+	t.Parallel()
+	addr, err := server.ListenAddr(":8080")
+	require.NoError(t, err)
+	require.Equal(t, "localhost:8080", addr, "defaults to localhost")
+	addr, err = server.ListenAddr("0.0.0.0:8080")
+	require.NoError(t, err)
+	require.Equal(t, "0.0.0.0:8080", addr)
+	_, err = server.ListenAddr("8080")
+	require.Error(t, err)
+}
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g, input := newGraph(ctx, t)
+	input <- ping.PingResults{Data: ping.PingDataPoint{Duration: 10 * time.Millisecond, Timestamp: time.Now()}}
+	input <- ping.PingResults{Data: ping.PingDataPoint{Duration: 20 * time.Millisecond, Timestamp: time.Now()}}
+	input <- ping.PingResults{Data: ping.PingDataPoint{DropReason: ping.Timeout, Timestamp: time.Now()}}
+	require.Eventually(t, func() bool { return g.Size() == 3 }, time.Second, time.Millisecond)
+
+	srv := httptest.NewServer(server.Handler(ctx, g))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/stats")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	var stats server.Stats
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&stats))
+	require.Equal(t, "www.google.com", stats.URL)
+	require.Equal(t, uint64(3), stats.Count)
+	require.Equal(t, uint64(1), stats.PacketsDropped)
+	require.Equal(t, (10 * time.Millisecond).Nanoseconds(), stats.Min)
+	require.Equal(t, (20 * time.Millisecond).Nanoseconds(), stats.Max)
+	require.InDelta(t, 1.0/3.0, stats.PacketLoss, 0.0001)
+}
+
+func TestLive(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g, input := newGraph(ctx, t)
+	srv := httptest.NewServer(server.Handler(ctx, g))
+	defer srv.Close()
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/live", "", srv.URL)
+	require.NoError(t, err)
+	defer ws.Close()
+	// The subscription is only made once the WebSocket is open on the server, so keep sending pings until one
+	// arrives.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for seq := uint16(0); ; seq++ {
+			result := ping.PingResults{
+				Data: ping.PingDataPoint{Duration: 15 * time.Millisecond, Timestamp: time.Now()},
+				IP:   net.IPv4(8, 8, 8, 8),
+				Seq:  seq,
+			}
+			select {
+			case input <- result:
+			case <-stop:
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+
+	var p server.Ping
+	require.NoError(t, ws.SetDeadline(time.Now().Add(5*time.Second)))
+	require.NoError(t, websocket.JSON.Receive(ws, &p))
+	require.Equal(t, (15 * time.Millisecond).Nanoseconds(), p.Latency)
+	require.Equal(t, "8.8.8.8", p.IP)
+	require.Empty(t, p.DropReason)
+
+	cancel()
+	for websocket.JSON.Receive(ws, &p) == nil {
+	}
+}
+
+func TestLive_origin(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g, _ := newGraph(ctx, t)
+	srv := httptest.NewServer(server.Handler(ctx, g))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/live"
+
+	for _, origin := range []string{"http://localhost:8080", "http://[::1]", srv.URL} {
+		ws, err := websocket.Dial(url, "", origin)
+		require.NoError(t, err, origin)
+		require.NoError(t, ws.Close())
+	}
+	for _, origin := range []string{"https://example.com", "http://192.168.0.1", "http://localhost.example.com"} {
+		_, err := websocket.Dial(url, "", origin)
+		require.Error(t, err, origin)
+	}
+
+	// Without an Origin at all.
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/live", nil)
+	require.NoError(t, err)
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func newGraph(ctx context.Context, t *testing.T) (*graph.Graph, chan ping.PingResults) {
+	t.Helper()
+	_, _, term, _, err := th.NewTestTerminal()
+	require.NoError(t, err)
+	input := make(chan ping.PingResults)
+	g, err := graph.NewGraph(ctx, input, term, 0, "www.google.com")
+	require.NoError(t, err)
+	return g, input
+}
@@ -0,0 +1,53 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package backoff
+
+import (
+	"context"
+	"time"
+)
+
+// ExponentialBackoff produces delays which double after every attempt, starting at an initial delay and
+// capped at a maximum. Not safe for concurrent use.
+type ExponentialBackoff struct {
+	initial time.Duration
+	max     time.Duration
+	next    time.Duration
+}
+
+func NewExponentialBackoff(initial, max time.Duration) *ExponentialBackoff {
+	return &ExponentialBackoff{
+		initial: initial,
+		max:     max,
+		next:    initial,
+	}
+}
+
+// Next returns the delay to wait before the next attempt and doubles the delay for the attempt after.
+func (b *ExponentialBackoff) Next() time.Duration {
+	ret := b.next
+	b.next = min(b.next*2, b.max)
+	return ret
+}
+
+// Reset starts the delays again from the initial delay, call this once an attempt succeeds.
+func (b *ExponentialBackoff) Reset() {
+	b.next = b.initial
+}
+
+// Wait blocks for the [ExponentialBackoff.Next] delay, returning early with the cause if the context is
+// cancelled first.
+func (b *ExponentialBackoff) Wait(ctx context.Context) error {
+	timer := time.NewTimer(b.Next())
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-timer.C:
+		return nil
+	}
+}
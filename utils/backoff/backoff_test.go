@@ -0,0 +1,36 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package backoff_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Lexer747/AcciPing/utils/backoff"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	t.Parallel()
+	b := backoff.NewExponentialBackoff(time.Second, 5*time.Second)
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for _, e := range expected {
+		require.Equal(t, e, b.Next())
+	}
+	b.Reset()
+	require.Equal(t, time.Second, b.Next())
+}
+
+func TestExponentialBackoff_cancelled(t *testing.T) {
+	t.Parallel()
+	b := backoff.NewExponentialBackoff(time.Hour, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := b.Wait(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -8,10 +8,12 @@ package siphon
 
 import (
 	"context"
+	"sync"
 )
 
 // TeeBufferedChannel, duplicates the channel such that both returned channels receive values from [c], this
-// duplication is unsynchronised. Both channels are closed when the [ctx] is done.
+// duplication is unsynchronised. Both channels are closed when the [ctx] is done, or once [c] is closed and
+// both channels have received every value.
 func TeeBufferedChannel[T any](ctx context.Context, c chan T, channelSize int) (
 	chan T,
 	chan T,
@@ -19,20 +21,57 @@ func TeeBufferedChannel[T any](ctx context.Context, c chan T, channelSize int) (
 	left := make(chan T, channelSize)
 	right := make(chan T, channelSize)
 	go func() {
+		var inFlight sync.WaitGroup
 		defer close(left)
 		defer close(right)
+		defer inFlight.Wait()
+		send := func(out chan T, v T) {
+			inFlight.Add(1)
+			go func() {
+				defer inFlight.Done()
+				select {
+				case <-ctx.Done():
+				case out <- v:
+				}
+			}()
+		}
 		for {
 			select {
 			case <-ctx.Done():
-			case v := <-c:
-				go func() {
-					left <- v
-				}()
-				go func() {
-					right <- v
-				}()
+				return
+			case v, ok := <-c:
+				if !ok {
+					return
+				}
+				send(left, v)
+				send(right, v)
 			}
 		}
 	}()
 	return left, right
 }
+
+// Limit forwards the first [n] values from [c] and then closes the returned channel, any values after that
+// are left in [c]. The returned channel is also closed when [ctx] is done or [c] is closed.
+func Limit[T any](ctx context.Context, c chan T, n int) chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for range n {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-c:
+				if !ok {
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case out <- v:
+				}
+			}
+		}
+	}()
+	return out
+}
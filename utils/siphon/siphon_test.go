@@ -0,0 +1,57 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package siphon_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/Lexer747/AcciPing/utils/siphon"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitAndTee(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := make(chan int)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-ctx.Done():
+				return
+			case c <- i:
+			}
+		}
+	}()
+	left, right := siphon.TeeBufferedChannel(ctx, siphon.Limit(ctx, c, 5), 2)
+	collect := func(in chan int, out chan []int) {
+		ret := []int{}
+		for v := range in {
+			ret = append(ret, v)
+		}
+		slices.Sort(ret) // the tee is unsynchronised
+		out <- ret
+	}
+	leftValues, rightValues := make(chan []int), make(chan []int)
+	go collect(left, leftValues)
+	go collect(right, rightValues)
+	require.Equal(t, []int{0, 1, 2, 3, 4}, <-leftValues)
+	require.Equal(t, []int{0, 1, 2, 3, 4}, <-rightValues)
+}
+
+func TestTeeClosedOnCancel(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	left, right := siphon.TeeBufferedChannel(ctx, make(chan int), 0)
+	cancel()
+	_, ok := <-left
+	require.False(t, ok)
+	_, ok = <-right
+	require.False(t, ok)
+}
@@ -7,6 +7,10 @@
 package timeutils
 
 import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/Lexer747/AcciPing/utils/numeric"
@@ -14,5 +18,29 @@ import (
 
 func HumanString(t time.Duration, digits int) string {
 	rounded := numeric.RoundToNearestSigFig(float64(t), digits)
-	return time.Duration(rounded).String()
+	return time.Duration(math.Round(rounded)).String()
+}
+
+// LocalZoneName is the name of the local time zone (e.g. "Europe/London") as set by the TZ environment
+// variable or the /etc/localtime link, empty if the name can't be found. Note [time.Local] is always named
+// "Local" which is why this is needed.
+func LocalZoneName() string {
+	if tz, ok := os.LookupEnv("TZ"); ok {
+		if _, err := time.LoadLocation(tz); err == nil && tz != "" {
+			return tz
+		}
+		return ""
+	}
+	link, err := filepath.EvalSymlinks("/etc/localtime")
+	if err != nil {
+		return ""
+	}
+	_, name, found := strings.Cut(link, "zoneinfo/")
+	if !found {
+		return ""
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return ""
+	}
+	return name
 }
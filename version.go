@@ -0,0 +1,40 @@
+// Use of this source code is governed by a GPL-2 license that can be found in the LICENSE file.
+//
+// Copyright 2024 Lexer747
+//
+// SPDX-License-Identifier: GPL-2.0-only
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime/debug"
+
+	"github.com/Lexer747/AcciPing/graph/data"
+)
+
+// printVersion writes the module version and the commit it was built from, along with the version of the
+// .pings files this build writes. Useful when reporting a problem reading a file.
+func printVersion(w io.Writer) {
+	version, commit := "unknown", "unknown"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		version = info.Main.Version
+		commit = buildSetting(info, "vcs.revision", commit)
+		if buildSetting(info, "vcs.modified", "false") == "true" {
+			commit += " (modified)"
+		}
+	}
+	fmt.Fprintf(w, "AcciPing %s\n", version)
+	fmt.Fprintf(w, "commit: %s\n", commit)
+	fmt.Fprintf(w, "data version: %d\n", data.CurrentVersion())
+}
+
+func buildSetting(info *debug.BuildInfo, key, fallback string) string {
+	for _, setting := range info.Settings {
+		if setting.Key == key {
+			return setting.Value
+		}
+	}
+	return fallback
+}